@@ -3,9 +3,10 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"maps"
 	"math"
 	"os"
-	"regexp"
+	"slices"
 	"strconv"
 
 	l "github.com/Jose-Prince/UWUCompiler/lib"
@@ -27,6 +28,8 @@ type afdSwitch struct {
 
 	InitialState     reg.AFDState
 	AcceptanceStates l.Set[reg.AFDState]
+	// The number written for every state on the generated code
+	StateNumbers map[reg.AFDState]int
 }
 
 func simplifyIntoSwitch(afd *reg.AFD) afdSwitch {
@@ -35,6 +38,7 @@ func simplifyIntoSwitch(afd *reg.AFD) afdSwitch {
 		InitialState:     afd.InitialState,
 		AcceptanceStates: afd.AcceptanceStates,
 		Transitions:      make(map[reg.AFDState]map[rune]afdLeafInfo),
+		StateNumbers:     afd.StateNumbers(),
 	}
 	_simplifyIntoSwitch(afd, afd.InitialState, &sw, &visitedSet)
 	return sw
@@ -108,605 +112,563 @@ func _simplifyIntoSwitch(afd *reg.AFD, state reg.AFDState, sw *afdSwitch, visite
 	}
 }
 
-func WriteCompilerFile(filePath string, info *CompilerFileInfo) error {
+// Writes the generated lexer and parser as a package named packageName.
+//
+// The package exposes NewLexer, Lexer.Next and Parse so it can be embedded in other programs,
+// a program to run them from the command line can be written with WriteDriverFile.
+// The parsing itself is done by the parsertypes package, the parsing table is embedded already packed
+// or as the predictive table of the ll1 mode.
+func WriteCompilerFile(filePath string, packageName string, info *CompilerFileInfo) error {
 	f, err := os.Create(filePath)
 	if err != nil {
-		panic("Error creating output file!")
+		return err
 	}
 	defer f.Close()
 
+	g := info.Grammar()
+	// The ll1 parser doesn't recover from errors, so it never uses the error token
+	var llTable parsertypes.LLTable
+	var packed parsertypes.PackedTable
+	endToken, errorToken := 0, parsertypes.UNRECOGNIZABLE
+	if info.LLTable.HasValue() {
+		table := info.LLTable.GetValue()
+		llTable = table.ToParserTable()
+		endToken = llTable.EndToken
+	} else {
+		table := info.ParsingTable.ToParserTable()
+		packed = table.Pack()
+		endToken, errorToken = table.EndToken, table.ErrorToken
+	}
+
 	writer := bufio.NewWriter(f)
 	writer.WriteString(`
-package main
-
+package `)
+	writer.WriteString(packageName)
+	writer.WriteString(`
 
-// Lexer imports
 import (
 	"fmt"
-	"os"
+	"io"
 	"strconv"
 	"strings"
-	"cmp"
-	"slices"
+
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
 )
+
+// The semantic actions and the lex rules can use these packages without importing them
+var _ = fmt.Sprint
+var _ = strconv.Itoa
+var _ = strings.Compare
 	`)
 	writer.WriteString(info.LexInfo.Header)
-	writer.WriteString(`
-const END_TOKEN_TYPE =`)
-	endTk := grammar.NewEndToken()
-	writer.WriteString(strconv.FormatInt(int64(info.ParsingTable.Original.TokenToParserType(&endTk)), 10))
-	writer.WriteString(`
-const UNRECOGNIZABLE int = -1
-const GIVE_NEXT int = -2
-const IGNORE int = -3
+	writeTokenConstants(writer, g)
+	writer.WriteString(fmt.Sprintf(`
+const END_TOKEN_TYPE = %d
 
+// The token shifted when the parser recovers from a syntax error, UNRECOGNIZABLE if the grammar doesn't use it
+const ERROR_TOKEN_TYPE = %d
+const UNRECOGNIZABLE = parsertypes.UNRECOGNIZABLE
+const GIVE_NEXT = parsertypes.GIVE_NEXT
+const IGNORE = parsertypes.IGNORE
 
-const CONTEXT_TOKENS int = 10
-
-const CMD_HELP = `)
-	writer.WriteRune('`')
-	writer.WriteString(
-		`Tokenizes a specified source file
-Usage: lexer <source file>`)
-	writer.WriteRune('`')
-	writer.WriteString(`
+const INITIAL_LEXER_STATE = 0
 
-type Optional[T any] struct {
-	isValid bool
-	value   T
-}
-
-func CreateValue[T any](val T) Optional[T] {
-	return Optional[T]{value: val, isValid: true}
-}
+type Token = parsertypes.Token
+type TokenSource = parsertypes.TokenSource
+type Lexer = parsertypes.Lexer
+type LexError = parsertypes.LexError
+type ParseError = parsertypes.ParseError
+type SyntaxErrors = parsertypes.SyntaxErrors
 
-func CreateNull[T any]() Optional[T] {
-	var defaultVal T
-	return Optional[T]{value: defaultVal, isValid: false}
-}
-
-func (self Optional[T]) HasValue() bool {
-	return self.isValid
+func TokenToHuman(tk int) string {
+	return parsingTable.TokenToHuman(tk)
 }
+`, endToken, errorToken))
+	writeLexerConstructor(writer, info)
 
-func (self Optional[T]) GetValue() T {
-	if !self.isValid {
-		panic("Can't access not valid optional value!")
+	if info.LLTable.HasValue() {
+		writeLLTable(writer, &llTable)
 	} else {
-		return self.value
+		writePackedTable(writer, &packed)
+	}
+	writeSemanticActions(writer, g)
+	writer.WriteString(`
+// Parses all the tokens until END_TOKEN_TYPE is found.
+//
+`)
+	if info.LLTable.HasValue() {
+		writer.WriteString(`// The parser predicts the rule of every nonterminal from the next token, so it stops on the first syntax error.
+`)
+	} else {
+		writer.WriteString(`// Just like yacc, when a token isn't expected the parser pops states until one can shift the error token
+// and then discards tokens until one can follow it, so all the syntax errors of a source are found in one run.
+`)
+	}
+	writer.WriteString(`//
+// Returns the semantic value of the initial symbol of the grammar,
+// and SyntaxErrors with every error found or the error of the source that couldn't be read.
+func Parse(tokens TokenSource) (any, error) {
+	actions := parsertypes.ParseActions{
+		Shifted: func(token Token) any {
+			if token.Type == ERROR_TOKEN_TYPE {
+				return nil
+			}
+			return token.Text
+		},
+		Reduced: func(ruleIdx int, children []any, lookahead Token) any {
+			return executeSemanticAction(ruleIdx, children)
+		},
 	}
-}
-
-type Token struct {
-	// When does this token start in the contents of the source file
-	Start int
-	// The type of the token that it found
-	Type int
-}
-
-func (self *Token) String() string {
-	b := strings.Builder{}
-	b.WriteString("{ ")
-	b.WriteString("Start = ")
-	b.WriteString(strconv.Itoa(self.Start))
-	b.WriteString(", Type = ")
-	b.WriteString(strconv.Itoa(self.Type))
-	b.WriteString(" }")
-	return b.String()
-}
-
-type AFDNodeId = string
-type GrammarToken = int
 
-// An action can be either:
-// * a shift action
-// * a reduce action
-// * an accept state
-type Action struct {
-	// Shift to another AFDNode
-	Shift Optional[AFDNodeId]
-	// Reduce according to a production in the original grammar
-	Reduce Optional[int]
-	Accept bool
+	return parsingTable.ParseWithActions(tokens, actions)
 }
+`)
+	if info.BuildTree {
+		writer.WriteString(`
+type TreeNode = parsertypes.TreeNode
 
-func (s Action) IsShift() bool {
-	return s.Shift.HasValue()
+// Parses all the tokens until END_TOKEN_TYPE is found, just like Parse,
+// but returns the concrete syntax tree of the source instead of running the semantic actions.
+func ParseTree(tokens TokenSource) (*TreeNode, error) {
+	return parsingTable.Parse(tokens)
 }
-
-func (s Action) GetShift() AFDNodeId {
-	if !s.IsShift() {
-		panic("Can't get shift of an action that isn't a shift!")
+`)
 	}
 
-	return s.Shift.GetValue()
-}
+	for i, mode := range info.LexInfo.Modes {
+		writer.WriteString(fmt.Sprintf(`
+func %s(state *int, input rune) int {
+`, lexStepName(mode)))
+		sw := simplifyIntoSwitch(&info.LexAFDs[i])
+		sw.WriteTo(writer)
+		writer.WriteRune('}')
+	}
+	writer.WriteString(info.LexInfo.Footer)
 
-func (s Action) IsReduce() bool {
-	return s.Reduce.HasValue()
+	return writer.Flush()
 }
 
-func (s Action) GetReduce() int {
-	if !s.IsReduce() {
-		panic("Can't get reduce of an action that isn't a reduce!")
+// The generated function with the AFD of a lexer mode.
+func lexStepName(mode string) string {
+	if mode == LEX_INITIAL_MODE {
+		return "gettoken"
 	}
-
-	return s.Reduce.GetValue()
+	return "gettoken_" + mode
 }
 
-type EpsilonString = Optional[string]
-
-type GrammarRule struct {
-	Head       GrammarToken
-	Production []GrammarToken
+// Writes NewLexer, when the lex file has modes it also writes a constant for each one
+// and the changes of mode of their rules by the state their AFD accepts them on.
+func writeLexerConstructor(writer *bufio.Writer, info *CompilerFileInfo) {
+	modes := info.LexInfo.Modes
+	if len(modes) <= 1 {
+		writer.WriteString(`
+// Creates a lexer that tokenizes all the contents of reader.
+func NewLexer(reader io.Reader) *Lexer {
+	return parsertypes.NewLexer(reader, INITIAL_LEXER_STATE, gettoken, END_TOKEN_TYPE)
 }
-
-type Set[T comparable] map[T]struct{}
-
-// Prints a set as a string.
-//
-// Since a Set is internally represented as a map, the keys will be unordered!
-func (self Set[T]) String() string {
-	b := strings.Builder{}
-	b.WriteString("[ ")
-
-	for k := range self {
-		b.WriteString(fmt.Sprint(k))
-		b.WriteString(", ")
+`)
+		return
 	}
 
-	b.WriteString("]")
-	return b.String()
-}
-
-func GetValuesStable[T cmp.Ordered](self Set[T]) []T {
-	values := make([]T, 0, len(self))
-	for k := range self {
-		values = append(values, k)
+	writer.WriteString(`
+// The modes of the lexer, the index of each one on lexerModes
+const (
+`)
+	for i, mode := range modes {
+		writer.WriteString(fmt.Sprintf("\tLEXER_MODE_%s int = %d\n", mode, i))
 	}
+	writer.WriteString(`)
 
-	slices.Sort(values)
-
-	return values
-}
-
-// Prints a set as a string with it's keys on the same order every time!
-func StableSetString[T cmp.Ordered](self Set[T]) string {
-	b := strings.Builder{}
-	b.WriteString("[ ")
+// The AFD of every mode and the changes of mode of its rules
+var lexerModes = []parsertypes.LexerMode{
+`)
+	for i, mode := range modes {
+		writer.WriteString(fmt.Sprintf("\t{Step: %s, InitialState: INITIAL_LEXER_STATE, Changes: map[int]parsertypes.LexerModeChange{", lexStepName(mode)))
+		changes := lexModeChanges(&info.LexAFDs[i], info.LexInfo.Rules)
+		states := slices.Sorted(maps.Keys(changes))
+		for j, state := range states {
+			if j > 0 {
+				writer.WriteString(", ")
+			}
 
-	values := GetValuesStable(self)
-	for _, k := range values {
-		b.WriteString(fmt.Sprint(k))
-		b.WriteString(", ")
+			change := changes[state]
+			writer.WriteString(fmt.Sprintf("%d: {Type: parsertypes.MODE_%s", state, change.Type))
+			if change.Type != parsertypes.MODE_POP {
+				writer.WriteString(", Mode: LEXER_MODE_" + change.Mode)
+			}
+			writer.WriteString("}")
+		}
+		writer.WriteString("}},\n")
 	}
+	writer.WriteString(`}
 
-	b.WriteString("]")
-	return b.String()
+// Creates a lexer that tokenizes all the contents of reader, starting on LEXER_MODE_INITIAL.
+func NewLexer(reader io.Reader) *Lexer {
+	return parsertypes.NewModalLexer(reader, lexerModes, END_TOKEN_TYPE)
+}
+`)
 }
 
-// Checks if self is equal to other.
+// Finds the states of the generated AFD where a rule that changes the mode is accepted.
 //
-// Equal means that all items in self are contained in other and no more items are in other.
-func (self *Set[T]) Equals(other *Set[T]) bool {
-	if len(*self) != len(*other) {
-		return false
-	}
+// Just like the code executed on them, the rule accepted on a state is the one with the lowest priority.
+func lexModeChanges(afd *reg.AFD, rules []LexFileRule) map[int]LexModeChange {
+	byPriority := make(map[uint]LexFileRule)
+	for _, rule := range rules {
+		byPriority[rule.Info.Priority] = rule
+	}
+
+	numbers := afd.StateNumbers()
+	changes := make(map[int]LexModeChange)
+	for state, transitions := range afd.Transitions {
+		hasDummy := false
+		for input := range transitions {
+			hasDummy = hasDummy || input.IsDummy()
+		}
+		if !hasDummy {
+			continue
+		}
 
-	for k := range *self {
-		if !other.Contains(k) {
-			return false
+		dummy := getLowestPriorityDummy(afd, state)
+		rule := byPriority[dummy.GetDummy().Priority]
+		if rule.ModeChange.HasValue() {
+			changes[numbers[state]] = rule.ModeChange.GetValue()
 		}
 	}
 
-	return true
+	return changes
 }
 
-// Checks if an element exists on the set.
+// Writes a main program that parses the file supplied as argument with the generated parser.
 //
-// Returns True if the value is contained in the set.
-func (self *Set[T]) Contains(val T) bool {
-	_, alreadyAdded := (*self)[val]
-
-	return alreadyAdded
-}
-
-// Adds an element to the set.
-//
-// Returns True if the element is new to the set,
-// false otherwise.
-func (self *Set[T]) Add(val T) bool {
-	ref := *self
-	_, alreadyAdded := ref[val]
-
-	if !alreadyAdded {
-		ref[val] = struct{}{}
+// It must be on the same directory as the file written by WriteCompilerFile, so that one must be a main package.
+func WriteDriverFile(filePath string, info *CompilerFileInfo) error {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
 
-	return !alreadyAdded
-}
+	writer := bufio.NewWriter(f)
+	writer.WriteString(`
+package main
 
-// Adds all values from other into self.
-func (self *Set[T]) Merge(other *Set[T]) {
-	for val := range *other {
-		self.Add(val)
-	}
-}
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
 
-func NewSet[T comparable]() Set[T] {
-	return make(Set[T])
+const CONTEXT_CHARS int = 40
+`)
+	if info.BuildTree {
+		writer.WriteString(`
+const CMD_HELP = "Parses a specified source file and prints its concrete syntax tree\nUsage: parser [-json] <source file>"
+`)
+	} else {
+		writer.WriteString(`
+const CMD_HELP = "Parses a specified source file\nUsage: parser <source file>"
+`)
+	}
+	writer.WriteString(`
+func markRed(contents []byte, start, end int) string {
+	end = min(end, len(contents))
+	start = min(start, end)
+	return fmt.Sprintf("%s\033[31;1;4m%s\033[0m%s", contents[:start], contents[start:end], contents[end:])
 }
 
-func (self *Set[T]) IsEmpty() bool {
-	return len(*self) == 0
+// Shows where an error happened in the source file.
+//
+// The lexer doesn't keep the source in memory, so the surroundings are read again from the file.
+func errorContext(source io.ReaderAt, start, end int) string {
+	previewStart := max(0, start-CONTEXT_CHARS)
+	contents := make([]byte, end+CONTEXT_CHARS-previewStart)
+	read, _ := source.ReadAt(contents, int64(previewStart))
+	return markRed(contents[:read], start-previewStart, end-previewStart)
 }
 
-func (self *Set[T]) Clear() {
-	for k := range *self {
-		delete(*self, k)
+// Reports every error found while parsing the source file.
+func reportErrors(sourceFilePath string, source io.ReaderAt, err error) {
+	var syntaxErrors SyntaxErrors
+	if !errors.As(err, &syntaxErrors) {
+		syntaxErrors = SyntaxErrors{err}
 	}
-}
 
-func (self *Set[T]) ToSlice() []T {
-	slice := make([]T, 0, len(*self))
-	for k := range *self {
-		slice = append(slice, k)
+	for _, err := range syntaxErrors {
+		reportError(sourceFilePath, source, err)
 	}
-
-	return slice
-}
-
-type Grammar struct {
-	InitialSimbol GrammarToken
-	Rules         []GrammarRule
-	Terminals     Set[GrammarToken]
-	NonTerminals  Set[GrammarToken]
+	fmt.Fprintf(os.Stderr, "\nFound %d errors!\n", len(syntaxErrors))
 }
 
-type ParsingTable struct {
-	// The Action table contains all the reduce and shifts of the parsing table.
-	ActionTable map[AFDNodeId]map[GrammarToken]Action
-	// The GoTo table contains all the nonterminal tokens and what transitions to make of them.
-	GoToTable map[AFDNodeId]map[GrammarToken]AFDNodeId
-	// The original grammar, IT MUST NOT BE EXPANDED!
-	Original Grammar
-
-	// The node to start parsing from
-	InitialNodeId AFDNodeId
-}
+func reportError(sourceFilePath string, source io.ReaderAt, err error) {
+	var lexErr *LexError
+	var parseErr *ParseError
 
-type Stack[T any] []T
-
-func NewStack[T any]() Stack[T] {
-	return Stack[T]{}
-}
-
-func (self *Stack[T]) Empty() bool {
-	return len(*self) == 0
-}
+	if errors.As(err, &lexErr) {
+		fmt.Fprintf(os.Stderr, "\nSYNTAX ERROR: Unexpected character (%c)\n", lexErr.Char)
+		fmt.Fprintln(os.Stderr, "==============================================")
+		fmt.Fprintf(os.Stderr, "ON (%s:%d:%d)\n", sourceFilePath, lexErr.Line, lexErr.Col)
+		fmt.Fprintln(os.Stderr, errorContext(source, lexErr.Offset, lexErr.Offset+utf8.RuneLen(lexErr.Char)))
+	} else if errors.As(err, &parseErr) {
+		token := parseErr.Token
+		msg := fmt.Sprintf("Unexpected token (%s) : (%s)", token.Text, token.String())
+		if token.Type == END_TOKEN_TYPE {
+			msg = "Unexpected EOF Reached!"
+		}
 
-func (self *Stack[T]) Peek() Optional[T] {
-	idx := len(*self) - 1
-	ref := *self
+		meantOptions := "(No options)"
+		if len(parseErr.Expected) > 0 {
+			b := strings.Builder{}
+			for _, k := range parseErr.Expected {
+				b.WriteString("- ")
+				b.WriteString(TokenToHuman(k))
+				b.WriteString("\n")
+			}
+			meantOptions = b.String()
+		}
 
-	if idx < 0 {
-		return CreateNull[T]()
+		fmt.Fprintf(os.Stderr, "\nGRAMMAR ERROR: %s\n", msg)
+		fmt.Fprintf(os.Stderr, "Maybe you meant:\n%s\n", meantOptions)
+		fmt.Fprintln(os.Stderr, "==============================================")
+		fmt.Fprintf(os.Stderr, "ON (%s:%d:%d)\n", sourceFilePath, token.Line, token.Col)
+		fmt.Fprintln(os.Stderr, errorContext(source, token.Start, token.End))
+	} else {
+		fmt.Fprintf(os.Stderr, "Error reading the source file! %v\n", err)
 	}
-
-	return CreateValue(ref[idx])
-}
-
-func (self *Stack[T]) Push(val T) *Stack[T] {
-	*self = append(*self, val)
-	return self
 }
 
-func (self *Stack[T]) Pop() Optional[T] {
-	ref := *self
-	length := len(ref)
-	idx := length - 1
+func main() {
+`)
+	if info.BuildTree {
+		writer.WriteString(`	dumpJSON := len(os.Args) == 3 && os.Args[1] == "-json"
+	if len(os.Args) != 2 && !dumpJSON {
+`)
+	} else {
+		writer.WriteString(`	if len(os.Args) != 2 {
+`)
+	}
+	writer.WriteString(`		fmt.Fprintf(os.Stderr, "Please supply only a source file as argument!\n")
+		fmt.Fprintln(os.Stderr, CMD_HELP)
+		os.Exit(1)
+	}
 
-	if idx < 0 {
-		return CreateNull[T]()
+	sourceFilePath := os.Args[len(os.Args)-1]
+	file, err := os.Open(sourceFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening the source file! %v\n", err)
+		os.Exit(1)
 	}
+	defer file.Close()
 
-	val := ref[idx]
-	*self = ref[:idx]
+	lexer := NewLexer(file)
+`)
+	if info.BuildTree {
+		writer.WriteString(`	tree, err := ParseTree(lexer)
+	if err != nil {
+		reportErrors(sourceFilePath, file, err)
+		fmt.Println("The input can't be accepted!")
+		os.Exit(1)
+	}
 
-	return CreateValue(val)
-}
+	if dumpJSON {
+		contents, err := tree.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing the tree as JSON! %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(string(contents))
+		return
+	}
 
-type ParseItem struct {
-	Token  Optional[GrammarToken]
-	NodeId Optional[AFDNodeId]
+	fmt.Println("The input is accepted!")
+	fmt.Print(tree.String())
 }
-
-func CreateTokenItem(token GrammarToken) ParseItem {
-	return ParseItem{
-		Token:  CreateValue(token),
-		NodeId: CreateNull[AFDNodeId](),
+`)
+		return writer.Flush()
 	}
-}
 
-func CreateNodeItem(nodeId AFDNodeId) ParseItem {
-	return ParseItem{
-		Token:  CreateNull[GrammarToken](),
-		NodeId: CreateValue(nodeId),
+	writer.WriteString(`	result, err := Parse(lexer)
+	if err != nil {
+		reportErrors(sourceFilePath, file, err)
+		fmt.Println("The input can't be accepted!")
+		os.Exit(1)
 	}
-}
 
-func (item ParseItem) IsToken() bool {
-	return item.Token.HasValue()
-}
+	fmt.Println("The input is accepted!")
+`)
+	if hasSemanticActions(info.Grammar()) {
+		writer.WriteString(`	if result != nil {
+		fmt.Println("The result is:", result)
+	}
+`)
+	} else {
+		writer.WriteString(`	_ = result
+`)
+	}
+	writer.WriteString(`}
+`)
 
-func (item ParseItem) IsNodeId() bool {
-	return item.NodeId.HasValue()
+	return writer.Flush()
 }
 
-func (item ParseItem) GetNodeId() AFDNodeId {
-	if !item.IsNodeId() {
-		panic("Invalid acces! Can't get node id from invalid item")
+// Writes the packed parsing table as a parsertypes.PackedTable literal.
+func writePackedTable(writer *bufio.Writer, table *parsertypes.PackedTable) {
+	writer.WriteString(`
+// The parsing table of the grammar, packed with ParsingTable.Pack
+var parsingTable = parsertypes.PackedTable{
+`)
+	writer.WriteString(fmt.Sprintf("\tInitialState: %d,\n", table.InitialState))
+	writer.WriteString(fmt.Sprintf("\tEndToken: %d,\n", table.EndToken))
+	writer.WriteString(fmt.Sprintf("\tErrorToken: %d,\n", table.ErrorToken))
+	writer.WriteString("\tTokenNames: []string{")
+	for i, name := range table.TokenNames {
+		if i > 0 {
+			writer.WriteString(", ")
+		}
+		writer.WriteString(strconv.Quote(name))
 	}
+	writer.WriteString("},\n")
 
-	return item.NodeId.GetValue()
+	arrays := []struct {
+		name   string
+		values []int
+	}{
+		{"RuleHeads", table.RuleHeads},
+		{"RuleLengths", table.RuleLengths},
+		{"ActionBase", table.ActionBase},
+		{"DefaultActions", table.DefaultActions},
+		{"GoToBase", table.GoToBase},
+		{"DefaultGoTos", table.DefaultGoTos},
+		{"Table", table.Table},
+		{"Check", table.Check},
+	}
+	for _, array := range arrays {
+		writeIntArray(writer, array.name, array.values)
+	}
+	writer.WriteString("}\n")
 }
 
-func (item ParseItem) GetToken() GrammarToken {
-	if !item.IsToken() {
-		panic("Invalid acces! Can't get node id from invalid item")
+// Writes the predictive parsing table as a parsertypes.LLTable literal.
+func writeLLTable(writer *bufio.Writer, table *parsertypes.LLTable) {
+	writer.WriteString(`
+// The predictive parsing table of the grammar
+var parsingTable = parsertypes.LLTable{
+`)
+	writer.WriteString(fmt.Sprintf("\tInitialSymbol: %d,\n", table.InitialSymbol))
+	writer.WriteString(fmt.Sprintf("\tEndToken: %d,\n", table.EndToken))
+	writer.WriteString("\tTokenNames: []string{")
+	for i, name := range table.TokenNames {
+		if i > 0 {
+			writer.WriteString(", ")
+		}
+		writer.WriteString(strconv.Quote(name))
 	}
+	writer.WriteString("},\n")
 
-	return item.Token.GetValue()
+	writeIntArray(writer, "RuleHeads", table.RuleHeads)
+	writer.WriteString("\tProductions: [][]int{\n")
+	for _, production := range table.Productions {
+		writer.WriteString("\t\t{")
+		for i, symbol := range production {
+			if i > 0 {
+				writer.WriteString(", ")
+			}
+			writer.WriteString(strconv.Itoa(symbol))
+		}
+		writer.WriteString("},\n")
+	}
+	writer.WriteString("\t},\n")
+	writeIntArray(writer, "PredictionRows", table.PredictionRows)
+	writeIntArray(writer, "Predictions", table.Predictions)
+	writer.WriteString("}\n")
 }
 
-func getLineAndCol(contents []byte, idx int) (int, int) {
-	line := 0
-	col := 0
-
-	for i := range idx {
-		if contents[i] == '\n' {
-			line++
-			col = 0
+func writeIntArray(writer *bufio.Writer, name string, values []int) {
+	writer.WriteString("\t")
+	writer.WriteString(name)
+	writer.WriteString(": []int{")
+	for i, value := range values {
+		if i%16 == 0 {
+			writer.WriteString("\n\t\t")
 		} else {
-			col++
+			writer.WriteString(" ")
 		}
-	}
 
-	return line + 1, col + 1
+		writer.WriteString(strconv.Itoa(value))
+		writer.WriteString(",")
+	}
+	writer.WriteString("\n\t},\n")
 }
 
-func markRed(contents []byte, start, end int) string {
-	prefix := contents[:start]
-	postfix := []byte{}
-	if end < len(contents) {
-		postfix = contents[end:]
+// Writes a constant for every terminal of the grammar so the lex rules can return them.
+func writeTokenConstants(writer *bufio.Writer, g *grammar.Grammar) {
+	writer.WriteString(`
+// Token types generated from the grammar
+const (
+`)
+	for _, constant := range getTokenConstants(g) {
+		writer.WriteString(fmt.Sprintf("\t%s int = %d\n", constant.Name, constant.Id))
 	}
-
-	return fmt.Sprintf("%s\033[31;1;4m%s\033[0m%s", prefix, contents[start:end], postfix)
+	writer.WriteString(")\n")
 }
 
-var TokenArrayMap = `)
-	writer.WriteString(removeModulesFromStaticType(fmt.Sprintf("%#v", info.ParsingTable.Original.TransposeTokenIds())))
-
-	writer.WriteString(`
+// Replaces $$ and $1..$n with the variables of the generated executeSemanticAction function.
+//
+// The grammar already checked every $n is a symbol of the production.
+func translateSemanticAction(code string) string {
+	return grammar.ReplaceSemanticValues(code, func(match string) string {
+		if match == "$$" {
+			return "semanticResult"
+		}
 
-func TokenToHuman(tk int) string {
-	return fmt.Sprintf("%d (%s)", tk, TokenArrayMap[tk])
+		n, _ := strconv.Atoi(match[1:])
+		return fmt.Sprintf("semanticValues[%d]", n-1)
+	})
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Please supply only a source file as argument!\n")
-		panic(CMD_HELP)
-	}
-
-	sourceFilePath := os.Args[1]
-	sourceFileContent, err := os.ReadFile(sourceFilePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening the source file! %v", err)
-	}
-
-	tokens := make([]Token, 0, 1000)
-
-	for i := 0; i < len(sourceFileContent); i++ {
-		afdState := "`)
-	writer.WriteString(info.LexAFD.InitialState)
-	writer.WriteString(`" // INITIAL AFD STATE!
-
-		previousParsingResult := -1000
-		j := 0
-		for j = i; j < len(sourceFileContent); j++ {
-			parsingResult := gettoken(&afdState, rune(sourceFileContent[j]))
-
-			if parsingResult == UNRECOGNIZABLE {
-				foundSomething := previousParsingResult != -1000 
-				if previousParsingResult == IGNORE {
-
-				} else if foundSomething {
-					token := Token{Start: i, Type: previousParsingResult}
-					tokens = append(tokens, token)
-					fmt.Println(token.String())
-					i = j - 1
-					break
-				} else {
-					line, col := getLineAndCol(sourceFileContent, j)
-					start := i
-					if len(tokens) > 0 {
-						tokensBackwards := min(len(tokens), CONTEXT_TOKENS)
-						start = tokens[len(tokens)-tokensBackwards].Start
-					}
-					
-					end := min(len(sourceFileContent), j+10)
-					panic(fmt.Sprintf(`)
-	writer.WriteRune('`')
-	writer.WriteString(`
-SYNTAX ERROR: Unexpected character (%c)
-==============================================
-ON (%s:%d:%d)
-%s`)
-	writer.WriteRune('`')
-	writer.WriteString(`, sourceFileContent[j],
-	sourceFilePath,
-	line, col,
-	markRed(sourceFileContent[start:end], j-start, j-start+1)))
-
-				}
-			} else if parsingResult != GIVE_NEXT {
-				previousParsingResult = parsingResult
-			}
+func hasSemanticActions(g *grammar.Grammar) bool {
+	for _, rule := range g.Rules {
+		if rule.SemanticAction != "" {
+			return true
 		}
 	}
 
-	tokens = append(tokens, Token {Start: len(sourceFileContent), Type: END_TOKEN_TYPE})
-
-	`)
-
-	writer.WriteString("table := ")
-	var transformedTable parsertypes.ParsingTable = info.ParsingTable.ToParserTable()
-	writer.WriteString(removeModulesFromStaticType(fmt.Sprintf("%#v", transformedTable)))
+	return false
+}
 
+// Writes the function that executes the semantic action of a rule when it's reduced.
+//
+// Terminals have the text they matched as value.
+// Just like yacc, if the rule has no action the value of the head is the value of $1.
+func writeSemanticActions(writer *bufio.Writer, g *grammar.Grammar) {
 	writer.WriteString(`
+func executeSemanticAction(ruleIdx int, semanticValues []any) any {
+	var semanticResult any
+	if len(semanticValues) > 0 {
+		semanticResult = semanticValues[0]
+	}
 
-	isAccepted := false
-	stack := Stack[ParseItem]{}
-	stack = append(stack, CreateNodeItem(table.InitialNodeId))
-	for i := 0; i < len(tokens); i++ {
-		token := tokens[i]
-		if val := stack.Peek(); !val.HasValue() {
-			panic("Invalid parsing state! Stack is empty!")
-		}
+	switch ruleIdx {
+`)
 
-		item := stack.Peek().GetValue()
-		if !item.IsNodeId() {
-			panic("Invalid parsing state! The item on the stack is not a NodeID!")
+	for i, rule := range g.Rules {
+		if rule.SemanticAction == "" {
+			continue
 		}
 
-		nodeId := item.GetNodeId()
-
-		// go check the action table
-		isTerminal := table.Original.Terminals.Contains(token.Type)
-		if isTerminal {
-			action, found := table.ActionTable[nodeId][token.Type]
-			if !found {
-				if token.Type == END_TOKEN_TYPE {
-				}
-				tokenEnd := len(sourceFileContent)
-				if i+1 < len(tokens) {
-					tokenEnd = tokens[i+1].Start
-				}
-				line, col := getLineAndCol(sourceFileContent, token.Start)
-
-				previewStart := tokens[i-min(i, CONTEXT_TOKENS)].Start
-				previewEnd := tokens[i+min(len(tokens)-(i+1), CONTEXT_TOKENS)].Start
-
-				msg := fmt.Sprintf("Unexpected token (%s) : (%s)", sourceFileContent[token.Start:tokenEnd], token.String())
-				if token.Type == END_TOKEN_TYPE {
-					msg = "Unexpected EOF Reached!"
-				}
-
-				meantOptions := "(No options)"
-				if len(table.ActionTable[nodeId]) > 0 {
-					b := strings.Builder{}
-
-					for k := range table.ActionTable[nodeId] {
-						b.WriteString("- ")
-						b.WriteString(TokenToHuman(k))
-						// b.WriteString(strconv.FormatInt(int64(k), 10))
-						b.WriteString("\n")
-					}
-
-					meantOptions = b.String()
-				}
-
-				panic(fmt.Sprintf(`)
-	writer.WriteString("`")
-	writer.WriteString(`
-GRAMMAR ERROR: %s
-Maybe you meant:
-%s
-==============================================
-ON (%s:%d:%d)
-%s`)
-	writer.WriteString("`")
-	writer.WriteString(`,
-					msg,
-					meantOptions,
-					sourceFilePath,
-					line, col,
-					markRed(sourceFileContent[previewStart:previewEnd], token.Start-previewStart, tokenEnd-previewStart)))
-			}
-
-			if action.Accept {
-				isAccepted = true
-				break
-			} else if action.IsShift() {
-				stack.Push(CreateTokenItem(token.Type))
-				stack.Push(CreateNodeItem(action.GetShift()))
-
-			} else if action.IsReduce() {
-				idx := action.GetReduce()
-				// rule := table.Original.Rules[idx]
-				productionsCopy := make([]GrammarToken, len(table.Original.Rules[idx].Production))
-				copy(productionsCopy, table.Original.Rules[idx].Production)
-
-				for len(productionsCopy) > 0 {
-					reduceItem := stack.Pop()
-					if !reduceItem.HasValue() {
-						panic("Invalid parsing state! The stack is empty, can't keep up reducing!")
-					}
-
-					{
-						reduceItem := reduceItem.GetValue()
-						if reduceItem.IsToken() {
-							itemIdx := -1
-							for j, prodToken := range productionsCopy {
-								if prodToken == reduceItem.Token.GetValue() {
-									itemIdx = j
-									break
-								}
-							}
-
-							if itemIdx == -1 {
-								panic("Token not found in reduce production!")
-							}
-							productionsCopy = slices.Delete(productionsCopy, itemIdx, itemIdx+1)
-						}
-
-					}
-				}
-				stack.Push(CreateTokenItem(table.Original.Rules[idx].Head))
-
-				// Now we execute the follow
-				nonTerminalToken := stack.Pop().GetValue()
-				gotoNodeId := stack.Pop().GetValue()
-
-				newNodeId := table.GoToTable[gotoNodeId.GetNodeId()][nonTerminalToken.GetToken()]
-				stack.Push(gotoNodeId)
-				stack.Push(nonTerminalToken)
-				stack.Push(CreateNodeItem(newNodeId))
-				i--
-			}
-		} else {
-			panic("Token should always be a terminal!")
-		}
+		writer.WriteString(fmt.Sprintf("\tcase %d: // %s\n\t\t{\n", i, rule.ToString()))
+		writer.WriteString(translateSemanticAction(rule.SemanticAction))
+		writer.WriteString("\n\t\t}\n")
 	}
 
-	if isAccepted {
-		fmt.Println("The input is accepted!")
-	} else {
-		fmt.Println("The input can't be accepted!")
-	}
-}
+	writer.WriteString(`	}
 
-func gettoken(state *string, input rune) int {
+	return semanticResult
+}
 `)
-
-	sw := simplifyIntoSwitch(&info.LexAFD)
-	sw.WriteTo(writer)
-	writer.WriteRune('}')
-	writer.WriteString(info.LexInfo.Footer)
-
-	return writer.Flush()
 }
 
 func (s *afdSwitch) WriteTo(writer *bufio.Writer) {
@@ -724,13 +686,16 @@ func _writeTo(s *afdSwitch, w *bufio.Writer, state reg.AFDState, alreadyWrittenS
 		return
 	}
 
-	w.WriteString("case \"")
-	w.WriteString(state)
-	w.WriteString(`":
+	w.WriteString("case ")
+	w.WriteString(strconv.Itoa(s.StateNumbers[state]))
+	w.WriteString(`:
 	switch input {
 `)
 
-	for input, caseInfo := range s.Transitions[state] {
+	// The inputs are sorted so the generated code is the same on every run
+	inputs := slices.Sorted(maps.Keys(s.Transitions[state]))
+	for _, input := range inputs {
+		caseInfo := s.Transitions[state][input]
 		w.WriteString("case '")
 		switch input {
 		case '\t':
@@ -747,25 +712,15 @@ func _writeTo(s *afdSwitch, w *bufio.Writer, state reg.AFDState, alreadyWrittenS
 			w.WriteRune(input)
 		}
 		w.WriteString(`':
-		*state = "`)
-		w.WriteString(caseInfo.NewState)
-		w.WriteString("\"\n")
+		*state = `)
+		w.WriteString(strconv.Itoa(s.StateNumbers[caseInfo.NewState]))
+		w.WriteString("\n")
 		w.WriteString(caseInfo.Code)
 		w.WriteRune('\n')
 	}
 	w.WriteString("}\n")
 
-	for _, caseInfo := range s.Transitions[state] {
-		_writeTo(s, w, caseInfo.NewState, alreadyWrittenStates)
-	}
-}
-
-func removeModulesFromStaticType(t string) string {
-	reg, err := regexp.Compile(`([A-Za-z\/-]+)\.`)
-	if err != nil {
-		panic(err)
+	for _, input := range inputs {
+		_writeTo(s, w, s.Transitions[state][input].NewState, alreadyWrittenStates)
 	}
-
-	replaced := reg.ReplaceAll([]byte(t), []byte{})
-	return string(replaced)
 }
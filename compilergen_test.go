@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateSemanticAction(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"$$ = $1", "semanticResult = semanticValues[0]"},
+		{"$$ = $1.(int) + $3.(int)", "semanticResult = semanticValues[0].(int) + semanticValues[2].(int)"},
+		{"$$ = append($1.([]any), $12)", "semanticResult = append(semanticValues[0].([]any), semanticValues[11])"},
+		{"fmt.Println(\"no values\")", "fmt.Println(\"no values\")"},
+		// The text of strings and runes is kept
+		{"$$ = fmt.Sprint(\"$1 \\\" $2\", '$', `$3\\`, $1)", "semanticResult = fmt.Sprint(\"$1 \\\" $2\", '$', `$3\\`, semanticValues[0])"},
+	}
+
+	for _, tt := range tests {
+		got := translateSemanticAction(tt.code)
+		if got != tt.want {
+			t.Errorf("translateSemanticAction(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+// Generates a lexer that returns non ASCII tokens and tests it on its own module.
+func TestGeneratedLexer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Compiling the generated code is slow")
+	}
+
+	files := map[string]string{
+		"tokens.lex": `{
+}
+
+rule gettoken =
+	[ \n]+	{ return IGNORE }
+	| 'λ'	{ return LAMBDA }
+	| 'ñandú'	{ return BIRD }
+	| '\.'	{ return DOT }
+	| '\.\.\.'	{ return ELLIPSIS }
+`,
+		"grammar.yal": `%token LAMBDA BIRD DOT ELLIPSIS
+%%
+list: list item
+	| item
+	;
+item: LAMBDA
+	| BIRD
+	| DOT
+	| ELLIPSIS
+	;
+`,
+		"lexer_test.go": `package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestLexer(t *testing.T) {
+	// Reading one byte at a time splits the runes between reads
+	lexer := NewLexer(iotest.OneByteReader(strings.NewReader("λ ñandú\n.. ...λ ñan")))
+	expected := []Token{
+		{Start: 0, End: 2, Line: 1, Col: 1, Text: "λ", Type: LAMBDA},
+		{Start: 3, End: 10, Line: 1, Col: 3, Text: "ñandú", Type: BIRD},
+		{Start: 11, End: 12, Line: 2, Col: 1, Text: ".", Type: DOT},
+		{Start: 12, End: 13, Line: 2, Col: 2, Text: ".", Type: DOT},
+		{Start: 14, End: 17, Line: 2, Col: 4, Text: "...", Type: ELLIPSIS},
+		{Start: 17, End: 19, Line: 2, Col: 7, Text: "λ", Type: LAMBDA},
+	}
+	for _, want := range expected {
+		got, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("Expected %#v but got %#v", want, got)
+		}
+	}
+
+	_, err := lexer.Next()
+	var lexErr *LexError
+	if !errors.As(err, &lexErr) || lexErr.Offset != 20 || lexErr.Col != 9 || lexErr.Char != 'ñ' {
+		t.Fatalf("Expected an error on the last ñ but got %v", err)
+	}
+}
+
+func TestLexerLargeInput(t *testing.T) {
+	source := strings.Repeat("ñandú λ ... ", 2000)
+	result, err := Parse(NewLexer(strings.NewReader(source)))
+	if err != nil {
+		t.Fatal(err, result)
+	}
+}
+`,
+	}
+	testGeneratedCode(t, files)
+}
+
+// Generates a lexer with modes for strings with escapes and nested comments.
+func TestGeneratedLexerModes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Compiling the generated code is slow")
+	}
+
+	files := map[string]string{
+		"tokens.lex": `{
+}
+
+rule gettoken =
+	<INITIAL,COMMENT> [ \n]+	{ return IGNORE }
+	| [a-z]+	{ return ID }
+	| '"'	{ BEGIN(STRING) }
+	| \(\*	{ PUSH(COMMENT) }
+	| <STRING> [^"\\]+	{ return TEXT }
+	| <STRING> \\["\\n]	{ return ESCAPE }
+	| <STRING> '"'	{ BEGIN(INITIAL) }
+	| <COMMENT> \(\*	{ PUSH(COMMENT) }
+	| <COMMENT> \*\)	{ POP() }
+	| <COMMENT> [^*(]+|\*|\(	{ return IGNORE }
+`,
+		"grammar.yal": `%token ID TEXT ESCAPE
+%%
+list: list item | item ;
+item: ID | TEXT | ESCAPE ;
+`,
+		"lexer_test.go": `package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexerModes(t *testing.T) {
+	lexer := NewLexer(strings.NewReader("a \"x\\\"y\" (* c (* d *) e *) b \"z"))
+	expected := []struct {
+		text      string
+		tokenType int
+		mode      int
+	}{
+		{"a", ID, LEXER_MODE_INITIAL},
+		{"x", TEXT, LEXER_MODE_STRING},
+		{"\\\"", ESCAPE, LEXER_MODE_STRING},
+		{"y", TEXT, LEXER_MODE_STRING},
+		// The comments are skipped, coming back to the initial mode after the outer one
+		{"b", ID, LEXER_MODE_INITIAL},
+		{"z", TEXT, LEXER_MODE_STRING},
+	}
+	for _, want := range expected {
+		got, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Text != want.text || got.Type != want.tokenType || lexer.Mode() != want.mode {
+			t.Fatalf("Expected %q of type %d on mode %d but got %q of type %d on mode %d", want.text, want.tokenType, want.mode, got.Text, got.Type, lexer.Mode())
+		}
+	}
+
+	if got, err := lexer.Next(); err != nil || got.Type != END_TOKEN_TYPE {
+		t.Fatalf("Expected the end of the source but got %v %v", got, err)
+	}
+}
+`,
+	}
+	testGeneratedCode(t, files)
+}
+
+// Generates the lexer and parser of the files on its own module and runs the tests of the files with them.
+//
+// files must have a tokens.lex, a grammar.yal and some _test.go file,
+// flags are passed to the generator.
+func testGeneratedCode(t *testing.T, files map[string]string, flags ...string) {
+	dir := t.TempDir()
+	// The generated code imports the runtime from this repository
+	repo, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	module := "github.com/Jose-Prince/UWUCompiler"
+	files["go.mod"] = fmt.Sprintf("module generated\n\ngo 1.24\n\nrequire %s v0.0.0\n\nreplace %s => %s\n", module, module, repo)
+	for name, contents := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := []string{"run", ".",
+		"-lexPath", filepath.Join(dir, "tokens.lex"),
+		"-grammarPath", filepath.Join(dir, "grammar.yal"),
+		"-outPath", filepath.Join(dir, "out.go"),
+	}
+	generate := exec.Command("go", append(args, flags...)...)
+	if output, err := generate.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to generate the code: %v\n%s", err, output)
+	}
+
+	run := exec.Command("go", "test", ".")
+	run.Dir = dir
+	if output, err := run.CombinedOutput(); err != nil {
+		t.Fatalf("The generated code failed: %v\n%s", err, output)
+	}
+}
+
+// Generates a parser that recovers from syntax errors with the error token.
+func TestGeneratedParserRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Compiling the generated code is slow")
+	}
+
+	testGeneratedCode(t, map[string]string{
+		"tokens.lex": `{
+}
+
+rule gettoken =
+	[ \n]+	{ return IGNORE }
+	| [0-9]+	{ return NUMBER }
+	| '\+'	{ return PLUS }
+	| ';'	{ return SEMI }
+`,
+		"grammar.yal": `%token NUMBER PLUS SEMI
+%%
+program: program stmt
+	| stmt
+	;
+stmt: expr SEMI
+	| error SEMI
+	;
+expr: expr PLUS NUMBER
+	| NUMBER
+	;
+`,
+		"parser_test.go": `package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func parseErrors(t *testing.T, source string) SyntaxErrors {
+	_, err := Parse(NewLexer(strings.NewReader(source)))
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErrors SyntaxErrors
+	if !errors.As(err, &syntaxErrors) {
+		t.Fatalf("Expected SyntaxErrors but got %v", err)
+	}
+	return syntaxErrors
+}
+
+func TestRecovery(t *testing.T) {
+	if errs := parseErrors(t, "1 + 2;\n3;"); errs != nil {
+		t.Fatalf("Expected no errors but got %v", errs)
+	}
+
+	errs := parseErrors(t, "1 + + 2;\n3 + 4;\n5 $ 6;\n10;\n7 8 + 9;\n11;")
+	expected := []string{
+		"1:5: unexpected token \"+\" (" + TokenToHuman(PLUS) + ")",
+		"3:3: unexpected character '$'",
+		"3:5: unexpected token \"6\" (" + TokenToHuman(NUMBER) + ")",
+		"5:3: unexpected token \"8\" (" + TokenToHuman(NUMBER) + ")",
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors but got:\n%v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("Error %d = %s, want %s", i, err.Error(), expected[i])
+		}
+	}
+}
+
+func TestRecoveryWaitsForThreeShifts(t *testing.T) {
+	// Only ";" and "3" are shifted after recovering from the first error
+	errs := parseErrors(t, "1 + + 2;\n3 4;")
+	if len(errs) != 1 {
+		t.Fatalf("Expected only the first error but got %v", errs)
+	}
+}
+
+func TestRecoveryGivesUpAtEOF(t *testing.T) {
+	errs := parseErrors(t, "1 + 2;\n1 +")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "unexpected EOF") {
+		t.Fatalf("Expected only an EOF error but got %v", errs)
+	}
+}
+`,
+	})
+}
+
+// Generates a parser that builds the concrete syntax tree with -cst.
+func TestGeneratedParseTree(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Compiling the generated code is slow")
+	}
+
+	testGeneratedCode(t, map[string]string{
+		"tokens.lex": `{
+}
+
+rule gettoken =
+	[ \n]+	{ return IGNORE }
+	| [0-9]+	{ return NUMBER }
+	| '\+'	{ return PLUS }
+	| ';'	{ return SEMI }
+`,
+		"grammar.yal": `%token NUMBER PLUS SEMI
+%%
+program: program stmt
+	| stmt
+	;
+stmt: expr SEMI
+	| error SEMI
+	;
+expr: expr PLUS NUMBER { $$ = "ignored" }
+	| NUMBER
+	;
+`,
+		"tree_test.go": `package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseTree(t *testing.T) {
+	tree, err := ParseTree(NewLexer(strings.NewReader("1 + 2;\n3;")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ` + "`" + `<program> -> <program> <stmt> (1:1) [0, 9)
+  <program> -> <stmt> (1:1) [0, 6)
+    <stmt> -> <expr> SEMI (1:1) [0, 6)
+      <expr> -> <expr> PLUS NUMBER (1:1) [0, 5)
+        <expr> -> NUMBER (1:1) [0, 1)
+          NUMBER "1" (1:1) [0, 1)
+        PLUS "+" (1:3) [2, 3)
+        NUMBER "2" (1:5) [4, 5)
+      SEMI ";" (1:6) [5, 6)
+  <stmt> -> <expr> SEMI (2:1) [7, 9)
+    <expr> -> NUMBER (2:1) [7, 8)
+      NUMBER "3" (2:1) [7, 8)
+    SEMI ";" (2:2) [8, 9)
+` + "`" + `
+	if tree.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, tree.String())
+	}
+
+	contents, err := tree.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := TreeNode{}
+	if err := json.Unmarshal(contents, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != expected {
+		t.Fatalf("The JSON dump doesn't keep the tree:\n%s", contents)
+	}
+}
+
+func TestParseTreeKeepsErrorTokens(t *testing.T) {
+	tree, err := ParseTree(NewLexer(strings.NewReader("1 + + 2;\n3;")))
+	if err == nil {
+		t.Fatal("Expected a syntax error")
+	}
+
+	errorStmt := tree.Children[0].Children[0]
+	errorNode := errorStmt.Children[0]
+	// "1 +" is popped to recover from the second "+"
+	if errorNode.Type != ERROR_TOKEN_TYPE || errorNode.Start != 0 || errorNode.End != 4 {
+		t.Fatalf("Expected the error node to cover \"1 + \" but got:\n%s", tree.String())
+	}
+	if errorStmt.Start != 0 || errorStmt.End != 8 {
+		t.Fatalf("The recovered statement should cover up to the ; but got:\n%s", tree.String())
+	}
+}
+`,
+	}, "-cst")
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the contents as a .lex file and parses it, returning the path of the file for the diagnostics.
+func parseLexString(t *testing.T, contents string) (LexFileData, string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokens.lex")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	lex, err := LexParser(path)
+	return lex, path, err
+}
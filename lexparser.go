@@ -5,14 +5,45 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/Jose-Prince/UWUCompiler/lib"
 	"github.com/Jose-Prince/UWUCompiler/lib/regex"
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
 )
 
+// The mode the lexer starts on and the one of the rules without a `<MODE>` prefix
+const LEX_INITIAL_MODE = "INITIAL"
+
+// A change of mode done by the action of a rule with BEGIN(MODE), PUSH(MODE) or POP().
+type LexModeChange struct {
+	Type parsertypes.LexerModeChangeType
+	// Empty for POP()
+	Mode string
+}
+
+func (self LexModeChange) String() string {
+	return fmt.Sprintf("%s(%s)", self.Type, self.Mode)
+}
+
 type LexFileRule struct {
 	Regex string
 	Info  regex.DummyInfo
+	// The line of the lex file where the rule is defined
+	Line int
+	// The modes where the rule can match, nil if it's only on LEX_INITIAL_MODE
+	Modes []string
+	// The change of mode after the rule matches, already removed from the code of the action
+	ModeChange lib.Optional[LexModeChange]
+}
+
+// Checks if the rule can match while the lexer is on mode.
+func (self LexFileRule) InMode(mode string) bool {
+	if self.Modes == nil {
+		return mode == LEX_INITIAL_MODE
+	}
+	return slices.Contains(self.Modes, mode)
 }
 
 type LexFileData struct {
@@ -21,6 +52,13 @@ type LexFileData struct {
 	// The key represents the regex expanded to only have valid regex items
 	// The value is the go code to execute when the regex matches
 	Rules []LexFileRule
+	// The modes used by the rules, LEX_INITIAL_MODE is always the first one
+	Modes []string
+
+	// The line of the lex file where the header code starts
+	HeaderLine int
+	// The line of the lex file with the `rule` declaration
+	RulesLine int
 }
 
 func (fileData LexFileData) String() string {
@@ -32,6 +70,9 @@ func (fileData LexFileData) String() string {
 	b.WriteString(fileData.Footer)
 	b.WriteString("== RULES ==\n")
 	for _, rule := range fileData.Rules {
+		if rule.Modes != nil {
+			b.WriteString("<" + strings.Join(rule.Modes, ",") + "> ")
+		}
 		b.WriteString(rule.Regex)
 		b.WriteString(" -> ")
 		b.WriteString(rule.Info.String())
@@ -69,6 +110,9 @@ func (fileData LexFileData) String() string {
 //     | '{'           { return LBRACE }
 //     | '}'           { return RBRACE }
 //     | eof           { return nil }
+//     | '"'           { BEGIN(STRING) }
+//     | <STRING> [^'"']+  { return STRING_TEXT }
+//     | <STRING> '"'      { BEGIN(INITIAL) }
 //
 // {
 //     fmt.Println("Footer!")
@@ -85,10 +129,17 @@ func (fileData LexFileData) String() string {
 // 	}
 // }
 
-func LexParser(yalexFile string) (LexFileData, error) { // string represents the error
+// Parses a lex file.
+//
+// Just like the start conditions of flex, a rule prefixed with `<MODE>` or `<A,B>` only matches while the lexer
+// is on those modes and `<*>` matches on all of them, the rules without a prefix are on LEX_INITIAL_MODE.
+// The actions change the mode with BEGIN(MODE), PUSH(MODE) to come back later with POP(),
+// an action that only changes the mode skips the text it matched.
+//
+// Every problem found on the file is collected and returned as lib.Diagnostics.
+func LexParser(yalexFile string) (LexFileData, error) {
 	file, err := os.Open(yalexFile)
 	if err != nil {
-		fmt.Println("Error opening the file:", err)
 		return LexFileData{}, err
 	}
 	defer file.Close()
@@ -101,30 +152,87 @@ func LexParser(yalexFile string) (LexFileData, error) { // string represents the
 	scanner := bufio.NewScanner(file)
 	var header, footer strings.Builder
 	dummyRules := make(map[string]string)
-	// rules := make(map[string]regex.DummyInfo)
 	rules := []LexFileRule{}
-	state := 0 // 0: Reading header, 1: Reading rules, 2: Reading footer
+	modes := []string{LEX_INITIAL_MODE}
+	// The rules with `<*>` get all the modes once the file is read
+	allModesRules := []int{}
+	// The modes changed to by the actions are checked once all of them are known
+	type modeUse struct {
+		mode string
+		line int
+		col  int
+	}
+	modeUses := []modeUse{}
+	diagnostics := lib.Diagnostics{}
+	state := 0 // 0: Reading header, 1: Reading rules, 2: Reading footer, 3: Footer already read
 
 	// Regex to identify
 	ruleDeclaration := regexp.MustCompile(`(?i)\b(rule)\b`) // Ignores line "rule gettoken ="
 	ruleRegex := regexp.MustCompile(`^\s*let\s+([^\s=]+)\s*=\s*(.*)`)
 	regexBrackets := regexp.MustCompile(`'(?:[^']*)'|{([^}]*)}`) // Identifies what is inside {}
 
+	headerOpened := false
+	foundRuleDeclaration := false
+	// Where the header and footer blocks start, used to report them if they're never closed
+	headerLine := 0
+	footerLine := 0
+	rulesLine := 0
+	footerDepth := 0
+	lineNumber := 0
+
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+		rawLine := scanner.Text()
+		line := strings.TrimSpace(rawLine)
+		// Finds the column where a fragment of the line starts
+		colOf := func(fragment string) int {
+			return strings.Index(rawLine, fragment) + 1
+		}
 
 		// Header identification
-		if line == "{" && state == 0 {
-			continue
-		} else if line == "}" && state == 0 {
+		if state == 0 {
+			if line == "{" && !headerOpened {
+				headerOpened = true
+				headerLine = lineNumber
+				continue
+			} else if line == "}" && headerOpened {
+				state = 1
+				continue
+			} else if headerOpened {
+				header.WriteString(line + "\n")
+				continue
+			} else if line == "" {
+				continue
+			}
+
+			diagnostics.Add(yalexFile, lineNumber, colOf(line), "expected `{` to open the header")
 			state = 1
+		}
+
+		// Footer identification
+		if state == 2 {
+			if line == "}" && footerDepth == 0 {
+				state = 3
+				continue
+			}
+
+			footerDepth += strings.Count(line, "{") - strings.Count(line, "}")
+			footer.WriteString(line + "\n")
+			continue
+		} else if state == 3 {
+			if line != "" {
+				diagnostics.Add(yalexFile, lineNumber, colOf(line), "unexpected `%s` after the footer", line)
+			}
 			continue
-		} else if state == 0 {
-			header.WriteString(line + "\n")
+		}
+
+		if line == "" || isLexComment(line) {
 			continue
 		}
 
 		if ruleDeclaration.MatchString(line) {
+			foundRuleDeclaration = true
+			rulesLine = lineNumber
 			continue
 		}
 
@@ -132,111 +240,243 @@ func LexParser(yalexFile string) (LexFileData, error) { // string represents the
 		match := ruleRegex.FindStringSubmatch(line)
 
 		if len(match) > 2 {
+			checkLexRegex(&diagnostics, yalexFile, lineNumber, colOf(match[2]), match[2], dummyRules)
 			resolvedValue := resolveRule(match[2], dummyRules)
 			dummyRules[match[1]] = resolvedValue
 			continue
 		}
 
-		if line == "" {
+		if line == "{" {
+			state = 2
+			footerLine = lineNumber
 			continue
 		}
 
 		bracketsMatches := regexBrackets.FindAllStringSubmatch(line, -1)
+		lastMatch := ""
+		if len(bracketsMatches) > 0 {
+			lastMatch = bracketsMatches[len(bracketsMatches)-1][0]
+		}
 
-		if len(bracketsMatches) == 1 {
-			line = strings.Replace(line, bracketsMatches[0][0], "", 1)
-			line = strings.TrimSpace(line)
-			line = strings.Trim(line, "|")
-			line = strings.TrimSpace(line)
-			if line[0] == '\'' && line[len(line)-1] == '\'' {
-				line = line[1 : len(line)-1]
+		if !strings.HasPrefix(lastMatch, "{") {
+			if foundRuleDeclaration {
+				diagnostics.Add(yalexFile, lineNumber, colOf(line), "expected an action like `{ return TOKEN }` after the regex")
+			} else {
+				diagnostics.Add(yalexFile, lineNumber, colOf(line), "expected a `let` definition or a `rule` declaration")
 			}
-
-			code := strings.TrimSpace(bracketsMatches[0][1])
-
-			info.Code = code
-			info.Priority = index
-			info.Regex = line
-
-			rules = append(rules, LexFileRule{
-				Regex: line,
-				Info:  info,
-			})
-
-			index++
 			continue
-		} else if len(bracketsMatches) > 2 {
-			line = strings.Replace(line, bracketsMatches[len(bracketsMatches)-1][0], "", 1)
-			line = strings.TrimSpace(line)
-			line = strings.Trim(line, "|")
-			line = strings.TrimSpace(line)
-			if line[0] == '\'' && line[len(line)-1] == '\'' {
-				line = line[1 : len(line)-1]
-			}
+		}
 
-			code := strings.TrimSpace(bracketsMatches[len(bracketsMatches)-1][1])
+		actionCol := strings.LastIndex(rawLine, lastMatch) + 1
+		code := strings.TrimSpace(bracketsMatches[len(bracketsMatches)-1][1])
+		line = strings.Replace(line, lastMatch, "", 1)
+		line = strings.TrimSpace(line)
+		line = strings.Trim(line, "|")
+		line = strings.TrimSpace(line)
+
+		var ruleModes []string
+		allModes := false
+		if prefix := lexModesRegex.FindStringSubmatch(line); prefix != nil {
+			line = line[len(prefix[0]):]
+			if prefix[1] == "*" {
+				allModes = true
+			} else {
+				for _, mode := range strings.Split(prefix[1], ",") {
+					mode = strings.TrimSpace(mode)
+					ruleModes = append(ruleModes, mode)
+					if !slices.Contains(modes, mode) {
+						modes = append(modes, mode)
+					}
+				}
+			}
+		}
 
-			info.Code = code
-			info.Priority = index
-			info.Regex = line
+		modeChange := lib.CreateNull[LexModeChange]()
+		changes := lexModeChangeRegex.FindAllStringSubmatch(code, -1)
+		if len(changes) > 1 {
+			diagnostics.Add(yalexFile, lineNumber, actionCol, "an action can change the mode only once but found `%s` and `%s`", changes[0][1], changes[1][1])
+		} else if len(changes) == 1 {
+			change := LexModeChange{Type: parsertypes.MODE_POP}
+			switch changes[0][2] {
+			case "BEGIN":
+				change = LexModeChange{Type: parsertypes.MODE_BEGIN, Mode: changes[0][3]}
+			case "PUSH":
+				change = LexModeChange{Type: parsertypes.MODE_PUSH, Mode: changes[0][3]}
+			}
+			if change.Type == parsertypes.MODE_POP && changes[0][3] != "" {
+				diagnostics.Add(yalexFile, lineNumber, colOf(changes[0][1]), "`%s` doesn't take a mode, it comes back to the one saved by PUSH", changes[0][1])
+			} else if change.Type != parsertypes.MODE_POP && change.Mode == "" {
+				diagnostics.Add(yalexFile, lineNumber, colOf(changes[0][1]), "`%s` needs the mode to change to", changes[0][1])
+			} else if change.Type != parsertypes.MODE_POP {
+				modeUses = append(modeUses, modeUse{change.Mode, lineNumber, colOf(changes[0][1])})
+			}
 
-			rules = append(rules, LexFileRule{
-				Regex: line,
-				Info:  info,
-			})
+			modeChange = lib.CreateValue(change)
+			code = strings.TrimSpace(strings.Replace(code, changes[0][0], "", 1))
+			if code == "" {
+				code = "return IGNORE"
+			}
+		}
 
-			index++
+		if line == "" {
+			diagnostics.Add(yalexFile, lineNumber, actionCol, "missing regex before the action")
 			continue
+		}
 
-		} else if len(bracketsMatches) > 1 {
-			line = strings.Replace(line, bracketsMatches[1][0], "", 1)
-			line = strings.TrimSpace(line)
-			line = strings.Trim(line, "|")
-			line = strings.TrimSpace(line)
-
+		regexValue := line
+		regexCol := colOf(line)
+		if len(bracketsMatches) == 2 && bracketsMatches[0][0] == line {
+			// Either a quoted literal or a reference to a definition
+			isReference := line[0] == '{'
 			line = line[1 : len(line)-1]
 
-			regexValue := dummyRules[line]
-			if regexValue == "" {
-				regexValue = line
+			regexValue = line
+			if isReference && line != "" {
+				definition, found := dummyRules[line]
+				if found {
+					regexValue = definition
+				} else {
+					diagnostics.Add(yalexFile, lineNumber, colOf(line), "undefined definition `%s`", line)
+				}
+			} else if !isReference {
+				checkLexRegex(&diagnostics, yalexFile, lineNumber, regexCol+1, regexValue, dummyRules)
+			}
+		} else {
+			if regexValue[0] == '\'' && regexValue[len(regexValue)-1] == '\'' && len(regexValue) >= 2 {
+				regexValue = regexValue[1 : len(regexValue)-1]
 			}
-			code := strings.TrimSpace(bracketsMatches[1][1])
-			info.Code = code
-			info.Priority = index
-			info.Regex = regexValue
 
-			rules = append(rules, LexFileRule{
-				Regex: regexValue,
-				Info:  info,
-			})
+			checkLexRegex(&diagnostics, yalexFile, lineNumber, colOf(regexValue), regexValue, dummyRules)
+			regexValue = resolveRule(regexValue, dummyRules)
+		}
 
-			index++
+		// An empty regex can't be converted into an AFD
+		if regexValue == "" {
+			diagnostics.Add(yalexFile, lineNumber, regexCol, "empty regex")
 			continue
 		}
 
-		// Footer identification
-		if line == "{" && state == 1 {
-			state = 2
-			continue
-		} else if state == 2 {
-			if line == "}" {
-				continue
-			}
-			footer.WriteString(line + "\n")
+		info.Code = code
+		info.Priority = index
+		info.Regex = regexValue
+
+		if allModes {
+			allModesRules = append(allModesRules, len(rules))
 		}
+		rules = append(rules, LexFileRule{
+			Regex:      regexValue,
+			Info:       info,
+			Line:       lineNumber,
+			Modes:      ruleModes,
+			ModeChange: modeChange,
+		})
+
+		index++
 	}
 
 	if err := scanner.Err(); err != nil {
-		fmt.Println("Error scaning the file:", err)
+		return LexFileData{}, err
+	}
+
+	for _, idx := range allModesRules {
+		rules[idx].Modes = slices.Clone(modes)
+	}
+	for _, use := range modeUses {
+		if !slices.Contains(modes, use.mode) {
+			diagnostics.Add(yalexFile, use.line, use.col, "unknown mode `%s`, no rule is prefixed with `<%s>`", use.mode, use.mode)
+		}
 	}
 
+	if state == 0 && headerOpened {
+		diagnostics.Add(yalexFile, headerLine, 1, "unterminated header, expected a `}` line to close it")
+	} else if state == 2 {
+		diagnostics.Add(yalexFile, footerLine, 1, "unterminated footer, expected a `}` line to close it")
+	}
+
+	if len(rules) == 0 {
+		diagnostics.Add(yalexFile, max(lineNumber, 1), 1, "the lex file doesn't define any rule")
+	} else if !slices.ContainsFunc(rules, func(rule LexFileRule) bool { return rule.InMode(LEX_INITIAL_MODE) }) {
+		diagnostics.Add(yalexFile, max(rulesLine, 1), 1, "no rule matches on the %s mode where the lexer starts", LEX_INITIAL_MODE)
+	}
+
+	diagnostics.Sort()
 	fileData := LexFileData{
-		Header: header.String(),
-		Footer: footer.String(),
-		Rules:  rules,
+		Header:     header.String(),
+		Footer:     footer.String(),
+		Rules:      rules,
+		Modes:      modes,
+		HeaderLine: headerLine + 1,
+		RulesLine:  rulesLine,
+	}
+
+	return fileData, diagnostics.AsError()
+}
+
+// The rules that can match on mode, in the order of the file.
+func (fileData LexFileData) RulesOf(mode string) []LexFileRule {
+	rules := []LexFileRule{}
+	for _, rule := range fileData.Rules {
+		if rule.InMode(mode) {
+			rules = append(rules, rule)
+		}
 	}
+	return rules
+}
+
+// Matches the `<MODE>`, `<A,B>` or `<*>` prefix of a rule
+var lexModesRegex = regexp.MustCompile(`^<\s*(\*|[A-Za-z_][A-Za-z0-9_]*(?:\s*,\s*[A-Za-z_][A-Za-z0-9_]*)*)\s*>\s*`)
+
+// Matches BEGIN(MODE), PUSH(MODE) and POP() inside the action of a rule
+var lexModeChangeRegex = regexp.MustCompile(`(\b(BEGIN|PUSH|POP)\(\s*([A-Za-z_][A-Za-z0-9_]*)?\s*\))\s*;?`)
+
+// Lines like `(* Keywords *)` are comments.
+func isLexComment(line string) bool {
+	return strings.HasPrefix(line, "(*") && strings.HasSuffix(line, "*)")
+}
+
+// Matches references to definitions like `{digit}`
+var definitionReferenceRegex = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
 
-	return fileData, nil
+// Reports unbalanced brackets or parenthesis and references to undefined definitions.
+//
+// col is the column of the line where the regex starts.
+func checkLexRegex(diagnostics *lib.Diagnostics, file string, line int, col int, rx string, definitions map[string]string) {
+	for _, match := range definitionReferenceRegex.FindAllStringSubmatchIndex(rx, -1) {
+		name := rx[match[2]:match[3]]
+		if _, found := definitions[name]; !found {
+			diagnostics.Add(file, line, col+match[2], "undefined definition `%s`", name)
+		}
+	}
+
+	openParens := []int{}
+	bracketStart := -1
+	for i := 0; i < len(rx); i++ {
+		switch {
+		case rx[i] == '\\':
+			i++
+		case bracketStart != -1:
+			if rx[i] == ']' {
+				bracketStart = -1
+			}
+		case rx[i] == '[':
+			bracketStart = i
+		case rx[i] == '(':
+			openParens = append(openParens, i)
+		case rx[i] == ')':
+			if len(openParens) == 0 {
+				diagnostics.Add(file, line, col+i, "unmatched `)`")
+			} else {
+				openParens = openParens[:len(openParens)-1]
+			}
+		}
+	}
+
+	if bracketStart != -1 {
+		diagnostics.Add(file, line, col+bracketStart, "unterminated bracket")
+	}
+	for _, paren := range openParens {
+		diagnostics.Add(file, line, col+paren, "unclosed `(`")
+	}
 }
 
 // Replace rules into other rules
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/Jose-Prince/UWUCompiler/lib"
 	reg "github.com/Jose-Prince/UWUCompiler/lib/regex"
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
 )
 
 func TestLexParser(t *testing.T) {
@@ -24,10 +27,10 @@ func TestLexParser(t *testing.T) {
 	TOKENA int = iota
 	TOKENB
 )`,
-				Rule: map[string]reg.DummyInfo{
-					"[ \\t\\n]": {Regex: "[ \\t\\n]", Code: "", Priority: 1},
-					"abc":       {Regex: "abc", Code: "return TOKENA", Priority: 2},
-					"(abc)|c":   {Regex: "(abc)|c", Code: "return TOKENB", Priority: 3},
+				Rules: []LexFileRule{
+					{Regex: "[ \\t\\n]", Info: reg.DummyInfo{Regex: "[ \\t\\n]", Code: "", Priority: 1}, Line: 15},
+					{Regex: "abc", Info: reg.DummyInfo{Regex: "abc", Code: "return TOKENA", Priority: 2}, Line: 16},
+					{Regex: "(abc)|c", Info: reg.DummyInfo{Regex: "(abc)|c", Code: "return TOKENB", Priority: 3}, Line: 17},
 				},
 			}, // Define el valor esperado para un archivo válido
 		},
@@ -38,14 +41,14 @@ func TestLexParser(t *testing.T) {
 			want: LexFileData{
 				Header: "import myToken\n",
 				Footer: "",
-				Rule: map[string]reg.DummyInfo{
-					"[0-9]+": {Regex: "[0-9]+", Code: "return NUMBER", Priority: 1},
-					"\\+":    {Regex: "\\+", Code: "return PLUS", Priority: 2},
-					"-":      {Regex: "-", Code: "return MINUS", Priority: 3},
-					"\\*":    {Regex: "\\*", Code: "return TIMES", Priority: 4},
-					"/":      {Regex: "/", Code: "return DIV", Priority: 5},
-					"\\(":    {Regex: "\\(", Code: "return LPAREN", Priority: 6},
-					"\\)":    {Regex: "\\)", Code: "return RPAREN", Priority: 7},
+				Rules: []LexFileRule{
+					{Regex: "[0-9]+", Info: reg.DummyInfo{Regex: "[0-9]+", Code: "return NUMBER", Priority: 1}, Line: 13},
+					{Regex: "\\+", Info: reg.DummyInfo{Regex: "\\+", Code: "return PLUS", Priority: 2}, Line: 14},
+					{Regex: "-", Info: reg.DummyInfo{Regex: "-", Code: "return MINUS", Priority: 3}, Line: 15},
+					{Regex: "\\*", Info: reg.DummyInfo{Regex: "\\*", Code: "return TIMES", Priority: 4}, Line: 16},
+					{Regex: "/", Info: reg.DummyInfo{Regex: "/", Code: "return DIV", Priority: 5}, Line: 17},
+					{Regex: "\\(", Info: reg.DummyInfo{Regex: "\\(", Code: "return LPAREN", Priority: 6}, Line: 18},
+					{Regex: "\\)", Info: reg.DummyInfo{Regex: "\\)", Code: "return RPAREN", Priority: 7}, Line: 19},
 				},
 			}, // Define el valor esperado para un archivo válido
 		},
@@ -60,7 +63,7 @@ func TestLexParser(t *testing.T) {
 			}
 
 			// Verifica si se produjo un error
-			if (got.Header == "" && got.Footer == "" && len(got.Rule) == 0) != tt.wantErr {
+			if (got.Header == "" && got.Footer == "" && len(got.Rules) == 0) != tt.wantErr {
 				t.Errorf("LexParser() error = %v, wantErr %v", got, tt.wantErr)
 			}
 
@@ -73,16 +76,14 @@ func TestLexParser(t *testing.T) {
 				t.Errorf("LexParser() Footer = %v, want %v", got.Footer, tt.want.Footer)
 			}
 
-			// Compara las reglas ignorando el orden del mapa
-			if len(got.Rule) != len(tt.want.Rule) {
-				t.Errorf("LexParser() Rule length = %d, want %d", len(got.Rule), len(tt.want.Rule))
+			// Compara las reglas en el orden en que fueron definidas
+			if len(got.Rules) != len(tt.want.Rules) {
+				t.Errorf("LexParser() Rules length = %d, want %d", len(got.Rules), len(tt.want.Rules))
 			} else {
-				for key, wantValue := range tt.want.Rule {
-					gotValue, exists := got.Rule[key]
-					if !exists {
-						t.Errorf("LexParser() missing key in Rule: %v", key)
-					} else if !reflect.DeepEqual(gotValue, wantValue) {
-						t.Errorf("LexParser() Rule[%v] = %v, want %v", key, gotValue, wantValue)
+				for i, wantValue := range tt.want.Rules {
+					gotValue := got.Rules[i]
+					if !reflect.DeepEqual(gotValue, wantValue) {
+						t.Errorf("LexParser() Rules[%d] = %v, want %v", i, gotValue, wantValue)
 					}
 				}
 			}
@@ -126,3 +127,122 @@ func TestResolveRule(t *testing.T) {
 		})
 	}
 }
+
+func TestLexParserDiagnostics(t *testing.T) {
+	_, path, err := parseLexString(t, `{
+}
+
+let digit = [0-9
+let number = {digits}+
+
+rule gettoken =
+	{digit}	{ return NUMBER }
+	| (ab	{ return IGNORE }
+	| { return PLUS }
+	| '+'
+	| ''	{ return A }
+	| '(a'	{ return B }
+	| {}	{ return C }
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":4:13: unterminated bracket",
+		path + ":5:15: undefined definition `digits`",
+		path + ":9:4: unclosed `(`",
+		path + ":10:4: missing regex before the action",
+		path + ":11:2: expected an action like `{ return TOKEN }` after the regex",
+		path + ":12:4: empty regex",
+		path + ":13:5: unclosed `(`",
+		path + ":14:4: empty regex",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestLexParserModes(t *testing.T) {
+	got, _, err := parseLexString(t, `{
+}
+
+rule gettoken =
+	'"'	{ PUSH(STRING) }
+	| <STRING> [^"\\]+	{ return TEXT }
+	| <STRING,COMMENT> '"'	{ POP(); return END }
+	| <*> \n	{ BEGIN(INITIAL) }
+	| a	{ return A }
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{LEX_INITIAL_MODE, "STRING", "COMMENT"}; !reflect.DeepEqual(got.Modes, expected) {
+		t.Errorf("Expected the modes %v but got %v", expected, got.Modes)
+	}
+
+	expected := []struct {
+		regex  string
+		code   string
+		modes  []string
+		change lib.Optional[LexModeChange]
+	}{
+		{`"`, "return IGNORE", nil, lib.CreateValue(LexModeChange{Type: parsertypes.MODE_PUSH, Mode: "STRING"})},
+		{`[^"\\]+`, "return TEXT", []string{"STRING"}, lib.CreateNull[LexModeChange]()},
+		{`"`, "return END", []string{"STRING", "COMMENT"}, lib.CreateValue(LexModeChange{Type: parsertypes.MODE_POP})},
+		{`\n`, "return IGNORE", []string{LEX_INITIAL_MODE, "STRING", "COMMENT"}, lib.CreateValue(LexModeChange{Type: parsertypes.MODE_BEGIN, Mode: LEX_INITIAL_MODE})},
+		{"a", "return A", nil, lib.CreateNull[LexModeChange]()},
+	}
+	if len(got.Rules) != len(expected) {
+		t.Fatalf("Expected %d rules but got %d: %v", len(expected), len(got.Rules), got)
+	}
+	for i, want := range expected {
+		rule := got.Rules[i]
+		if rule.Regex != want.regex || rule.Info.Code != want.code || !reflect.DeepEqual(rule.Modes, want.modes) || rule.ModeChange != want.change {
+			t.Errorf("Rule %d: expected %q { %s } on %v changing to %v but got %q { %s } on %v changing to %v",
+				i, want.regex, want.code, want.modes, want.change, rule.Regex, rule.Info.Code, rule.Modes, rule.ModeChange)
+		}
+	}
+
+	if !got.Rules[4].InMode(LEX_INITIAL_MODE) || got.Rules[4].InMode("STRING") || !got.Rules[3].InMode("COMMENT") {
+		t.Errorf("Expected the rules without a prefix to only be on %s", LEX_INITIAL_MODE)
+	}
+}
+
+func TestLexParserModeDiagnostics(t *testing.T) {
+	_, path, err := parseLexString(t, `{
+}
+
+rule gettoken =
+	a	{ BEGIN(COMMENT) }
+	| <STRING> b	{ PUSH(INITIAL); POP() }
+	| c	{ POP(STRING) }
+	| d	{ BEGIN() }
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":5:6: unknown mode `COMMENT`, no rule is prefixed with `<COMMENT>`",
+		path + ":6:15: an action can change the mode only once but found `PUSH(INITIAL)` and `POP()`",
+		path + ":7:8: `POP(STRING)` doesn't take a mode, it comes back to the one saved by PUSH",
+		path + ":8:8: `BEGIN()` needs the mode to change to",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
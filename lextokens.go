@@ -0,0 +1,271 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+	"github.com/Jose-Prince/UWUCompiler/lib/grammar"
+	"github.com/Jose-Prince/UWUCompiler/lib/regex"
+)
+
+// A token type constant written on the generated code.
+type tokenConstant struct {
+	Name string
+	Id   int
+}
+
+var goIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Matches the token returned by the code of a lex rule, like `return NUMBER`
+var lexReturnRegex = regexp.MustCompile(`\breturn\s+([A-Za-z_][A-Za-z0-9_]*)\b`)
+
+// Matches a declaration like `NUMBER int = iota` or `const NUMBER = 0`, capturing the name
+var constantDeclarationRegex = regexp.MustCompile(`^\s*(?:const\s+|var\s+)?([A-Za-z_][A-Za-z0-9_]*)(?:\s+[A-Za-z_][A-Za-z0-9_]*)?\s*(?:=.*)?(?:\s*//.*)?$`)
+
+// The name of the constant of every literal of the grammar, like LITERAL_PLUS for '+'.
+//
+// A literal is numbered if another terminal already has its name.
+func literalConstants(g *grammar.Grammar) map[grammar.GrammarToken]string {
+	taken := lib.NewSet[string]()
+	literals := []grammar.GrammarToken{}
+	for terminal := range g.Terminals {
+		taken.Add(terminal.Symbol())
+		if _, isLiteral := terminal.LiteralText(); isLiteral {
+			literals = append(literals, terminal)
+		}
+	}
+	slices.SortFunc(literals, func(a, b grammar.GrammarToken) int {
+		return g.TokenToParserType(&a) - g.TokenToParserType(&b)
+	})
+
+	names := make(map[grammar.GrammarToken]string)
+	for _, literal := range literals {
+		text, _ := literal.LiteralText()
+		base := "LITERAL_" + grammar.LiteralName(text)
+		name := base
+		for i := 2; taken.Contains(name); i++ {
+			name = base + strconv.Itoa(i)
+		}
+		taken.Add(name)
+		names[literal] = name
+	}
+
+	return names
+}
+
+// The constants of every terminal of the grammar, sorted by id.
+//
+// The ids are the same ones the parsing table uses, so the lexer can't disagree with the parser.
+func getTokenConstants(g *grammar.Grammar) []tokenConstant {
+	literals := literalConstants(g)
+	constants := []tokenConstant{}
+	for terminal := range g.Terminals {
+		name := terminal.Symbol()
+		if literal, found := literals[terminal]; found {
+			name = literal
+		}
+		// The error token is written as ERROR_TOKEN_TYPE since error is a Go builtin
+		if terminal.IsEnd || name == grammar.ERROR_TOKEN_NAME || !goIdentifierRegex.MatchString(name) {
+			continue
+		}
+
+		constants = append(constants, tokenConstant{Name: name, Id: int(g.TokenToParserType(&terminal))})
+	}
+
+	slices.SortFunc(constants, func(a, b tokenConstant) int {
+		return a.Id - b.Id
+	})
+	return constants
+}
+
+// Checks that the lex file and the grammar agree on the token names.
+//
+// Every `return X` of a lex rule must name a terminal of the grammar (or IGNORE),
+// every terminal used on a production must be returned by some lex rule (except error)
+// and the header can't declare the token constants since they're generated from the grammar.
+func checkLexTokens(lexPath string, lex *LexFileData, g *grammar.Grammar) error {
+	diagnostics := lib.Diagnostics{}
+
+	// Only used to find the column of each problem
+	contents, _ := os.ReadFile(lexPath)
+	lines := strings.Split(string(contents), "\n")
+	colOf := func(line int, fragment string) int {
+		if line < 1 || line > len(lines) {
+			return 1
+		}
+		return strings.Index(lines[line-1], fragment) + 1
+	}
+
+	// The literals are returned with their constants
+	literals := literalConstants(g)
+	nameOf := func(terminal grammar.GrammarToken) string {
+		if literal, found := literals[terminal]; found {
+			return literal
+		}
+		return terminal.Symbol()
+	}
+
+	terminalNames := lib.NewSet[string]()
+	for terminal := range g.Terminals {
+		if !terminal.IsEnd && terminal.Symbol() != grammar.ERROR_TOKEN_NAME {
+			terminalNames.Add(nameOf(terminal))
+		}
+	}
+
+	for i, line := range strings.Split(lex.Header, "\n") {
+		match := constantDeclarationRegex.FindStringSubmatch(line)
+		if match != nil && terminalNames.Contains(match[1]) {
+			lineNumber := lex.HeaderLine + i
+			diagnostics.Add(lexPath, lineNumber, colOf(lineNumber, match[1]), "token `%s` is generated from the grammar, remove its declaration from the header", match[1])
+		}
+	}
+
+	returned := lib.NewSet[string]()
+	for _, rule := range lex.Rules {
+		for _, match := range lexReturnRegex.FindAllStringSubmatch(rule.Info.Code, -1) {
+			name := match[1]
+			returned.Add(name)
+			if name == grammar.ERROR_TOKEN_NAME {
+				diagnostics.Add(lexPath, rule.Line, colOf(rule.Line, match[0]), "`%s` is reserved for error recovery, a rule can't return it", name)
+			} else if name != "IGNORE" && !terminalNames.Contains(name) {
+				diagnostics.Add(lexPath, rule.Line, colOf(rule.Line, match[0]), "`%s` is not a token declared on the grammar", name)
+			}
+		}
+	}
+
+	missing := lib.NewSet[string]()
+	for _, rule := range g.Rules {
+		for _, token := range rule.Production {
+			name := nameOf(token)
+			if token.IsTerminal() && terminalNames.Contains(name) && !returned.Contains(name) && missing.Add(name) {
+				diagnostics.Add(lexPath, max(lex.RulesLine, 1), 1, "no rule returns the token `%s` used by the grammar", name)
+			}
+		}
+	}
+
+	diagnostics.Sort()
+	return diagnostics.AsError()
+}
+
+// The characters that are operators on the regexes of the lex rules
+const lexRegexOperators = `|*+?()[\\`
+
+// Escapes text so a lex regex matches it as it is.
+func escapeLexLiteral(text string) string {
+	b := strings.Builder{}
+	for _, r := range text {
+		switch {
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case strings.ContainsRune(lexRegexOperators, r):
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// The text a lex regex matches if it only matches that text, like `\+\+` or `while`.
+func lexRegexLiteral(regex string) (string, bool) {
+	b := strings.Builder{}
+	runes := []rune(regex)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r != '\\' {
+			if strings.ContainsRune(lexRegexOperators, r) {
+				return "", false
+			}
+			b.WriteRune(r)
+			continue
+		}
+
+		if i+1 == len(runes) {
+			return "", false
+		}
+		i++
+		switch runes[i] {
+		case 'n':
+			b.WriteRune('\n')
+		case 't':
+			b.WriteRune('\t')
+		case 'r':
+			b.WriteRune('\r')
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+
+	return b.String(), b.Len() > 0
+}
+
+// Adds a lex rule for every literal of the grammar that no rule of the lex file returns.
+//
+// The new rules go before the ones of the lex file, so a keyword like 'while' wins over a rule for identifiers.
+// They only match on LEX_INITIAL_MODE, the mode of the rules without a prefix.
+// A rule of the lex file that matches a literal but returns another token could never match, so it's reported.
+func addLiteralRules(lexPath string, lex *LexFileData, g *grammar.Grammar) error {
+	diagnostics := lib.Diagnostics{}
+	literals := literalConstants(g)
+	if len(literals) == 0 {
+		return nil
+	}
+
+	returned := lib.NewSet[string]()
+	for _, rule := range lex.Rules {
+		for _, match := range lexReturnRegex.FindAllStringSubmatch(rule.Info.Code, -1) {
+			returned.Add(match[1])
+		}
+	}
+
+	byText := make(map[string]grammar.GrammarToken)
+	tokens := []grammar.GrammarToken{}
+	for token, name := range literals {
+		text, _ := token.LiteralText()
+		byText[text] = token
+		if !returned.Contains(name) {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b grammar.GrammarToken) int {
+		return g.TokenToParserType(&a) - g.TokenToParserType(&b)
+	})
+
+	// The rules of the literals are only added to the initial mode
+	for _, rule := range lex.RulesOf(LEX_INITIAL_MODE) {
+		text, isLiteral := lexRegexLiteral(rule.Regex)
+		token, found := byText[text]
+		if !isLiteral || !found || !slices.Contains(tokens, token) {
+			continue
+		}
+
+		diagnostics.Add(lexPath, rule.Line, 1, "the literal %s of the grammar matches the same text as this rule, return %s from it instead", token.Symbol(), literals[token])
+	}
+
+	rules := []LexFileRule{}
+	for _, token := range tokens {
+		text, _ := token.LiteralText()
+		regexValue := escapeLexLiteral(text)
+		rules = append(rules, LexFileRule{
+			Regex: regexValue,
+			Info:  regex.DummyInfo{Regex: regexValue, Code: "return " + literals[token]},
+		})
+	}
+	lex.Rules = append(rules, lex.Rules...)
+	for i := range lex.Rules {
+		lex.Rules[i].Info.Priority = uint(i + 1)
+	}
+
+	diagnostics.Sort()
+	return diagnostics.AsError()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+	"github.com/Jose-Prince/UWUCompiler/lib/grammar"
+)
+
+// The grammar of most of the token tests
+const tokensTestGrammar = `%token NUMBER PLUS TIMES
+%%
+expr: expr PLUS NUMBER
+	| NUMBER
+	;
+`
+
+func writeTokensTestFiles(t *testing.T, yalContents string, lexContents string) (string, LexFileData, grammar.Grammar) {
+	lex, lexPath, err := parseLexString(t, lexContents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yalPath := filepath.Join(t.TempDir(), "grammar.yal")
+	if err := os.WriteFile(yalPath, []byte(yalContents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := grammar.ParseYalFile(yalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lexPath, lex, g
+}
+
+func TestCheckLexTokens(t *testing.T) {
+	lexPath, lex, g := writeTokensTestFiles(t, tokensTestGrammar, `{
+}
+
+rule gettoken =
+	[ \t]+	{ return IGNORE }
+	| [0-9]+	{ return NUMBER }
+	| '\+'	{ return PLUS }
+`)
+
+	if err := checkLexTokens(lexPath, &lex, &g); err != nil {
+		t.Fatalf("Expected no problems but got:\n%s", err)
+	}
+}
+
+func TestCheckLexTokensDiagnostics(t *testing.T) {
+	lexPath, lex, g := writeTokensTestFiles(t, tokensTestGrammar, `{
+const (
+	NUMBER int = iota
+)
+}
+
+rule gettoken =
+	[ \t]+	{ return IGNORE }
+	| [0-9]+	{ return NUMBER }
+	| '-'	{ return MINUS }
+	| '!'	{ return error }
+`)
+
+	err := checkLexTokens(lexPath, &lex, &g)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		lexPath + ":3:2: token `NUMBER` is generated from the grammar, remove its declaration from the header",
+		lexPath + ":7:1: no rule returns the token `PLUS` used by the grammar",
+		lexPath + ":10:10: `MINUS` is not a token declared on the grammar",
+		lexPath + ":11:10: `error` is reserved for error recovery, a rule can't return it",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestGetTokenConstants(t *testing.T) {
+	_, _, g := writeTokensTestFiles(t, tokensTestGrammar, "{\n}\n\nrule gettoken =\n\t[0-9]+\t{ return NUMBER }\n")
+
+	constants := getTokenConstants(&g)
+	names := lib.NewSet[string]()
+	for i, constant := range constants {
+		names.Add(constant.Name)
+		if i > 0 && constants[i-1].Id >= constant.Id {
+			t.Errorf("The constants should be sorted by id: %v", constants)
+		}
+
+		token := grammar.NewTerminalToken(constant.Name)
+		if constant.Id != int(g.TokenToParserType(&token)) {
+			t.Errorf("The id of %s doesn't match the grammar", constant.Name)
+		}
+	}
+
+	for _, name := range []string{"NUMBER", "PLUS", "TIMES"} {
+		if !names.Contains(name) {
+			t.Errorf("Missing the constant for %s: %v", name, constants)
+		}
+	}
+}
+
+// The grammar of the literal tests, with a declared token that has the name of a literal constant
+const literalsTestGrammar = `%token NUMBER ID LITERAL_PLUS
+%%
+stmt: 'while' expr "==" expr '(' ')' | LITERAL_PLUS ;
+expr: expr '+' NUMBER | ID ;
+`
+
+func TestAddLiteralRules(t *testing.T) {
+	lexPath, lex, g := writeTokensTestFiles(t, literalsTestGrammar, `{
+}
+
+rule gettoken =
+	[ \t]+	{ return IGNORE }
+	| [0-9]+	{ return NUMBER }
+	| [a-z]+	{ return ID }
+	| '\('	{ return LITERAL_LPAREN }
+	| 'plus'	{ return LITERAL_PLUS }
+`)
+
+	if err := addLiteralRules(lexPath, &lex, &g); err != nil {
+		t.Fatalf("Expected no problems but got:\n%s", err)
+	}
+	if err := checkLexTokens(lexPath, &lex, &g); err != nil {
+		t.Fatalf("Expected the new rules to return every literal but got:\n%s", err)
+	}
+
+	// The literal '(' is already returned by a rule of the lex file
+	expected := []struct {
+		regex string
+		code  string
+	}{
+		{"while", "return LITERAL_WHILE"},
+		{"==", "return LITERAL_EQUAL_EQUAL"},
+		{`\)`, "return LITERAL_RPAREN"},
+		{`\+`, "return LITERAL_PLUS2"},
+		{`[ \t]+`, "return IGNORE"},
+	}
+	for i, rule := range expected {
+		got := lex.Rules[i]
+		if got.Regex != rule.regex || got.Info.Regex != rule.regex || got.Info.Code != rule.code {
+			t.Errorf("Rule %d: expected %s { %s } but got %s { %s }", i, rule.regex, rule.code, got.Regex, got.Info.Code)
+		}
+	}
+	for i, rule := range lex.Rules {
+		if rule.Info.Priority != uint(i+1) {
+			t.Errorf("Rule %d: expected the priority %d but got %d", i, i+1, rule.Info.Priority)
+		}
+	}
+
+	constants := getTokenConstants(&g)
+	found := false
+	for _, constant := range constants {
+		token := grammar.NewLiteralToken("==")
+		if constant.Name == "LITERAL_EQUAL_EQUAL" {
+			found = constant.Id == int(g.TokenToParserType(&token))
+		}
+	}
+	if !found {
+		t.Errorf("Expected a constant for \"==\" with its id but got %v", constants)
+	}
+}
+
+func TestAddLiteralRulesShadowed(t *testing.T) {
+	lexPath, lex, g := writeTokensTestFiles(t, literalsTestGrammar, `{
+}
+
+rule gettoken =
+	[0-9]+	{ return NUMBER }
+	| '\+'	{ return ID }
+	| '=='	{ return LITERAL_EQUAL_EQUAL }
+`)
+
+	err := addLiteralRules(lexPath, &lex, &g)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := lexPath + ":6:1: the literal '+' of the grammar matches the same text as this rule, return LITERAL_PLUS2 from it instead"
+	if len(diagnostics) != 1 || diagnostics[0].String() != expected {
+		t.Errorf("Expected the diagnostic\n%s\nbut got\n%s", expected, diagnostics.Error())
+	}
+}
+
+func TestLexLiteralRegex(t *testing.T) {
+	for _, text := range []string{"+", "while", "(*)", "[x]", "a|b?", `\`, "\n\t"} {
+		escaped := escapeLexLiteral(text)
+		if read, isLiteral := lexRegexLiteral(escaped); !isLiteral || read != text {
+			t.Errorf("Expected %q to be read back from %q but got %q", text, escaped, read)
+		}
+	}
+
+	for _, regexValue := range []string{"[0-9]+", "a|b", "(ab)", ""} {
+		if _, isLiteral := lexRegexLiteral(regexValue); isLiteral {
+			t.Errorf("Expected %q not to be a literal", regexValue)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package lib
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The biggest string or list a BinaryReader accepts, so a corrupted file can't make it allocate everything.
+const MAX_BINARY_LENGTH int = 1 << 28
+
+// Writes values in a compact binary format, integers are written as varints.
+//
+// The first error is kept and every write after it is ignored, so it only needs to be checked once with Flush.
+type BinaryWriter struct {
+	writer *bufio.Writer
+	buffer [binary.MaxVarintLen64]byte
+	err    error
+}
+
+func NewBinaryWriter(writer io.Writer) *BinaryWriter {
+	return &BinaryWriter{writer: bufio.NewWriter(writer)}
+}
+
+// Writes the magic bytes that identify the format and its version.
+func (self *BinaryWriter) WriteHeader(magic string, version int) {
+	self.write([]byte(magic))
+	self.WriteInt(version)
+}
+
+func (self *BinaryWriter) WriteInt(value int) {
+	n := binary.PutVarint(self.buffer[:], int64(value))
+	self.write(self.buffer[:n])
+}
+
+func (self *BinaryWriter) WriteBool(value bool) {
+	if value {
+		self.write([]byte{1})
+	} else {
+		self.write([]byte{0})
+	}
+}
+
+func (self *BinaryWriter) WriteString(value string) {
+	self.WriteInt(len(value))
+	self.write([]byte(value))
+}
+
+func (self *BinaryWriter) write(bytes []byte) {
+	if self.err == nil {
+		_, self.err = self.writer.Write(bytes)
+	}
+}
+
+// Writes everything that's still buffered and returns the first error found.
+func (self *BinaryWriter) Flush() error {
+	if self.err == nil {
+		self.err = self.writer.Flush()
+	}
+
+	return self.err
+}
+
+// Reads the values written by a BinaryWriter.
+//
+// Just like the writer, the first error is kept and every read after it returns the zero value.
+type BinaryReader struct {
+	reader *bufio.Reader
+	err    error
+}
+
+func NewBinaryReader(reader io.Reader) *BinaryReader {
+	return &BinaryReader{reader: bufio.NewReader(reader)}
+}
+
+// Reads the header of the format identified by magic and returns its version.
+func (self *BinaryReader) ReadHeader(magic string) int {
+	found := make([]byte, len(magic))
+	self.read(found)
+	if self.err == nil && string(found) != magic {
+		self.err = fmt.Errorf("expected a file starting with %q but found %q", magic, found)
+	}
+
+	return self.ReadInt()
+}
+
+func (self *BinaryReader) ReadInt() int {
+	if self.err != nil {
+		return 0
+	}
+
+	value, err := binary.ReadVarint(self.reader)
+	self.setErr(err)
+	return int(value)
+}
+
+// Reads the length of a string or list, failing if it can't be right.
+func (self *BinaryReader) ReadLength() int {
+	length := self.ReadInt()
+	if self.err == nil && (length < 0 || length > MAX_BINARY_LENGTH) {
+		self.err = fmt.Errorf("invalid length %d", length)
+	}
+
+	if self.err != nil {
+		return 0
+	}
+	return length
+}
+
+func (self *BinaryReader) ReadBool() bool {
+	value := []byte{0}
+	self.read(value)
+	return value[0] != 0
+}
+
+func (self *BinaryReader) ReadString() string {
+	value := make([]byte, self.ReadLength())
+	self.read(value)
+	return string(value)
+}
+
+func (self *BinaryReader) read(bytes []byte) {
+	if self.err == nil {
+		_, err := io.ReadFull(self.reader, bytes)
+		self.setErr(err)
+	}
+}
+
+func (self *BinaryReader) setErr(err error) {
+	// Running out of data in the middle of a value means the file is truncated
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	self.err = err
+}
+
+// The first error found while reading.
+func (self *BinaryReader) Err() error {
+	return self.err
+}
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewBinaryWriter(&b)
+	w.WriteHeader("TEST", 3)
+	w.WriteInt(-42)
+	w.WriteInt(1 << 40)
+	w.WriteBool(true)
+	w.WriteString("ñandú")
+	w.WriteString("")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewBinaryReader(&b)
+	if version := r.ReadHeader("TEST"); version != 3 {
+		t.Errorf("Expected version 3 but got %d", version)
+	}
+	if value := r.ReadInt(); value != -42 {
+		t.Errorf("Expected -42 but got %d", value)
+	}
+	if value := r.ReadInt(); value != 1<<40 {
+		t.Errorf("Expected %d but got %d", 1<<40, value)
+	}
+	if !r.ReadBool() {
+		t.Error("Expected true")
+	}
+	if value := r.ReadString(); value != "ñandú" {
+		t.Errorf("Expected ñandú but got %q", value)
+	}
+	if value := r.ReadString(); value != "" {
+		t.Errorf("Expected an empty string but got %q", value)
+	}
+	if r.Err() != nil {
+		t.Fatal(r.Err())
+	}
+
+	r.ReadInt()
+	if !errors.Is(r.Err(), io.ErrUnexpectedEOF) {
+		t.Fatalf("Expected an unexpected EOF but got %v", r.Err())
+	}
+}
+
+func TestBinaryReaderRejectsOtherMagic(t *testing.T) {
+	r := NewBinaryReader(bytes.NewReader([]byte("NOPE\x02")))
+	r.ReadHeader("TEST")
+	if r.Err() == nil {
+		t.Fatal("Expected an error reading another format")
+	}
+}
+
+func TestBinaryReaderRejectsInvalidLengths(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewBinaryWriter(&b)
+	w.WriteInt(-1)
+	w.Flush()
+
+	r := NewBinaryReader(&b)
+	if value := r.ReadString(); value != "" || r.Err() == nil {
+		t.Fatalf("Expected an error reading a negative length but got %q", value)
+	}
+}
@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A problem found on a source file.
+type Diagnostic struct {
+	File string
+	// Both the line and the column start counting from 1
+	Line    int
+	Col     int
+	Message string
+}
+
+// Formats the diagnostic like compilers do: `file:line:col: message`
+func (self Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", self.File, self.Line, self.Col, self.Message)
+}
+
+// All the problems found on a source file.
+//
+// It's an error so parsers can return all of them at once.
+type Diagnostics []Diagnostic
+
+func (self *Diagnostics) Add(file string, line int, col int, format string, args ...any) {
+	*self = append(*self, Diagnostic{
+		File:    file,
+		Line:    line,
+		Col:     col,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Sorts the diagnostics by the position where they were found.
+func (self Diagnostics) Sort() {
+	slices.SortStableFunc(self, func(a, b Diagnostic) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Col - b.Col
+	})
+}
+
+func (self Diagnostics) Error() string {
+	lines := make([]string, 0, len(self))
+	for _, diagnostic := range self {
+		lines = append(lines, diagnostic.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Returns the diagnostics as an error, or nil if there aren't any.
+func (self Diagnostics) AsError() error {
+	if len(self) == 0 {
+		return nil
+	}
+
+	return self
+}
@@ -0,0 +1,27 @@
+package lib
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	diagnostics := Diagnostics{}
+	if diagnostics.AsError() != nil {
+		t.Fatalf("No diagnostics shouldn't be an error!")
+	}
+
+	diagnostics.Add("tokens.lex", 12, 5, "unterminated bracket")
+	diagnostics.Add("tokens.lex", 20, 1, "undefined definition `%s`", "digit")
+
+	err := diagnostics.AsError()
+	var found Diagnostics
+	if !errors.As(err, &found) || len(found) != 2 {
+		t.Fatalf("Expected to get back the 2 diagnostics but got: %v", err)
+	}
+
+	expected := "tokens.lex:12:5: unterminated bracket\ntokens.lex:20:1: undefined definition `digit`"
+	if err.Error() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, err.Error())
+	}
+}
@@ -0,0 +1,215 @@
+package grammar
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Matches the start of a rule like `expression:`
+var ruleHeadRegex = regexp.MustCompile(`^[^\s:{}|'"][^\s:{}|]*\s*:`)
+
+// Walks the runes of a rule body keeping track of the semantic action blocks and the quoted literals.
+//
+// Quotes are tracked inside semantic actions, so Go strings like "}" don't close the block,
+// and outside of them when they start a literal like '{', so it doesn't open a block.
+type actionScanner struct {
+	depth    int
+	quote    rune
+	escaped  bool
+	previous rune
+}
+
+// Checks if the last rune was part of a literal outside of the semantic actions, except its closing quote.
+func (self *actionScanner) inLiteral() bool {
+	return self.depth == 0 && self.quote != 0
+}
+
+// Advances the scanner by one rune.
+// Returns true if the rune is part of a semantic action, including its braces.
+func (self *actionScanner) next(r rune) bool {
+	previous := self.previous
+	self.previous = r
+	inAction := self.depth > 0
+
+	if self.quote != 0 {
+		switch {
+		case self.escaped:
+			self.escaped = false
+		case r == '\\' && self.quote != '`':
+			self.escaped = true
+		case r == self.quote:
+			self.quote = 0
+		}
+		return inAction
+	}
+
+	switch r {
+	case '"', '\'', '`':
+		if inAction || startsLiteral(r, previous) {
+			self.quote = r
+		}
+	case '{':
+		self.depth++
+		return true
+	case '}':
+		if self.depth > 0 {
+			self.depth--
+			return true
+		}
+	}
+
+	return inAction
+}
+
+// Computes how many semantic action blocks are still open after the line.
+func actionDepthAfter(line string, depth int) int {
+	scanner := actionScanner{depth: depth}
+	for _, r := range line {
+		scanner.next(r)
+	}
+
+	return scanner.depth
+}
+
+// Splits a rule body on every `|` that is not inside a semantic action, a literal or a group like `( A | B )`.
+func splitAlternatives(body string) []string {
+	alternatives := []string{}
+	scanner := actionScanner{}
+	current := strings.Builder{}
+	groupDepth := 0
+
+	for _, r := range body {
+		inAction := scanner.next(r) || scanner.inLiteral()
+		if !inAction && r == '(' {
+			groupDepth++
+		} else if !inAction && r == ')' && groupDepth > 0 {
+			groupDepth--
+		}
+
+		if !inAction && r == '|' && groupDepth == 0 {
+			alternatives = append(alternatives, current.String())
+			current.Reset()
+		} else {
+			current.WriteRune(r)
+		}
+	}
+	alternatives = append(alternatives, current.String())
+
+	return alternatives
+}
+
+// Separates the symbols of an alternative from its semantic action.
+//
+// The semantic action is the code inside the last `{ }` block of the alternative.
+func extractSemanticAction(alt string) (string, string) {
+	symbols, action, _ := locateSemanticAction(alt)
+	return symbols, action
+}
+
+// Separates the symbols of an alternative from its semantic action like extractSemanticAction,
+// also returning the offset of the action on alt.
+func locateSemanticAction(alt string) (string, string, int) {
+	symbols := strings.Builder{}
+	action := strings.Builder{}
+	scanner := actionScanner{}
+	start := 0
+
+	for i, r := range alt {
+		if !scanner.next(r) {
+			symbols.WriteRune(r)
+			continue
+		}
+
+		if r == '{' && scanner.depth == 1 && scanner.quote == 0 {
+			// A new block replaces any previous one
+			action.Reset()
+			start = i + 1
+		} else if r == '}' && scanner.depth == 0 {
+			continue
+		} else {
+			action.WriteRune(r)
+		}
+	}
+
+	code := action.String()
+	trimmed := strings.TrimLeftFunc(code, unicode.IsSpace)
+	return symbols.String(), strings.TrimSpace(code), start + len(code) - len(trimmed)
+}
+
+// Matches $$ and $1..$n at the start of the text
+var semanticValueRegex = regexp.MustCompile(`^\$(\$|[0-9]+)`)
+
+// Finds the $$ and $1..$n of a semantic action as pairs of start and end offsets.
+//
+// The ones inside Go strings, raw strings and runes are part of the text, so they're skipped.
+func semanticValueIndexes(code string) [][2]int {
+	indexes := [][2]int{}
+	quote := byte(0)
+	for i := 0; i < len(code); i++ {
+		switch {
+		case quote != 0 && code[i] == '\\' && quote != '`':
+			i++
+		case quote != 0:
+			if code[i] == quote {
+				quote = 0
+			}
+		case code[i] == '"' || code[i] == '\'' || code[i] == '`':
+			quote = code[i]
+		case code[i] == '$':
+			if match := semanticValueRegex.FindStringIndex(code[i:]); match != nil {
+				indexes = append(indexes, [2]int{i, i + match[1]})
+				i += match[1] - 1
+			}
+		}
+	}
+
+	return indexes
+}
+
+// Replaces every $$ and $1..$n of a semantic action outside of its Go strings and runes with the result of replace.
+func ReplaceSemanticValues(code string, replace func(value string) string) string {
+	b := strings.Builder{}
+	last := 0
+	for _, index := range semanticValueIndexes(code) {
+		b.WriteString(code[last:index[0]])
+		b.WriteString(replace(code[index[0]:index[1]]))
+		last = index[1]
+	}
+	b.WriteString(code[last:])
+
+	return b.String()
+}
+
+// A $n of a semantic action that isn't a symbol of its production.
+type invalidSemanticValue struct {
+	Value string
+	// The offset of the value on the rule body
+	Offset int
+	// How many symbols the production has
+	Symbols int
+}
+
+// Finds the $n of the action that are outside the symbols of every production written with it.
+//
+// offset is where the action starts on the rule body.
+func checkSemanticValues(action string, offset int, productions [][]GrammarToken) []invalidSemanticValue {
+	invalid := []invalidSemanticValue{}
+	for _, index := range semanticValueIndexes(action) {
+		value := action[index[0]:index[1]]
+		if value == "$$" {
+			continue
+		}
+
+		n, _ := strconv.Atoi(value[1:])
+		for _, production := range productions {
+			if n < 1 || n > len(production) {
+				invalid = append(invalid, invalidSemanticValue{Value: value, Offset: offset + index[0], Symbols: len(production)})
+				break
+			}
+		}
+	}
+
+	return invalid
+}
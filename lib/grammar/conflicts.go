@@ -0,0 +1,192 @@
+package grammar
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+type ConflictType int
+
+const (
+	SHIFT_REDUCE ConflictType = iota
+	REDUCE_REDUCE
+)
+
+func (self ConflictType) String() string {
+	switch self {
+	case SHIFT_REDUCE:
+		return "shift/reduce"
+	case REDUCE_REDUCE:
+		return "reduce/reduce"
+	}
+
+	return "invalid"
+}
+
+// An action that wanted a cell of the action table
+// along with the items that caused it.
+type ConflictingAction struct {
+	Action Action
+	// For shifts these are the items with the dot right before the lookahead.
+	// For reduces this is the completed item.
+	Items []AutomataItem
+}
+
+// Represents a cell of the action table that more than one action tried to claim.
+type Conflict struct {
+	Type ConflictType
+	// The state of the automata where the conflict happened
+	State AFDNodeId
+	// The terminal that triggers the conflict
+	Lookahead GrammarToken
+	// Every action that competed for the cell
+	Actions []ConflictingAction
+	// The action that ended up on the parsing table.
+	// An empty action means the cell was left as a syntax error.
+	Chosen Action
+	// Examples of the conflict, only found when Automata.FindCounterexamples is called
+	Counterexample lib.Optional[Counterexample]
+}
+
+func NewConflict(state AFDNodeId, lookahead GrammarToken, actions []ConflictingAction, chosen Action) Conflict {
+	reduceCount := 0
+	for _, a := range actions {
+		if a.Action.Reduce.HasValue() {
+			reduceCount++
+		}
+	}
+
+	conflictType := SHIFT_REDUCE
+	if reduceCount > 1 {
+		conflictType = REDUCE_REDUCE
+	}
+
+	return Conflict{
+		Type:           conflictType,
+		State:          state,
+		Lookahead:      lookahead,
+		Actions:        actions,
+		Chosen:         chosen,
+		Counterexample: lib.CreateNull[Counterexample](),
+	}
+}
+
+func (self Conflict) String() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("State %s: %s conflict on %s (chose %s)\n", self.State, self.Type.String(), self.Lookahead.Symbol(), self.Chosen.String()))
+
+	for _, a := range self.Actions {
+		if a.Action.Shift.HasValue() {
+			b.WriteString(fmt.Sprintf("\tshift to state %s:\n", a.Action.Shift.GetValue()))
+		} else {
+			b.WriteString(fmt.Sprintf("\treduce by rule %d:\n", a.Action.Reduce.GetValue()))
+		}
+
+		for _, item := range a.Items {
+			b.WriteString("\t\t")
+			b.WriteString(item.String())
+			b.WriteRune('\n')
+		}
+	}
+
+	if self.Counterexample.HasValue() {
+		b.WriteString(self.Counterexample.GetValue().String())
+	}
+
+	return b.String()
+}
+
+// Sorts the conflicts by state and lookahead so they're always reported on the same order.
+func SortConflicts(conflicts []Conflict) {
+	slices.SortFunc(conflicts, func(a, b Conflict) int {
+		// State ids are numbers most of the time, so shorter ones go first
+		if c := cmp.Compare(len(a.State), len(b.State)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.State, b.State); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Lookahead.Symbol(), b.Lookahead.Symbol())
+	})
+}
+
+// Returns the items of the state that have the dot right before the supplied token.
+func (state *AutomataState) itemsWithDotBefore(token GrammarToken) []AutomataItem {
+	items := []AutomataItem{}
+	for _, item := range state.Items {
+		if item.DotIsAtEnd() {
+			continue
+		}
+
+		dotToken := item.Production[item.Dot]
+		if dotToken.Equal(&token) {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Adds a reduce to the candidates of a cell.
+// If the rule already has a reduce on the cell the item is appended to it instead.
+func appendReduceCandidate(candidates []ConflictingAction, ruleId int, item AutomataItem) []ConflictingAction {
+	for i, c := range candidates {
+		if c.Action.Reduce.HasValue() && c.Action.Reduce.GetValue() == ruleId {
+			candidates[i].Items = append(candidates[i].Items, item)
+			return candidates
+		}
+	}
+
+	return append(candidates, ConflictingAction{
+		Action: NewReduceAction(ruleId),
+		Items:  []AutomataItem{item},
+	})
+}
+
+// Chooses the action that stays on the table when multiple actions want the same cell.
+//
+// Shift/reduce conflicts are first resolved with the precedences of the grammar,
+// if that's not possible the shift wins.
+// Between reduces the reduce of the lowest rule wins.
+//
+// Returns the action for the cell (null if the cell must be a syntax error)
+// and whether the conflict couldn't be resolved and should be reported.
+func resolveConflict(grammar *Grammar, lookahead GrammarToken, actions []ConflictingAction) (lib.Optional[Action], bool) {
+	shift := lib.CreateNull[Action]()
+	reduce := lib.CreateNull[Action]()
+	reduceCount := 0
+	for _, a := range actions {
+		if a.Action.Shift.HasValue() {
+			shift = lib.CreateValue(a.Action)
+			continue
+		}
+
+		reduceCount++
+		if !reduce.HasValue() || a.Action.Reduce.GetValue() < reduce.GetValue().Reduce.GetValue() {
+			reduce = lib.CreateValue(a.Action)
+		}
+	}
+
+	unresolved := reduceCount > 1
+	if !shift.HasValue() {
+		return reduce, unresolved
+	}
+	if !reduce.HasValue() {
+		return shift, unresolved
+	}
+
+	switch grammar.resolveByPrecedence(lookahead, reduce.GetValue().Reduce.GetValue()) {
+	case PREFER_SHIFT:
+		return shift, unresolved
+	case PREFER_REDUCE:
+		return reduce, unresolved
+	case PREFER_ERROR:
+		return lib.CreateNull[Action](), unresolved
+	}
+
+	return shift, true
+}
@@ -0,0 +1,626 @@
+package grammar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// The longest example the search for a second derivation is tried on
+const MAX_UNIFYING_EXAMPLE_LENGTH = 30
+
+// A node of a derivation, the leaves are the symbols of the example.
+type DerivationNode struct {
+	Symbol GrammarToken
+	// The rule used to expand the node, -1 for the leaves
+	Rule     int
+	Children []*DerivationNode
+}
+
+// Writes the derivation like bison does: `expr → [ expr PLUS expr • ]`,
+// with the dot right after the leaf number dot, inside the innermost node of the leaf.
+func (self *DerivationNode) String(dot int) string {
+	b := strings.Builder{}
+	if dot == 0 {
+		b.WriteString("• ")
+	}
+	leaves := 0
+	self.write(&b, dot, &leaves)
+	return b.String()
+}
+
+func (self *DerivationNode) write(b *strings.Builder, dot int, leaves *int) {
+	b.WriteString(self.Symbol.Symbol())
+	if self.Rule == -1 {
+		*leaves++
+		if *leaves == dot {
+			b.WriteString(" •")
+		}
+		return
+	}
+
+	b.WriteString(" → [")
+	for _, child := range self.Children {
+		b.WriteRune(' ')
+		child.write(b, dot, leaves)
+	}
+	b.WriteString(" ]")
+}
+
+// The symbols of the leaves in order.
+func (self *DerivationNode) Leaves() []GrammarToken {
+	leaves := []GrammarToken{}
+	var visit func(node *DerivationNode)
+	visit = func(node *DerivationNode) {
+		if node.Rule == -1 {
+			leaves = append(leaves, node.Symbol)
+			return
+		}
+		for _, child := range node.Children {
+			visit(child)
+		}
+	}
+	visit(self)
+
+	return leaves
+}
+
+// Checks if a node expanded with rule ends right before the leaf number dot.
+func (self *DerivationNode) reducesOnDot(rule int, dot int) bool {
+	found := false
+	leaves := 0
+	var visit func(node *DerivationNode)
+	visit = func(node *DerivationNode) {
+		if node.Rule == -1 {
+			leaves++
+			return
+		}
+		start := leaves
+		for _, child := range node.Children {
+			visit(child)
+		}
+		found = found || (node.Rule == rule && leaves == dot && start < dot)
+	}
+	visit(self)
+
+	return found
+}
+
+// A way of deriving an example of a conflict that uses one of its actions.
+type Derivation struct {
+	Action Action
+	// The symbols of the example and the position of the conflict inside them
+	Example []GrammarToken
+	Dot     int
+	Tree    *DerivationNode
+}
+
+// Explains a conflict with examples like bison's -Wcounterexamples.
+type Counterexample struct {
+	// The shortest terminals that take the parser to the state of the conflict
+	InputPrefix []GrammarToken
+	// The derivations share the same example, so the grammar is ambiguous
+	Unifying    bool
+	Derivations []Derivation
+}
+
+func symbolsToHuman(symbols []GrammarToken, dot int) string {
+	parts := make([]string, 0, len(symbols)+1)
+	for i, symbol := range symbols {
+		if i == dot {
+			parts = append(parts, "•")
+		}
+		parts = append(parts, symbol.Symbol())
+	}
+	if dot == len(symbols) {
+		parts = append(parts, "•")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func actionToHuman(action Action) string {
+	if action.Shift.HasValue() {
+		return "shift"
+	}
+	if action.Reduce.HasValue() {
+		return fmt.Sprintf("reduce by rule %d", action.Reduce.GetValue())
+	}
+	return "derivation"
+}
+
+// Every line is indented with a tab.
+func (self Counterexample) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "\tinput prefix: %s\n", symbolsToHuman(self.InputPrefix, -1))
+	if self.Unifying {
+		fmt.Fprintf(&b, "\tambiguous example: %s\n", symbolsToHuman(self.Derivations[0].Example, self.Derivations[0].Dot))
+		for _, derivation := range self.Derivations {
+			fmt.Fprintf(&b, "\t\t%s derivation: %s\n", actionToHuman(derivation.Action), derivation.Tree.String(derivation.Dot))
+		}
+		return b.String()
+	}
+
+	b.WriteString("\tno ambiguous example was found, the conflict may need more lookahead or come from merging states:\n")
+	for _, derivation := range self.Derivations {
+		fmt.Fprintf(&b, "\t\t%s example: %s\n", actionToHuman(derivation.Action), symbolsToHuman(derivation.Example, derivation.Dot))
+		fmt.Fprintf(&b, "\t\t%s derivation: %s\n", actionToHuman(derivation.Action), derivation.Tree.String(derivation.Dot))
+	}
+	return b.String()
+}
+
+// An item of a state on the search for examples
+type searchNode struct {
+	state AutomataStateIndex
+	item  int
+	// The lookahead of the conflict still has to appear after the symbols of the example
+	pending bool
+}
+
+type searchStep struct {
+	next searchNode
+	// The step goes into the production of the symbol after the dot, instead of moving the dot
+	descends bool
+}
+
+type counterexampleSearch struct {
+	auto    *Automata
+	grammar *Grammar
+	firsts  *FirstFollowTable
+	// The nonterminals that can derive ε
+	nullable lib.Set[GrammarToken]
+	// The states with a transition to every state by symbol
+	predecessors  map[AutomataStateIndex]map[GrammarToken][]AutomataStateIndex
+	augmentedHead GrammarToken
+}
+
+func newCounterexampleSearch(auto *Automata, grammar *Grammar) counterexampleSearch {
+	firsts := NewFirstFollowTable()
+	GetFirsts(grammar, &firsts)
+
+	search := counterexampleSearch{
+		auto:         auto,
+		grammar:      grammar,
+		firsts:       &firsts,
+		nullable:     GetNullables(grammar),
+		predecessors: make(map[AutomataStateIndex]map[GrammarToken][]AutomataStateIndex),
+	}
+	if initial, found := auto.Nodes[auto.InitialState]; found && len(initial.Items) > 0 {
+		search.augmentedHead = initial.Items[0].Head
+	}
+
+	for _, from := range auto.sortedStateIds() {
+		for symbol, to := range auto.Transitions[from] {
+			if _, found := search.predecessors[to]; !found {
+				search.predecessors[to] = make(map[GrammarToken][]AutomataStateIndex)
+			}
+			search.predecessors[to][symbol] = append(search.predecessors[to][symbol], from)
+		}
+	}
+
+	return search
+}
+
+func (self *counterexampleSearch) isNullable(symbols []GrammarToken) bool {
+	for _, symbol := range symbols {
+		if !self.nullable.Contains(symbol) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks if the lookahead can be the first terminal derived from the symbols.
+func (self *counterexampleSearch) startsWith(symbols []GrammarToken, lookahead GrammarToken) bool {
+	first := self.firsts.FirstOfSequence(symbols)
+	return first.Contains(lookahead)
+}
+
+func (self *counterexampleSearch) findItem(state AutomataStateIndex, item *AutomataItem) int {
+	for i, other := range self.auto.Nodes[state].Items {
+		if other.EqualsWithoutLookahead(item) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Finds the shortest way of reaching the item of the state from the initial item,
+// searching backwards from the item.
+//
+// For a completed item the lookahead must be able to follow the example,
+// so the item is only reached from items where the lookahead comes after it.
+// Returns the path from the initial item to the item, or nil if there's none,
+// with whether every step goes into a production instead of moving the dot.
+func (self *counterexampleSearch) shortestPath(state AutomataStateIndex, item int, lookahead GrammarToken, pending bool) ([]searchNode, []bool) {
+	start := searchNode{state, item, pending}
+	steps := map[searchNode]searchStep{start: {}}
+	queue := []searchNode{start}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		current := self.auto.Nodes[node.state].Items[node.item]
+		isInitial := node.state == self.auto.InitialState && current.Head == self.augmentedHead && current.Dot == 0
+		if isInitial && (!node.pending || lookahead.IsEnd) {
+			path := []searchNode{node}
+			descends := []bool{false}
+			for path[len(path)-1] != start {
+				step := steps[path[len(path)-1]]
+				path = append(path, step.next)
+				descends = append(descends, step.descends)
+			}
+			return path, descends
+		}
+
+		visit := func(previous searchNode, descends bool) {
+			if _, seen := steps[previous]; !seen {
+				steps[previous] = searchStep{next: node, descends: descends}
+				queue = append(queue, previous)
+			}
+		}
+
+		if current.Dot > 0 {
+			symbol := current.Production[current.Dot-1]
+			previousItem := current
+			previousItem.Dot--
+			for _, from := range self.predecessors[node.state][symbol] {
+				if idx := self.findItem(from, &previousItem); idx != -1 {
+					visit(searchNode{from, idx, node.pending}, false)
+				}
+			}
+			continue
+		}
+
+		// The items of the state that added the item with their closure
+		for idx, parent := range self.auto.Nodes[node.state].Items {
+			if parent.DotIsAtEnd() || parent.Production[parent.Dot] != current.Head {
+				continue
+			}
+
+			rest := parent.Production[parent.Dot+1:]
+			pending := node.pending
+			if pending && self.startsWith(rest, lookahead) {
+				pending = false
+			} else if pending && !self.isNullable(rest) {
+				continue
+			}
+			visit(searchNode{node.state, idx, pending}, true)
+		}
+	}
+
+	return nil, nil
+}
+
+// Builds the derivation described by a path from the initial item,
+// the augmented rule isn't part of it.
+func (self *counterexampleSearch) pathDerivation(path []searchNode, descends []bool, action Action) Derivation {
+	// The item of every nested rule when the path went into the next one
+	levels := []AutomataItem{self.auto.Nodes[path[0].state].Items[path[0].item]}
+	dot := 0
+	for i := 1; i < len(path); i++ {
+		item := self.auto.Nodes[path[i].state].Items[path[i].item]
+		if descends[i] {
+			levels = append(levels, item)
+		} else {
+			levels[len(levels)-1] = item
+			dot++
+		}
+	}
+
+	var build func(level int) *DerivationNode
+	build = func(level int) *DerivationNode {
+		item := levels[level]
+		node := &DerivationNode{Symbol: item.Head, Rule: self.grammar.FindIndexOfRule(&item)}
+		for i, symbol := range item.Production {
+			if level+1 < len(levels) && i == item.Dot {
+				node.Children = append(node.Children, build(level+1))
+			} else {
+				node.Children = append(node.Children, &DerivationNode{Symbol: symbol, Rule: -1})
+			}
+		}
+		return node
+	}
+
+	// The augmented rule only has the initial symbol
+	tree := build(1)
+	derivation := Derivation{Action: action, Dot: dot, Tree: tree}
+	derivation.Example = tree.Leaves()
+
+	return derivation
+}
+
+// The shortest terminals derived from every nonterminal.
+func (self *counterexampleSearch) shortestYields() map[GrammarToken][]GrammarToken {
+	yields := make(map[GrammarToken][]GrammarToken)
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range self.grammar.Rules {
+			yield := []GrammarToken{}
+			productive := true
+			for _, symbol := range rule.Production {
+				if !symbol.IsNonTerminal() {
+					yield = append(yield, symbol)
+				} else if symbolYield, found := yields[symbol]; found {
+					yield = append(yield, symbolYield...)
+				} else {
+					productive = false
+					break
+				}
+			}
+
+			current, found := yields[rule.Head]
+			if productive && (!found || len(yield) < len(current)) {
+				yields[rule.Head] = yield
+				changed = true
+			}
+		}
+	}
+
+	return yields
+}
+
+// The shortest terminals that reach the state from the initial one.
+func (self *counterexampleSearch) inputPrefix(state AutomataStateIndex) []GrammarToken {
+	symbols := map[AutomataStateIndex][]GrammarToken{self.auto.InitialState: {}}
+	queue := []AutomataStateIndex{self.auto.InitialState}
+	for len(queue) > 0 {
+		from := queue[0]
+		queue = queue[1:]
+		if from == state {
+			break
+		}
+
+		inputs := make([]GrammarToken, 0, len(self.auto.Transitions[from]))
+		for input := range self.auto.Transitions[from] {
+			inputs = append(inputs, input)
+		}
+		slices.SortFunc(inputs, func(a, b GrammarToken) int { return strings.Compare(a.String(), b.String()) })
+		for _, input := range inputs {
+			to := self.auto.Transitions[from][input]
+			if _, found := symbols[to]; !found {
+				symbols[to] = append(slices.Clone(symbols[from]), input)
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	yields := self.shortestYields()
+	prefix := []GrammarToken{}
+	for _, symbol := range symbols[state] {
+		if yield, found := yields[symbol]; found && symbol.IsNonTerminal() {
+			prefix = append(prefix, yield...)
+		} else {
+			prefix = append(prefix, symbol)
+		}
+	}
+
+	return prefix
+}
+
+// Finds a counterexample for every conflict and stores it on it.
+func (auto *Automata) FindCounterexamples(grammar *Grammar, conflicts []Conflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+
+	search := newCounterexampleSearch(auto, grammar)
+	for i := range conflicts {
+		conflicts[i].Counterexample = lib.CreateValue(search.find(&conflicts[i]))
+	}
+}
+
+func (self *counterexampleSearch) find(conflict *Conflict) Counterexample {
+	counterexample := Counterexample{InputPrefix: self.inputPrefix(conflict.State)}
+
+	for _, a := range conflict.Actions {
+		for _, item := range a.Items {
+			idx := self.findItem(conflict.State, &item)
+			if idx == -1 {
+				continue
+			}
+
+			path, descends := self.shortestPath(conflict.State, idx, conflict.Lookahead, a.Action.Reduce.HasValue())
+			if path != nil {
+				counterexample.Derivations = append(counterexample.Derivations, self.pathDerivation(path, descends, a.Action))
+				break
+			}
+		}
+	}
+
+	// One of the examples may be derived in another way
+	for _, derivation := range counterexample.Derivations {
+		if len(derivation.Example) > MAX_UNIFYING_EXAMPLE_LENGTH {
+			continue
+		}
+		trees := twoDerivations(self.grammar, derivation.Example)
+		if len(trees) < 2 {
+			continue
+		}
+
+		// The trees must use different actions of the conflict, otherwise the ambiguity is somewhere else
+		first := treeAction(conflict, trees[0], derivation.Dot)
+		second := treeAction(conflict, trees[1], derivation.Dot)
+		if first == -1 || second == -1 || first == second {
+			continue
+		}
+
+		counterexample.Unifying = true
+		counterexample.Derivations = []Derivation{}
+		// Shown in the same order as the actions of the conflict
+		if first > second {
+			first, second = second, first
+			trees[0], trees[1] = trees[1], trees[0]
+		}
+		for i, idx := range []int{first, second} {
+			counterexample.Derivations = append(counterexample.Derivations, Derivation{
+				Action:  conflict.Actions[idx].Action,
+				Example: derivation.Example,
+				Dot:     derivation.Dot,
+				Tree:    trees[i],
+			})
+		}
+		break
+	}
+
+	return counterexample
+}
+
+// The index of the action of the conflict used by the tree, or -1 if it doesn't use any.
+//
+// The tree reduces if it has a node of the rule that ends right before the dot,
+// otherwise it shifts the lookahead.
+func treeAction(conflict *Conflict, tree *DerivationNode, dot int) int {
+	for i, a := range conflict.Actions {
+		if a.Action.Reduce.HasValue() && tree.reducesOnDot(a.Action.Reduce.GetValue(), dot) {
+			return i
+		}
+	}
+	for i, a := range conflict.Actions {
+		if a.Action.Shift.HasValue() {
+			return i
+		}
+	}
+	return -1
+}
+
+// The derivations of symbols[i:j] from the symbols or from the first symbols of the rules, at most two of each.
+//
+// The keys are kept in the order they're added, so the derivations found don't depend on the order of a map.
+type derivationCell struct {
+	keys    []int
+	entries map[int][]derivationEntry
+}
+
+type derivationEntry struct {
+	children []*DerivationNode
+	// Shows the rules used by the children, so different derivations have different signatures
+	signature string
+}
+
+// Adds the entry to the key unless it already has two derivations or the same one.
+func (self *derivationCell) add(key int, entry derivationEntry) bool {
+	current, found := self.entries[key]
+	if len(current) >= 2 {
+		return false
+	}
+	for _, other := range current {
+		if other.signature == entry.signature {
+			return false
+		}
+	}
+
+	if !found {
+		self.keys = append(self.keys, key)
+	}
+	self.entries[key] = append(current, entry)
+	return true
+}
+
+// Finds up to two different derivations of the symbols from the initial symbol of the grammar.
+//
+// It's a chart parser where the symbols may be nonterminals, they're leaves of the derivations.
+// Only the first two derivations of every span are kept, that's enough to know if there's more than one.
+func twoDerivations(grammar *Grammar, symbols []GrammarToken) []*DerivationNode {
+	n := len(symbols)
+
+	// A rule with prefix symbols derived is keyed by ruleKeys[rule]+prefix, a symbol by -1-id
+	symbolIds := make(map[GrammarToken]int)
+	symbolKey := func(symbol GrammarToken) int {
+		id, found := symbolIds[symbol]
+		if !found {
+			id = len(symbolIds)
+			symbolIds[symbol] = id
+		}
+		return -1 - id
+	}
+	ruleKeys := make([]int, len(grammar.Rules))
+	keyRules := []int{}
+	productions := make([][]int, len(grammar.Rules))
+	for ruleIdx, rule := range grammar.Rules {
+		ruleKeys[ruleIdx] = len(keyRules)
+		for range len(rule.Production) + 1 {
+			keyRules = append(keyRules, ruleIdx)
+		}
+		for _, symbol := range rule.Production {
+			productions[ruleIdx] = append(productions[ruleIdx], symbolKey(symbol))
+		}
+	}
+
+	cells := make([][]derivationCell, n+1)
+	for i := range cells {
+		cells[i] = make([]derivationCell, n+1)
+		for j := i; j <= n; j++ {
+			cells[i][j].entries = make(map[int][]derivationEntry)
+		}
+		if i < n {
+			leaf := &DerivationNode{Symbol: symbols[i], Rule: -1}
+			cells[i][i+1].add(symbolKey(symbols[i]), derivationEntry{[]*DerivationNode{leaf}, symbols[i].String()})
+		}
+	}
+
+	// Adds the entry to cell, and a derivation of the head when the rule is complete
+	add := func(cell *derivationCell, key int, entry derivationEntry) bool {
+		if !cell.add(key, entry) {
+			return false
+		}
+		ruleIdx := keyRules[key]
+		if key-ruleKeys[ruleIdx] == len(productions[ruleIdx]) {
+			head := grammar.Rules[ruleIdx].Head
+			node := &DerivationNode{Symbol: head, Rule: ruleIdx, Children: entry.children}
+			cell.add(symbolKey(head), derivationEntry{[]*DerivationNode{node}, fmt.Sprintf("%d(%s)", ruleIdx, entry.signature)})
+		}
+		return true
+	}
+
+	for length := 0; length <= n; length++ {
+		for i := 0; i+length <= n; i++ {
+			j := i + length
+			cell := &cells[i][j]
+			if length == 0 {
+				for ruleIdx := range grammar.Rules {
+					add(cell, ruleKeys[ruleIdx], derivationEntry{})
+				}
+			}
+
+			// Rules deriving the whole span may need other rules of the same span, like A → B
+			for changed := true; changed; {
+				changed = false
+				for k := i; k <= j; k++ {
+					starts := &cells[i][k]
+					ends := &cells[k][j]
+					for keyIdx := 0; keyIdx < len(starts.keys); keyIdx++ {
+						key := starts.keys[keyIdx]
+						if key < 0 {
+							continue
+						}
+						ruleIdx := keyRules[key]
+						prefix := key - ruleKeys[ruleIdx]
+						if prefix == len(productions[ruleIdx]) {
+							continue
+						}
+
+						for _, start := range starts.entries[key] {
+							for _, end := range ends.entries[productions[ruleIdx][prefix]] {
+								entry := derivationEntry{
+									children:  append(slices.Clone(start.children), end.children[0]),
+									signature: start.signature + end.signature + " ",
+								}
+								changed = add(cell, key+1, entry) || changed
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	trees := []*DerivationNode{}
+	for _, entry := range cells[0][n].entries[symbolKey(grammar.InitialSimbol)] {
+		trees = append(trees, entry.children[0])
+	}
+	return trees
+}
@@ -0,0 +1,142 @@
+package grammar
+
+import "testing"
+
+func findCounterexamples(g *Grammar) []Conflict {
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+	auto := InitializeLALRAutomata(initialRule, *g)
+	_, conflicts := auto.GenerateParsingTable(g)
+	auto.FindCounterexamples(g, conflicts)
+	SortConflicts(conflicts)
+	return conflicts
+}
+
+func TestTwoDerivations(t *testing.T) {
+	E := NewNonTerminalToken("E")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+	g := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, E}},
+		{Head: E, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, id})
+
+	trees := twoDerivations(&g, []GrammarToken{id, plus, id})
+	if len(trees) != 1 {
+		t.Fatalf("Expected a single derivation of id + id but got %d", len(trees))
+	}
+
+	trees = twoDerivations(&g, []GrammarToken{id, plus, E, plus, id})
+	if len(trees) != 2 {
+		t.Fatalf("Expected two derivations of id + E + id but got %d", len(trees))
+	}
+	expected := []string{
+		"E → [ E → [ id ] + E → [ E • + E → [ id ] ] ]",
+		"E → [ E → [ E → [ id ] + E • ] + E → [ id ] ]",
+	}
+	for i, tree := range trees {
+		if tree.String(3) != expected[i] {
+			t.Errorf("Expected derivation %d to be\n%s\nbut got\n%s", i, expected[i], tree.String(3))
+		}
+	}
+
+	trees = twoDerivations(&g, []GrammarToken{plus})
+	if len(trees) != 0 {
+		t.Fatalf("Expected no derivations of + but got %d", len(trees))
+	}
+}
+
+func TestFindCounterexamplesAmbiguous(t *testing.T) {
+	E := NewNonTerminalToken("E")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+	g := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, E}},
+		{Head: E, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, id})
+
+	conflicts := findCounterexamples(&g)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected a single conflict but got %d", len(conflicts))
+	}
+
+	expected := "\tinput prefix: id + id\n" +
+		"\tambiguous example: E + E • + E\n" +
+		"\t\tshift derivation: E → [ E + E → [ E • + E ] ]\n" +
+		"\t\treduce by rule 0 derivation: E → [ E → [ E + E • ] + E ]\n"
+	counterexample := conflicts[0].Counterexample.GetValue()
+	if counterexample.String() != expected {
+		t.Errorf("Expected the counterexample\n%s\nbut got\n%s", expected, counterexample.String())
+	}
+}
+
+func TestFindCounterexamplesDanglingElse(t *testing.T) {
+	stmt := NewNonTerminalToken("stmt")
+	ifToken := NewTerminalToken("IF")
+	cond := NewTerminalToken("COND")
+	then := NewTerminalToken("THEN")
+	elseToken := NewTerminalToken("ELSE")
+	x := NewTerminalToken("X")
+	g := newGrammarFromRules(stmt, []GrammarRule{
+		{Head: stmt, Production: []GrammarToken{ifToken, cond, then, stmt}},
+		{Head: stmt, Production: []GrammarToken{ifToken, cond, then, stmt, elseToken, stmt}},
+		{Head: stmt, Production: []GrammarToken{x}},
+	}, []GrammarToken{ifToken, cond, then, elseToken, x})
+
+	conflicts := findCounterexamples(&g)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected a single conflict but got %d", len(conflicts))
+	}
+
+	expected := "\tinput prefix: IF COND THEN X\n" +
+		"\tambiguous example: IF COND THEN IF COND THEN stmt • ELSE stmt\n" +
+		"\t\tshift derivation: stmt → [ IF COND THEN stmt → [ IF COND THEN stmt • ELSE stmt ] ]\n" +
+		"\t\treduce by rule 0 derivation: stmt → [ IF COND THEN stmt → [ IF COND THEN stmt • ] ELSE stmt ]\n"
+	counterexample := conflicts[0].Counterexample.GetValue()
+	if counterexample.String() != expected {
+		t.Errorf("Expected the counterexample\n%s\nbut got\n%s", expected, counterexample.String())
+	}
+}
+
+func TestFindCounterexamplesFromMergedStates(t *testing.T) {
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	B := NewNonTerminalToken("B")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	c := NewTerminalToken("c")
+	d := NewTerminalToken("d")
+	e := NewTerminalToken("e")
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{a, A, d}},
+		{Head: S, Production: []GrammarToken{b, B, d}},
+		{Head: S, Production: []GrammarToken{a, B, e}},
+		{Head: S, Production: []GrammarToken{b, A, e}},
+		{Head: A, Production: []GrammarToken{c}},
+		{Head: B, Production: []GrammarToken{c}},
+	}, []GrammarToken{a, b, c, d, e})
+
+	// The grammar is LR(1), the conflicts only appear when the states after c are merged
+	conflicts := findCounterexamples(&g)
+	if len(conflicts) == 0 {
+		t.Fatalf("Expected reduce/reduce conflicts")
+	}
+	for _, conflict := range conflicts {
+		counterexample := conflict.Counterexample.GetValue()
+		if counterexample.Unifying {
+			t.Errorf("The conflict shouldn't have an ambiguous example:\n%s", conflict.String())
+		}
+		if len(counterexample.Derivations) != 2 {
+			t.Errorf("Expected an example for every action of the conflict:\n%s", conflict.String())
+		}
+	}
+
+	expected := "\tinput prefix: a c\n" +
+		"\tno ambiguous example was found, the conflict may need more lookahead or come from merging states:\n" +
+		"\t\treduce by rule 4 example: a c • d\n" +
+		"\t\treduce by rule 4 derivation: S → [ a A → [ c • ] d ]\n" +
+		"\t\treduce by rule 5 example: b c • d\n" +
+		"\t\treduce by rule 5 derivation: S → [ b B → [ c • ] d ]\n"
+	if conflicts[0].Counterexample.GetValue().String() != expected {
+		t.Errorf("Expected the counterexample\n%s\nbut got\n%s", expected, conflicts[0].Counterexample.GetValue().String())
+	}
+}
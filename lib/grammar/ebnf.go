@@ -0,0 +1,392 @@
+package grammar
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// The operators that can follow a symbol or a group on a production:
+// `?` for an optional element, `*` for zero or more and `+` for one or more.
+const EBNF_OPERATORS = "?*+"
+
+// Where the rules of a nonterminal generated for an EBNF expression were written.
+type EBNFSource struct {
+	// The expression as it's written, like `stmt*` or `( COMMA expr )*`
+	Expression string
+	// The head of the rule the expression was written on
+	Head GrammarToken
+	// The line where that rule starts
+	Line int
+}
+
+func (self EBNFSource) String() string {
+	return fmt.Sprintf("generated for `%s` on the rule of %s at line %d", self.Expression, self.Head.Symbol(), self.Line)
+}
+
+// A symbol or a group of a production as it's written, followed by its operators.
+type ebnfElement struct {
+	symbol string
+	// The alternatives of a group like `( A | B C )`, nil for symbols
+	group     [][]ebnfElement
+	operators []rune
+}
+
+// Writes the element like it's written on the yal file, with a single space between symbols.
+func (self ebnfElement) String() string {
+	return self.withoutOperators() + string(self.operators)
+}
+
+func (self ebnfElement) withoutOperators() string {
+	if self.group == nil {
+		return self.symbol
+	}
+
+	alternatives := make([]string, 0, len(self.group))
+	for _, alternative := range self.group {
+		alternatives = append(alternatives, joinElements(alternative))
+	}
+	return "( " + strings.Join(alternatives, " | ") + " )"
+}
+
+func joinElements(elements []ebnfElement) string {
+	parts := make([]string, 0, len(elements))
+	for _, element := range elements {
+		parts = append(parts, element.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// Splits an alternative into symbols, literals, parentheses, `|` and operators.
+//
+// A word that is a declared terminal is kept whole even if it has operator characters.
+func tokenizeAlternative(alt string, terminals lib.Set[GrammarToken]) ([]string, error) {
+	tokens := []string{}
+	current := strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	runes := []rune(alt)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		previous := ' '
+		if i > 0 {
+			previous = runes[i-1]
+		}
+
+		if unicode.IsSpace(previous) && !unicode.IsSpace(r) {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) {
+				end++
+			}
+			if word := string(runes[i:end]); isTerminal(word, terminals) {
+				tokens = append(tokens, word)
+				i = end - 1
+				continue
+			}
+		}
+
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case startsLiteral(r, previous):
+			flush()
+			end := literalEnd(runes, i)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated literal %s", strings.TrimSpace(string(runes[i:])))
+			}
+			tokens = append(tokens, string(runes[i:end+1]))
+			i = end
+		case r == '(' || r == ')' || r == '|' || strings.ContainsRune(EBNF_OPERATORS, r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// Parses the alternatives of a group, or of the whole alternative if it isn't nested,
+// until the `)` that closes the group.
+func parseElements(tokens []string, pos *int, nested bool) ([][]ebnfElement, error) {
+	alternatives := [][]ebnfElement{{}}
+	for *pos < len(tokens) {
+		token := tokens[*pos]
+		*pos++
+		current := &alternatives[len(alternatives)-1]
+
+		switch {
+		case token == ")":
+			if !nested {
+				return nil, fmt.Errorf("unexpected `)` without a `(` to close")
+			}
+			return alternatives, nil
+		case token == "|":
+			alternatives = append(alternatives, []ebnfElement{})
+		case token == "(":
+			group, err := parseElements(tokens, pos, true)
+			if err != nil {
+				return nil, err
+			}
+			*current = append(*current, ebnfElement{group: group})
+		case len(token) == 1 && strings.Contains(EBNF_OPERATORS, token):
+			if len(*current) == 0 {
+				return nil, fmt.Errorf("`%s` must follow a symbol or a group", token)
+			}
+			last := &(*current)[len(*current)-1]
+			last.operators = append(last.operators, rune(token[0]))
+		default:
+			*current = append(*current, ebnfElement{symbol: token})
+		}
+	}
+
+	if nested {
+		return nil, fmt.Errorf("unclosed `(`, expected a `)` to close it")
+	}
+	return alternatives, nil
+}
+
+// Reads the elements of an alternative and the token given with %prec.
+func parseAlternative(alt string, terminals lib.Set[GrammarToken]) ([][]ebnfElement, lib.Optional[GrammarToken], error) {
+	precedenceToken := lib.CreateNull[GrammarToken]()
+	symbols, err := tokenizeAlternative(alt, terminals)
+	if err != nil {
+		return nil, precedenceToken, err
+	}
+
+	// %prec TOKEN makes the alternative take the precedence of TOKEN
+	for i := slices.Index(symbols, "%prec"); i != -1; i = slices.Index(symbols, "%prec") {
+		// A %prec without token keeps an empty token so it can be reported
+		precedenceToken = lib.CreateValue(NewTerminalToken(""))
+		if i+1 < len(symbols) {
+			precedenceToken = lib.CreateValue(NewTerminalToken(symbols[i+1]))
+			if text, isLiteral := unquoteLiteral(symbols[i+1]); isLiteral {
+				precedenceToken = lib.CreateValue(NewLiteralToken(text))
+			}
+		}
+		symbols = slices.Delete(symbols, i, min(i+2, len(symbols)))
+	}
+
+	for _, symbol := range symbols {
+		if _, isLiteral := unquoteLiteral(symbol); startsLiteral(rune(symbol[0]), ' ') && !isLiteral {
+			return nil, precedenceToken, fmt.Errorf("invalid literal %s", symbol)
+		}
+	}
+
+	pos := 0
+	elements, err := parseElements(symbols, &pos, false)
+	return elements, precedenceToken, err
+}
+
+// The nonterminals generated for the EBNF expressions of a yal file.
+//
+// They get their names once all the rules are read, so they don't take the name of a symbol written later.
+// Until then they use placeholders with a space, since a written symbol can't have one.
+type ebnfHelpers struct {
+	// The nonterminal of every expression, so an expression written twice uses the same one
+	byExpression map[string]GrammarToken
+	rules        []GrammarRule
+	// How many groups every head has, to number their nonterminals
+	groupCounts map[GrammarToken]int
+	// The placeholders in the order they were created, with the names they should have
+	placeholders []GrammarToken
+	names        map[GrammarToken]string
+}
+
+func newEBNFHelpers() ebnfHelpers {
+	return ebnfHelpers{
+		byExpression: make(map[string]GrammarToken),
+		groupCounts:  make(map[GrammarToken]int),
+		names:        make(map[GrammarToken]string),
+	}
+}
+
+// Creates the placeholder of a nonterminal for expression that should be named name.
+func (self *ebnfHelpers) newNonTerminal(expression string, name string) GrammarToken {
+	token := NewNonTerminalToken("ebnf " + strconv.Itoa(len(self.placeholders)))
+	self.placeholders = append(self.placeholders, token)
+	self.names[token] = name
+	self.byExpression[expression] = token
+	return token
+}
+
+// The name a symbol has or will have once it's renamed, literals are named like comma for ','.
+func (self *ebnfHelpers) nameOf(token GrammarToken) string {
+	if name, found := self.names[token]; found {
+		return name
+	}
+	if text, isLiteral := token.LiteralText(); isLiteral {
+		return strings.ToLower(LiteralName(text))
+	}
+	return token.Symbol()
+}
+
+// Adds a rule of a generated nonterminal.
+func (self *ebnfHelpers) addRule(head GrammarToken, production []GrammarToken, action string, source EBNFSource) {
+	self.rules = append(self.rules, GrammarRule{
+		Head:            head,
+		Production:      production,
+		PrecedenceToken: lib.CreateNull[GrammarToken](),
+		SemanticAction:  action,
+		Generated:       lib.CreateValue(source),
+	})
+}
+
+// The symbols an element stands for on a production, nothing for ε.
+//
+// Groups and operators are replaced by generated nonterminals:
+// * `( A | B )` becomes `head_group1: A | B`
+// * `X?` becomes `X_opt: ε | X`
+// * `X*` becomes `X_star: ε | X_star X`
+// * `X+` becomes `X_plus: X | X_plus X`
+func (self *ebnfHelpers) desugar(element ebnfElement, head GrammarToken, line int, terminals lib.Set[GrammarToken], nonTerminals lib.Set[GrammarToken]) []GrammarToken {
+	symbols := []GrammarToken{}
+	expression := element.withoutOperators()
+	if element.group == nil {
+		switch {
+		// Epsilon is the empty production, it doesn't add any symbol
+		case element.symbol == "ε" || element.symbol == "epsilon" || element.symbol == "EPSILON":
+		case element.symbol == ERROR_TOKEN_NAME:
+			terminals.Add(NewErrorToken())
+			symbols = append(symbols, NewErrorToken())
+		case startsLiteral(rune(element.symbol[0]), ' '):
+			text, _ := unquoteLiteral(element.symbol)
+			token := NewLiteralToken(text)
+			terminals.Add(token)
+			symbols = append(symbols, token)
+		case isTerminal(element.symbol, terminals):
+			symbols = append(symbols, NewTerminalToken(element.symbol))
+		default:
+			token := NewNonTerminalToken(element.symbol)
+			nonTerminals.Add(token)
+			symbols = append(symbols, token)
+		}
+	} else if len(element.group) == 1 && len(element.group[0]) <= 1 {
+		// A group with a single element is just that element
+		for _, inner := range element.group[0] {
+			symbols = self.desugar(inner, head, line, terminals, nonTerminals)
+			expression = inner.String()
+		}
+	} else {
+		token, found := self.byExpression[expression]
+		if !found {
+			self.groupCounts[head]++
+			token = self.newNonTerminal(expression, head.Symbol()+"_group"+strconv.Itoa(self.groupCounts[head]))
+
+			source := EBNFSource{Expression: expression, Head: head, Line: line}
+			for _, alternative := range element.group {
+				production := []GrammarToken{}
+				for _, inner := range alternative {
+					production = append(production, self.desugar(inner, head, line, terminals, nonTerminals)...)
+				}
+				self.addRule(token, production, "", source)
+			}
+		}
+		symbols = []GrammarToken{token}
+	}
+
+	for _, operator := range element.operators {
+		// ε repeated or optional is still ε
+		if len(symbols) == 0 {
+			break
+		}
+
+		expression += string(operator)
+		inner := symbols[0]
+		token, found := self.byExpression[expression]
+		if !found {
+			suffix := map[rune]string{'?': "_opt", '*': "_star", '+': "_plus"}[operator]
+			token = self.newNonTerminal(expression, self.nameOf(inner)+suffix)
+
+			source := EBNFSource{Expression: expression, Head: head, Line: line}
+			switch operator {
+			case '?':
+				self.addRule(token, []GrammarToken{}, "", source)
+				self.addRule(token, []GrammarToken{inner}, "", source)
+			case '*':
+				self.addRule(token, []GrammarToken{}, "$$ = []any{}", source)
+				self.addRule(token, []GrammarToken{token, inner}, "$$ = append($1.([]any), $2)", source)
+			case '+':
+				self.addRule(token, []GrammarToken{inner}, "$$ = []any{$1}", source)
+				self.addRule(token, []GrammarToken{token, inner}, "$$ = append($1.([]any), $2)", source)
+			}
+		}
+		symbols = []GrammarToken{token}
+	}
+
+	return symbols
+}
+
+// Adds the generated rules after the written ones.
+//
+// The generated nonterminals are numbered if a written symbol has the same name,
+// and the lists only build their values if the written rules have semantic actions.
+func (self *ebnfHelpers) finish(rules *[]GrammarRule, terminals lib.Set[GrammarToken], nonTerminals lib.Set[GrammarToken]) {
+	if len(self.rules) == 0 {
+		return
+	}
+
+	taken := lib.NewSet[string]()
+	for token := range terminals {
+		taken.Add(token.Symbol())
+	}
+	for token := range nonTerminals {
+		taken.Add(token.Symbol())
+	}
+
+	renames := make(map[GrammarToken]GrammarToken)
+	for _, placeholder := range self.placeholders {
+		base := self.names[placeholder]
+		name := base
+		for i := 2; taken.Contains(name); i++ {
+			name = base + strconv.Itoa(i)
+		}
+		taken.Add(name)
+		renames[placeholder] = NewNonTerminalToken(name)
+		nonTerminals.Add(renames[placeholder])
+	}
+
+	hasActions := slices.ContainsFunc(*rules, func(rule GrammarRule) bool {
+		return rule.SemanticAction != ""
+	})
+	for _, rule := range self.rules {
+		if !hasActions {
+			rule.SemanticAction = ""
+		}
+		*rules = append(*rules, rule)
+	}
+
+	for i := range *rules {
+		rule := &(*rules)[i]
+		if renamed, found := renames[rule.Head]; found {
+			rule.Head = renamed
+		}
+		for j, token := range rule.Production {
+			if renamed, found := renames[token]; found {
+				rule.Production[j] = renamed
+			}
+		}
+	}
+}
+
+// The EBNF expressions every generated nonterminal of the grammar was created for.
+func (g *Grammar) generatedSources() map[GrammarToken]EBNFSource {
+	sources := make(map[GrammarToken]EBNFSource)
+	for _, rule := range g.Rules {
+		if rule.Generated.HasValue() {
+			sources[rule.Head] = rule.Generated.GetValue()
+		}
+	}
+	return sources
+}
@@ -0,0 +1,183 @@
+package grammar
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+func TestParseYalFileEBNF(t *testing.T) {
+	g, _, err := parseYalString(t, `%token ID NUM COMMA LPAREN RPAREN SEMI
+%%
+program: stmt* ;
+stmt: ID LPAREN args? RPAREN SEMI
+	| ID (NUM | ID)+ SEMI
+	| ID ( NUM | ID )+ COMMA stmt*
+	;
+args: expr (COMMA expr)* ;
+expr: NUM | ID | (expr) ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"program -> stmt_star",
+		"stmt -> ID LPAREN args_opt RPAREN SEMI",
+		"stmt -> ID stmt_group1_plus SEMI",
+		"stmt -> ID stmt_group1_plus COMMA stmt_star",
+		"args -> expr args_group1_star",
+		"expr -> NUM",
+		"expr -> ID",
+		"expr -> expr",
+		"stmt_star -> ε",
+		"stmt_star -> stmt_star stmt",
+		"args_opt -> ε",
+		"args_opt -> args",
+		"stmt_group1 -> NUM",
+		"stmt_group1 -> ID",
+		"stmt_group1_plus -> stmt_group1",
+		"stmt_group1_plus -> stmt_group1_plus stmt_group1",
+		"args_group1 -> COMMA expr",
+		"args_group1_star -> ε",
+		"args_group1_star -> args_group1_star args_group1",
+	}
+	if rules := rulesToHuman(&g); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected the rules\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(rules, "\n"))
+	}
+
+	for i, rule := range g.Rules {
+		if i < 8 && rule.Generated.HasValue() {
+			t.Errorf("Rule %d is written on the grammar but is marked as generated", i)
+		}
+		if i >= 8 && !rule.Generated.HasValue() {
+			t.Errorf("Rule %d is generated but doesn't say where it comes from", i)
+		}
+		if rule.SemanticAction != "" {
+			t.Errorf("Rule %d shouldn't have an action on a grammar without actions but got %q", i, rule.SemanticAction)
+		}
+	}
+
+	expectedSource := EBNFSource{Expression: "( NUM | ID )+", Head: NewNonTerminalToken("stmt"), Line: 4}
+	if source := g.Rules[15].Generated.GetValue(); source != expectedSource {
+		t.Errorf("Expected the source %v but got %v", expectedSource, source)
+	}
+	if !g.NonTerminals.Contains(NewNonTerminalToken("stmt_group1_plus")) {
+		t.Errorf("Expected the generated nonterminals to be nonterminals of the grammar")
+	}
+	if _, found := g.TokenIds[NewNonTerminalToken("args_group1_star")]; !found {
+		t.Errorf("Expected the generated nonterminals to have ids")
+	}
+}
+
+func TestParseYalFileEBNFActions(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUM
+%%
+list: NUM+ { $$ = len($1.([]any)) } ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"", "$$ = []any{$1}", "$$ = append($1.([]any), $2)"}
+	for i, action := range expected[1:] {
+		if g.Rules[i+1].SemanticAction != action {
+			t.Errorf("Rule %d: expected action %q but got %q", i+1, action, g.Rules[i+1].SemanticAction)
+		}
+	}
+}
+
+func TestParseYalFileEBNFNameClash(t *testing.T) {
+	g, _, err := parseYalString(t, `%token A
+%%
+s: A* s_group1 ;
+s_group1: A_star ;
+A_star: A ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"s -> A_star2 s_group1",
+		"s_group1 -> A_star",
+		"A_star -> A",
+		"A_star2 -> ε",
+		"A_star2 -> A_star2 A",
+	}
+	if rules := rulesToHuman(&g); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected the rules\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(rules, "\n"))
+	}
+}
+
+func TestParseYalFileEBNFDiagnostics(t *testing.T) {
+	_, path, err := parseYalString(t, `%token A B
+%%
+s: (A | B* ;
+t: + A ;
+u: A ) | B ;
+v: A (x)? ;
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":3:1: invalid production `(A | B*` of s: unclosed `(`, expected a `)` to close it",
+		path + ":4:1: invalid production `+ A` of t: `+` must follow a symbol or a group",
+		path + ":5:1: invalid production `A )` of u: unexpected `)` without a `(` to close",
+		path + ":6:7: unknown symbol `x`, it isn't a declared token and doesn't have rules",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestGeneratedRulesMapBack(t *testing.T) {
+	g, _, err := parseYalString(t, `%token A B
+%%
+s: A | ;
+t: (B A)+ ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"warning: nonterminal `t` can't be reached from the initial symbol",
+		"warning: nonterminal `t_group1` can't be reached from the initial symbol (generated for `( B A )` on the rule of t at line 4)",
+		"warning: nonterminal `t_group1_plus` can't be reached from the initial symbol (generated for `( B A )+` on the rule of t at line 4)",
+	}
+	issues := g.Validate()
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues but got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != expected[i] {
+			t.Errorf("Issue %d = %s, want %s", i, issue.String(), expected[i])
+		}
+	}
+
+	b := strings.Builder{}
+	writeReportGrammar(&b, &g)
+	line := "    4 t_group1_plus -> t_group1  (generated for `( B A )+` on the rule of t at line 4)\n"
+	if !strings.Contains(b.String(), line) {
+		t.Errorf("Expected the report to have the line\n%s\nbut got\n%s", line, b.String())
+	}
+}
+
+func TestSplitAlternativesKeepsGroups(t *testing.T) {
+	alternatives := splitAlternatives("a ( b | c )* { x := (1) } | d")
+	if len(alternatives) != 2 || strings.TrimSpace(alternatives[1]) != "d" {
+		t.Errorf("Expected the group to stay on the first alternative but got %q", alternatives)
+	}
+}
@@ -2,9 +2,14 @@ package grammar
 
 import (
 	"bufio"
+	"cmp"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"unicode"
 
 	"github.com/Jose-Prince/UWUCompiler/lib"
 	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
@@ -43,6 +48,23 @@ func (self GrammarToken) String() string {
 	return b.String()
 }
 
+// Returns the name of the token as it would be written on a grammar file.
+func (self GrammarToken) Symbol() string {
+	if self.IsTerminal() {
+		val := self.Terminal.GetValue()
+		if val.HasValue() {
+			return val.GetValue()
+		}
+		return "ε"
+	} else if self.IsNonTerminal() {
+		return self.NonTerminal.GetValue()
+	} else if self.IsEnd {
+		return "$"
+	}
+
+	return "INVALID"
+}
+
 func NewEndToken() GrammarToken {
 	return GrammarToken{
 		IsEnd: true,
@@ -56,6 +78,16 @@ func NewTerminalToken(val string) GrammarToken {
 	}
 }
 
+// The name of the reserved terminal used on productions to recover from syntax errors, just like on yacc.
+const ERROR_TOKEN_NAME = "error"
+
+// The token the parser shifts when it recovers from a syntax error.
+//
+// It doesn't need to be declared with %token and no lex rule can return it.
+func NewErrorToken() GrammarToken {
+	return NewTerminalToken(ERROR_TOKEN_NAME)
+}
+
 func NewNonTerminalToken(val string) GrammarToken {
 	return GrammarToken{
 		NonTerminal: lib.CreateValue(val),
@@ -101,7 +133,8 @@ func NewFirstFollowTable() FirstFollowTable {
 	}
 }
 
-func (self *FirstFollowTable) AppendFirst(key GrammarToken, val GrammarToken) {
+// Adds val to the first of key, returns false if it was already there.
+func (self *FirstFollowTable) AppendFirst(key GrammarToken, val GrammarToken) bool {
 	if _, found := self.table[key]; !found {
 		self.table[key] = FirstFollowRow{
 			First:  lib.NewSet[GrammarToken](),
@@ -111,13 +144,15 @@ func (self *FirstFollowTable) AppendFirst(key GrammarToken, val GrammarToken) {
 
 	row := self.table[key]
 	first := row.First
-	first.Add(val)
+	added := first.Add(val)
 
 	row.First = first
 	self.table[key] = row
+	return added
 }
 
-func (self *FirstFollowTable) AppendFollow(key GrammarToken, val GrammarToken) {
+// Adds val to the follow of key, returns false if it was already there.
+func (self *FirstFollowTable) AppendFollow(key GrammarToken, val GrammarToken) bool {
 	if _, found := self.table[key]; !found {
 		self.table[key] = FirstFollowRow{
 			First:  lib.NewSet[GrammarToken](),
@@ -127,10 +162,11 @@ func (self *FirstFollowTable) AppendFollow(key GrammarToken, val GrammarToken) {
 
 	row := self.table[key]
 	follow := row.Follow
-	follow.Add(val)
+	added := follow.Add(val)
 
 	row.Follow = follow
 	self.table[key] = row
+	return added
 }
 
 func (self *GrammarToken) IsTerminal() bool {
@@ -160,6 +196,14 @@ func (self *GrammarToken) Equal(other *GrammarToken) bool {
 type GrammarRule struct {
 	Head       GrammarToken
 	Production []GrammarToken
+	// The terminal given with %prec to take the precedence from.
+	// If it's not set the last terminal of the production is used.
+	PrecedenceToken lib.Optional[GrammarToken]
+	// The Go code to execute when the rule is reduced.
+	// It can use $$ for the value of the head and $1..$n for the values of the production.
+	SemanticAction string
+	// The expression the rule was generated for if its head was created for a group or an operator like `stmt*`
+	Generated lib.Optional[EBNFSource]
 }
 
 func (self GrammarRule) ToString() string {
@@ -179,6 +223,11 @@ type Grammar struct {
 	// You can use the file definition order,
 	// so the first defined token will have id 0 and so on
 	TokenIds map[GrammarToken]parsertypes.GrammarToken
+	// The precedence of the terminals declared with %left, %right or %nonassoc.
+	Precedences map[GrammarToken]Precedence
+	// Where the symbols are defined on the yal file,
+	// the first rule of the nonterminals and the declaration of the tokens.
+	Positions map[GrammarToken]SymbolPosition
 }
 
 func (g *Grammar) FindIndexOfRule(rule *AutomataItem) int {
@@ -259,182 +308,155 @@ func (g *Grammar) GetTokenByString(tokenStr string) GrammarToken {
 	return NewTerminalToken(tokenStr)
 }
 
-func GetFirsts(grammar *Grammar, table *FirstFollowTable) {
-	alreadyEvaluatedFirsts := lib.NewSet[GrammarToken]()
-
-	for nonTerminal := range grammar.NonTerminals {
-		getFirstFor(grammar, table, &nonTerminal, &alreadyEvaluatedFirsts)
-	}
+// Computes the nonterminals that can derive the empty string.
+//
+// A nonterminal is nullable when one of its productions only has nullable symbols,
+// so the rules are checked until no new nonterminal is found.
+func GetNullables(grammar *Grammar) lib.Set[GrammarToken] {
+	nullables := lib.NewSet[GrammarToken]()
 
-	for terminal := range grammar.Terminals {
-		table.AppendFirst(terminal, terminal)
-	}
+	changed := true
+	for changed {
+		changed = false
 
-	table.AppendFirst(NewEndToken(), NewEndToken())
-}
+		for _, rule := range grammar.Rules {
+			if nullables.Contains(rule.Head) {
+				continue
+			}
 
-func getAllRulesWhereTokenIsHead(grammar *Grammar, token *GrammarToken) []GrammarRule {
-	rules := []GrammarRule{}
-	for _, rule := range grammar.Rules {
-		if rule.Head.Equal(token) {
-			rules = append(rules, rule)
+			nullable := true
+			for _, symbol := range rule.Production {
+				if !IsEpsilon(symbol) && !nullables.Contains(symbol) {
+					nullable = false
+					break
+				}
+			}
+			if nullable {
+				nullables.Add(rule.Head)
+				changed = true
+			}
 		}
 	}
-	return rules
+
+	return nullables
 }
 
-func getFirstFor(grammar *Grammar, table *FirstFollowTable, current *GrammarToken, alreadyEvaluated *lib.Set[GrammarToken]) {
-	if !alreadyEvaluated.Add(*current) {
-		return
+// Computes the firsts of every symbol of the grammar.
+//
+// The first of a nonterminal are the terminals its productions can start with,
+// the ones of a nullable nonterminal also have ε.
+func GetFirsts(grammar *Grammar, table *FirstFollowTable) {
+	for terminal := range grammar.Terminals {
+		table.AppendFirst(terminal, terminal)
 	}
+	table.AppendFirst(NewEndToken(), NewEndToken())
 
-	rulesWhereHead := getAllRulesWhereTokenIsHead(grammar, current)
-	for _, rule := range rulesWhereHead {
-		firstFromProduction := rule.Production[0]
-
-		if firstFromProduction.IsTerminal() {
-			table.AppendFirst(*current, firstFromProduction)
-
-		} else if !firstFromProduction.Equal(current) {
-			getFirstFor(grammar, table, &firstFromProduction, alreadyEvaluated)
-
-			for evl := range table.table[firstFromProduction].First {
-				table.AppendFirst(*current, evl)
-			}
-		}
+	nullables := GetNullables(grammar)
+	for nullable := range nullables {
+		table.AppendFirst(nullable, CreateEpsilonToken())
 	}
-}
 
-func GetFollows(grammar *Grammar, table *FirstFollowTable) {
 	changed := true
-
-	table.AppendFollow(grammar.InitialSimbol, NewTerminalToken("$"))
-
-	for val := range grammar.NonTerminals {
-		if _, exists := table.table[val]; !exists {
-			table.table[val] = FirstFollowRow{
-				First:  lib.NewSet[GrammarToken](),
-				Follow: lib.NewSet[GrammarToken](),
-			}
-		}
-	}
-
 	for changed {
 		changed = false
 
 		for _, rule := range grammar.Rules {
-			A := rule.Head
-			production := rule.Production
-
-			for i, B := range production {
-				follow := table.table[B].Follow
-
-				if !B.IsNonTerminal() {
+			for _, symbol := range rule.Production {
+				if IsEpsilon(symbol) {
 					continue
 				}
 
-				if i+1 < len(production) {
-					beta := production[i+1:]
-
-					for _, terminal := range beta {
-						if terminal.IsTerminal() {
-
-							if follow.Add(terminal) {
-								changed = true
-							}
-						} else {
-							newTerminal := derivateNonTerminal(terminal, grammar)
+				if symbol.IsTerminal() || symbol.IsEnd {
+					changed = table.AppendFirst(rule.Head, symbol) || changed
+					break
+				}
 
-							if follow.Add(newTerminal) {
-								changed = true
-							}
-						}
+				for terminal := range table.table[symbol].First {
+					if !IsEpsilon(terminal) {
+						changed = table.AppendFirst(rule.Head, terminal) || changed
 					}
-
+				}
+				if !nullables.Contains(symbol) {
 					break
-				} else {
-					for terminal := range table.table[A].Follow {
-						if follow.Add(terminal) {
-							changed = true
-						}
-					}
 				}
 			}
 		}
 	}
 }
 
-func derivateNonTerminal(token GrammarToken, grammar *Grammar) GrammarToken {
-
-	if token.IsTerminal() {
-		return token
-	}
+// The terminals a sequence of symbols can start with, with ε if all of them are nullable.
+//
+// The firsts of the symbols must be already computed by GetFirsts.
+func (self *FirstFollowTable) FirstOfSequence(symbols []GrammarToken) lib.Set[GrammarToken] {
+	result := lib.NewSet[GrammarToken]()
+	for _, symbol := range symbols {
+		if IsEpsilon(symbol) {
+			continue
+		}
 
-	for _, rule := range grammar.Rules {
-		recursive := false
+		if symbol.IsTerminal() || symbol.IsEnd {
+			result.Add(symbol)
+			return result
+		}
 
-		// Check if rule isnt recursive
-		for _, producction := range rule.Production {
-			if producction.Equal(&token) {
-				recursive = true
+		nullable := false
+		for terminal := range self.table[symbol].First {
+			if IsEpsilon(terminal) {
+				nullable = true
+			} else {
+				result.Add(terminal)
 			}
 		}
-
-		if rule.Head.Equal(&token) && len(rule.Production) == 1 && !recursive {
-			return derivateNonTerminal(rule.Production[0], grammar)
+		if !nullable {
+			return result
 		}
 	}
 
-	return CreateEpsilonToken()
+	result.Add(CreateEpsilonToken())
+	return result
 }
 
-func (g *Grammar) First(token GrammarToken) []GrammarToken {
-	result := make(map[string]GrammarToken)
+// Computes the follows of every nonterminal of the grammar.
+//
+// The follow of a nonterminal B are all the terminals that can appear right after it:
+// * The first of the symbols that come after B on a production.
+// * The follow of the head of a production when the symbols after B are nullable.
+func GetFollows(grammar *Grammar, table *FirstFollowTable) {
+	firsts := NewFirstFollowTable()
+	GetFirsts(grammar, &firsts)
 
-	if token.IsTerminal() {
-		result[token.String()] = token
-		return mapToSlice(result)
+	for val := range grammar.NonTerminals {
+		if _, exists := table.table[val]; !exists {
+			table.table[val] = FirstFollowRow{
+				First:  lib.NewSet[GrammarToken](),
+				Follow: lib.NewSet[GrammarToken](),
+			}
+		}
 	}
+	table.AppendFollow(grammar.InitialSimbol, NewEndToken())
 
-	for _, rule := range g.Rules {
-		if rule.Head.Equal(&token) {
-			for i := 0; i < len(rule.Production); i++ {
-				symbol := rule.Production[i]
-				if symbol.IsTerminal() {
-					result[symbol.String()] = symbol
-					break
+	changed := true
+	for changed {
+		changed = false
+
+		for _, rule := range grammar.Rules {
+			for i, B := range rule.Production {
+				if !B.IsNonTerminal() {
+					continue
 				}
 
-				firsts := g.First(symbol)
-				hasEpsilon := false
-				for _, f := range firsts {
-					if f.String() == "ε" {
-						hasEpsilon = true
+				for terminal := range firsts.FirstOfSequence(rule.Production[i+1:]) {
+					if IsEpsilon(terminal) {
+						for follow := range table.table[rule.Head].Follow {
+							changed = table.AppendFollow(B, follow) || changed
+						}
 					} else {
-						result[f.String()] = f
+						changed = table.AppendFollow(B, terminal) || changed
 					}
 				}
-
-				if !hasEpsilon {
-					break
-				}
-
-				if i == len(rule.Production)-1 && hasEpsilon {
-					result["ε"] = NewEndToken()
-				}
 			}
 		}
 	}
-
-	return mapToSlice(result)
-}
-
-func mapToSlice(m map[string]GrammarToken) []GrammarToken {
-	slice := make([]GrammarToken, 0, len(m))
-	for _, v := range m {
-		slice = append(slice, v)
-	}
-	return slice
 }
 
 func (r1 GrammarRule) EqualRule(r2 *GrammarRule) bool {
@@ -455,6 +477,9 @@ func (r1 GrammarRule) EqualRule(r2 *GrammarRule) bool {
 	return true
 }
 
+// Parses a yal file.
+//
+// Every problem found on the file is collected and returned as lib.Diagnostics.
 func ParseYalFile(filename string) (Grammar, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -469,6 +494,11 @@ func ParseYalFile(filename string) (Grammar, error) {
 		tokenIds      = make(map[GrammarToken]parsertypes.GrammarToken)
 		initialSymbol GrammarToken
 		foundStart    = false
+		precedences   = make(map[GrammarToken]Precedence)
+		diagnostics   = lib.Diagnostics{}
+		// Where each symbol of the rules appears for the first time
+		symbolPositions = make(map[string]SymbolPosition)
+		positions       = make(map[GrammarToken]SymbolPosition)
 	)
 
 	terminals.Add(NewEndToken())
@@ -476,14 +506,44 @@ func ParseYalFile(filename string) (Grammar, error) {
 	scanner := bufio.NewScanner(file)
 	mode := "header"
 	tokenIdCounter := 0
+	precedenceLevel := 0
 
 	// Buffer to accumulate multi-line rules
 	var currentRule strings.Builder
 	var currentHead string
+	// Where the current rule starts
+	ruleLine, ruleCol := 0, 0
 	inRule := false
+	helpers := newEBNFHelpers()
+	// Where every line of the current rule is on its body
+	ruleSegments := []ruleSegment{}
+	process := func(ruleBody string) {
+		recordSymbolPositions(ruleBody, ruleSegments, symbolPositions)
+		invalidValues, err := processRule(currentHead, ruleBody, ruleLine, &rules, terminals, nonTerminals, &helpers)
+		if err != nil {
+			diagnostics.Add(filename, ruleLine, ruleCol, "%s", err)
+		}
+		for _, value := range invalidValues {
+			position := positionOnRule(ruleSegments, value.Offset)
+			diagnostics.Add(filename, position.Line, position.Col, "`%s` isn't a symbol of the production, it has %d symbols numbered from $1", value.Value, value.Symbols)
+		}
+	}
+	// How many `{` of semantic actions are still open
+	actionDepth := 0
+	actionStartLine := 0
+	lineNumber := 0
+	// Remembers where a symbol is declared or gets its first rule
+	define := func(token GrammarToken, col int) {
+		if _, found := positions[token]; !found {
+			positions[token] = SymbolPosition{Line: lineNumber, Col: col}
+		}
+	}
 
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+		rawLine := scanner.Text()
+		line := strings.TrimSpace(rawLine)
+		lineCol := strings.Index(rawLine, line) + 1
 
 		// Skip empty lines and comments (both // and /* */ style)
 		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "/*") {
@@ -503,8 +563,9 @@ func ParseYalFile(filename string) (Grammar, error) {
 				tokenLine := strings.TrimPrefix(line, "%token")
 				tokenLine = strings.TrimSpace(tokenLine)
 				parts := strings.Fields(tokenLine)
+				starts := fieldStarts(line, len("%token"))
 
-				for _, part := range parts {
+				for i, part := range parts {
 					// Skip commented out tokens
 					if strings.HasPrefix(part, "/*") || strings.HasSuffix(part, "*/") {
 						continue
@@ -512,22 +573,53 @@ func ParseYalFile(filename string) (Grammar, error) {
 
 					tok := NewTerminalToken(part)
 					terminals.Add(tok)
+					define(tok, lineCol+starts[i])
 					tokenIds[tok] = parsertypes.GrammarToken(tokenIdCounter)
 					tokenIdCounter++
 				}
+			} else if assoc, found := associativityFromDirective(line); found {
+				// Each precedence line binds tighter than the ones before it
+				precedenceLevel++
+
+				fields := strings.Fields(line)
+				starts := fieldStarts(line, 0)
+				for i, part := range fields[1:] {
+					if strings.HasPrefix(part, "/*") || strings.HasSuffix(part, "*/") {
+						continue
+					}
+
+					// Like yacc, tokens used on a precedence line don't need a %token line
+					tok := NewTerminalToken(part)
+					if text, isLiteral := unquoteLiteral(part); isLiteral {
+						tok = NewLiteralToken(text)
+					}
+					if terminals.Add(tok) {
+						tokenIds[tok] = parsertypes.GrammarToken(tokenIdCounter)
+						tokenIdCounter++
+					}
+					define(tok, lineCol+starts[i+1])
+					precedences[tok] = Precedence{Level: precedenceLevel, Associativity: assoc}
+				}
 			} else if strings.HasPrefix(line, "%start") {
 				// Parse start symbol
 				sym := strings.TrimSpace(strings.TrimPrefix(line, "%start"))
+				if sym == "" {
+					diagnostics.Add(filename, lineNumber, lineCol, "%%start needs the name of the initial symbol")
+					continue
+				}
 				initialSymbol = NewNonTerminalToken(sym)
 				nonTerminals.Add(initialSymbol)
 				foundStart = true
+			} else if !strings.HasPrefix(line, "IGNORE") {
+				diagnostics.Add(filename, lineNumber, lineCol, "unknown directive `%s`", strings.Fields(line)[0])
 			}
 		} else if mode == "rules" {
 			// Handle rule parsing
-			if strings.Contains(line, ":") {
+			// Lines inside a semantic action are Go code, so they can't start a rule
+			if actionDepth == 0 && ruleHeadRegex.MatchString(line) {
 				// Process any accumulated rule first
 				if inRule && currentRule.Len() > 0 {
-					processRule(currentHead, currentRule.String(), &rules, terminals, nonTerminals)
+					process(currentRule.String())
 					currentRule.Reset()
 				}
 
@@ -535,10 +627,12 @@ func ParseYalFile(filename string) (Grammar, error) {
 				parts := strings.SplitN(line, ":", 2)
 				currentHead = strings.TrimSpace(parts[0])
 				ruleBody := strings.TrimSpace(parts[1])
+				ruleLine, ruleCol = lineNumber, lineCol
 
 				// Add head to non-terminals
 				headToken := NewNonTerminalToken(currentHead)
 				nonTerminals.Add(headToken)
+				define(headToken, lineCol)
 
 				// If there's no start symbol defined, use the first rule's head
 				if !foundStart {
@@ -547,27 +641,39 @@ func ParseYalFile(filename string) (Grammar, error) {
 				}
 
 				inRule = true
+				ruleSegments = ruleSegments[:0]
 				if ruleBody != "" {
+					bodyCol := lineCol + len(parts[0]) + 1 + strings.Index(parts[1], ruleBody)
+					ruleSegments = append(ruleSegments, ruleSegment{offset: 0, line: lineNumber, col: bodyCol})
 					currentRule.WriteString(ruleBody)
 				}
 			} else if inRule {
 				// Continue accumulating rule body
+				// New lines are kept so the code of the semantic actions doesn't change
 				if line != "" {
 					if currentRule.Len() > 0 {
-						currentRule.WriteString(" ")
+						currentRule.WriteString("\n")
 					}
+					ruleSegments = append(ruleSegments, ruleSegment{offset: currentRule.Len(), line: lineNumber, col: lineCol})
 					currentRule.WriteString(line)
 				}
+			} else if actionDepth == 0 {
+				diagnostics.Add(filename, lineNumber, lineCol, "expected a rule like `head: symbols ;` but found `%s`", line)
+			}
+
+			if actionDepth == 0 {
+				actionStartLine = lineNumber
 			}
+			actionDepth = actionDepthAfter(line, actionDepth)
 
 			// Check if rule ends with semicolon
-			if strings.HasSuffix(line, ";") {
+			if actionDepth == 0 && strings.HasSuffix(line, ";") {
 				if inRule && currentRule.Len() > 0 {
 					ruleStr := currentRule.String()
 					if strings.HasSuffix(ruleStr, ";") {
 						ruleStr = strings.TrimSuffix(ruleStr, ";")
 					}
-					processRule(currentHead, ruleStr, &rules, terminals, nonTerminals)
+					process(ruleStr)
 					currentRule.Reset()
 					inRule = false
 				}
@@ -581,24 +687,65 @@ func ParseYalFile(filename string) (Grammar, error) {
 		if strings.HasSuffix(ruleStr, ";") {
 			ruleStr = strings.TrimSuffix(ruleStr, ";")
 		}
-		processRule(currentHead, ruleStr, &rules, terminals, nonTerminals)
+		process(ruleStr)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return Grammar{}, err
 	}
 
-	if !foundStart {
-		return Grammar{}, fmt.Errorf("no start symbol defined and no rules found")
+	if actionDepth > 0 {
+		diagnostics.Add(filename, actionStartLine, 1, "unterminated semantic action, expected a `}` to close it")
 	}
 
-	// Assign token IDs to non-terminals
-	for nonTerminal := range nonTerminals {
-		if _, exists := tokenIds[nonTerminal]; !exists {
+	if mode == "header" {
+		diagnostics.Add(filename, max(lineNumber, 1), 1, "missing `%%%%` before the rules")
+	} else if !foundStart {
+		diagnostics.Add(filename, max(lineNumber, 1), 1, "the grammar doesn't define any rule")
+	}
+
+	helpers.finish(&rules, terminals, nonTerminals)
+	checkRuleSymbols(filename, rules, terminals, symbolPositions, positions, &diagnostics)
+	if len(diagnostics) > 0 {
+		diagnostics.Sort()
+		return Grammar{}, diagnostics
+	}
+
+	// The literals get their ids in the order they're used
+	for _, rule := range rules {
+		for _, token := range rule.Production {
+			if _, exists := tokenIds[token]; token.IsTerminal() && !exists && token != NewErrorToken() {
+				tokenIds[token] = parsertypes.GrammarToken(tokenIdCounter)
+				tokenIdCounter++
+			}
+		}
+	}
+
+	// The error token is the only other terminal that can be used without declaring it
+	errorToken := NewErrorToken()
+	if _, exists := tokenIds[errorToken]; terminals.Contains(errorToken) && !exists {
+		tokenIds[errorToken] = parsertypes.GrammarToken(tokenIdCounter)
+		tokenIdCounter++
+	}
+
+	// The non-terminals get their ids in the order they appear, heads first,
+	// so the generated tables are the same on every run
+	assignNonTerminalId := func(nonTerminal GrammarToken) {
+		if _, exists := tokenIds[nonTerminal]; nonTerminals.Contains(nonTerminal) && !exists {
 			tokenIds[nonTerminal] = parsertypes.GrammarToken(tokenIdCounter)
 			tokenIdCounter++
 		}
 	}
+	for _, rule := range rules {
+		assignNonTerminalId(rule.Head)
+	}
+	for _, rule := range rules {
+		for _, token := range rule.Production {
+			if !token.IsTerminal() {
+				assignNonTerminalId(token)
+			}
+		}
+	}
 
 	// Add end token
 	endToken := NewEndToken()
@@ -610,59 +757,175 @@ func ParseYalFile(filename string) (Grammar, error) {
 		Terminals:     terminals,
 		NonTerminals:  nonTerminals,
 		TokenIds:      tokenIds,
+		Precedences:   precedences,
+		Positions:     positions,
 	}
 
 	return gram, nil
 }
 
 // processRule processes a complete rule body and creates grammar rules
-func processRule(headName, ruleBody string, rules *[]GrammarRule, terminals lib.Set[GrammarToken], nonTerminals lib.Set[GrammarToken]) {
+//
+// The groups and operators of the productions are replaced by the nonterminals of helpers,
+// line is the line where the rule starts so they can point to it.
+// Returns the $n of the semantic actions that aren't symbols of their productions.
+func processRule(headName, ruleBody string, line int, rules *[]GrammarRule, terminals lib.Set[GrammarToken], nonTerminals lib.Set[GrammarToken], helpers *ebnfHelpers) ([]invalidSemanticValue, error) {
 	headToken := NewNonTerminalToken(headName)
 	nonTerminals.Add(headToken)
 
+	var firstErr error
+	invalidValues := []invalidSemanticValue{}
+
 	// Split by | for alternative productions
-	alternatives := strings.Split(ruleBody, "|")
+	alternatives := splitAlternatives(ruleBody)
 
+	// Where the alternative starts on the rule body, each one is followed by its `|`
+	altOffset := 0
 	for _, alt := range alternatives {
-		alt = strings.TrimSpace(alt)
-		if alt == "" {
+		symbols, semanticAction, actionOffset := locateSemanticAction(alt)
+		actionOffset += altOffset
+		altOffset += len(alt) + 1
+
+		written, precedenceToken, err := parseAlternative(symbols, terminals)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("invalid production `%s` of %s: %w", strings.Join(strings.Fields(symbols), " "), headName, err)
+			}
 			continue
 		}
 
-		production := []GrammarToken{}
-		symbols := strings.Fields(alt)
-
-		for _, sym := range symbols {
-			sym = strings.TrimSpace(sym)
-			if sym == "" {
-				continue
+		productions := [][]GrammarToken{}
+		for _, elements := range written {
+			production := []GrammarToken{}
+			for _, element := range elements {
+				production = append(production, helpers.desugar(element, headToken, line, terminals, nonTerminals)...)
 			}
+			productions = append(productions, production)
+
+			*rules = append(*rules, GrammarRule{
+				Head:            headToken,
+				Production:      production,
+				PrecedenceToken: precedenceToken,
+				SemanticAction:  semanticAction,
+			})
+		}
+		invalidValues = append(invalidValues, checkSemanticValues(semanticAction, actionOffset, productions)...)
+	}
+
+	return invalidValues, firstErr
+}
 
-			var tok GrammarToken
+// A part of a rule body read from a line of the file.
+type ruleSegment struct {
+	// Where the part starts on the rule body
+	offset int
+	line   int
+	col    int
+}
 
-			// Check for epsilon
-			if sym == "ε" || sym == "epsilon" || sym == "EPSILON" {
-				tok = CreateEpsilonToken()
-			} else if isTerminal(sym, terminals) {
-				tok = NewTerminalToken(sym)
-			} else {
-				// It's a non-terminal
-				tok = NewNonTerminalToken(sym)
-				nonTerminals.Add(tok)
+// The line and column of the file where an offset of the rule body read from segments is.
+func positionOnRule(segments []ruleSegment, offset int) SymbolPosition {
+	position := SymbolPosition{}
+	for _, segment := range segments {
+		if segment.offset > offset {
+			break
+		}
+		position = SymbolPosition{Line: segment.line, Col: segment.col + offset - segment.offset}
+	}
+
+	return position
+}
+
+type SymbolPosition struct {
+	Line int
+	Col  int
+}
+
+// Remembers where each symbol of a rule body outside of semantic actions appears for the first time.
+//
+// segments are the parts of the body read from every line of the file.
+func recordSymbolPositions(body string, segments []ruleSegment, positions map[string]SymbolPosition) {
+	scanner := actionScanner{}
+	start := -1
+	record := func(end int) {
+		if start != -1 {
+			symbol := body[start:end]
+			if _, found := positions[symbol]; !found {
+				positions[symbol] = positionOnRule(segments, start)
 			}
+		}
+		start = -1
+	}
+
+	for i, r := range body {
+		inAction := scanner.next(r)
+		// The same separators tokenizeAlternative splits the symbols with
+		separator := !scanner.inLiteral() && (unicode.IsSpace(r) || strings.ContainsRune("|()", r) || strings.ContainsRune(EBNF_OPERATORS, r))
+		if inAction || separator {
+			record(i)
+		} else if start == -1 {
+			start = i
+		}
+	}
+	record(len(body))
+}
+
+// The offset where every field of the line starts, ignoring the first skip bytes.
+func fieldStarts(line string, skip int) []int {
+	starts := []int{}
+	inField := false
+	for i, r := range line {
+		isField := i >= skip && !unicode.IsSpace(r)
+		if isField && !inField {
+			starts = append(starts, i)
+		}
+		inField = isField
+	}
+
+	return starts
+}
 
-			production = append(production, tok)
+// Reports the symbols that are neither declared tokens nor have rules,
+// and %prec directives without a declared token.
+//
+// They're reported where they're written for the first time on the rule bodies,
+// or on the head of their rule if the symbol isn't found there.
+func checkRuleSymbols(filename string, rules []GrammarRule, terminals lib.Set[GrammarToken], positions map[string]SymbolPosition, headPositions map[GrammarToken]SymbolPosition, diagnostics *lib.Diagnostics) {
+	heads := lib.NewSet[GrammarToken]()
+	for _, rule := range rules {
+		heads.Add(rule.Head)
+	}
+
+	reported := lib.NewSet[string]()
+	report := func(rule GrammarRule, symbol string, format string, args ...any) {
+		if !reported.Add(symbol) {
+			return
+		}
+
+		position, found := positions[symbol]
+		if !found {
+			position = headPositions[rule.Head]
 		}
+		diagnostics.Add(filename, position.Line, position.Col, format, args...)
+	}
 
-		// Handle empty production (epsilon)
-		if len(production) == 0 {
-			production = append(production, CreateEpsilonToken())
+	for _, rule := range rules {
+		for _, token := range rule.Production {
+			if token.IsNonTerminal() && !heads.Contains(token) {
+				name := token.NonTerminal.GetValue()
+				report(rule, name, "unknown symbol `%s`, it isn't a declared token and doesn't have rules", name)
+			}
 		}
 
-		*rules = append(*rules, GrammarRule{
-			Head:       headToken,
-			Production: production,
-		})
+		if rule.PrecedenceToken.HasValue() {
+			token := rule.PrecedenceToken.GetValue()
+			name := token.Symbol()
+			if name == "" {
+				report(rule, "%prec", "%%prec needs the name of a token")
+			} else if !terminals.Contains(token) {
+				report(rule, name, "unknown token `%s` on %%prec", name)
+			}
+		}
 	}
 }
 
@@ -671,6 +934,82 @@ func isTerminal(symbol string, terminals lib.Set[GrammarToken]) bool {
 	return terminals.Contains(testToken)
 }
 
+// Writes the grammar as a yal file that ParseYalFile reads back as the same grammar.
+//
+// The terminals are declared in the order of their ids so they keep them,
+// the empty productions are written as ε.
+func (g *Grammar) WriteYal(w io.Writer) error {
+	b := strings.Builder{}
+
+	terminals := []GrammarToken{}
+	for terminal := range g.Terminals {
+		if !terminal.IsEnd && terminal != NewErrorToken() {
+			terminals = append(terminals, terminal)
+		}
+	}
+	slices.SortFunc(terminals, func(a, b GrammarToken) int {
+		if c := cmp.Compare(g.TokenIds[a], g.TokenIds[b]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Symbol(), b.Symbol())
+	})
+	// The literals don't need to be declared
+	declared := slices.DeleteFunc(slices.Clone(terminals), func(terminal GrammarToken) bool {
+		_, isLiteral := terminal.LiteralText()
+		return isLiteral
+	})
+	for i := 0; i < len(declared); i += 8 {
+		b.WriteString("%token")
+		for _, terminal := range declared[i:min(i+8, len(declared))] {
+			b.WriteString(" " + terminal.Symbol())
+		}
+		b.WriteRune('\n')
+	}
+
+	levels := make(map[int][]GrammarToken)
+	for _, terminal := range terminals {
+		if precedence, found := g.Precedences[terminal]; found {
+			levels[precedence.Level] = append(levels[precedence.Level], terminal)
+		}
+	}
+	for _, level := range slices.Sorted(maps.Keys(levels)) {
+		b.WriteString(g.Precedences[levels[level][0]].Associativity.String())
+		for _, terminal := range levels[level] {
+			b.WriteString(" " + terminal.Symbol())
+		}
+		b.WriteRune('\n')
+	}
+
+	if len(g.Rules) == 0 || g.Rules[0].Head != g.InitialSimbol {
+		b.WriteString("%start " + g.InitialSimbol.Symbol() + "\n")
+	}
+	b.WriteString("%%\n")
+
+	for i, rule := range g.Rules {
+		if i == 0 || g.Rules[i-1].Head != rule.Head {
+			b.WriteString("\n" + rule.Head.Symbol() + ":\n\t")
+		} else {
+			b.WriteString("\t| ")
+		}
+
+		b.WriteString(productionToHuman(rule.Production))
+		if rule.PrecedenceToken.HasValue() {
+			b.WriteString(" %prec " + rule.PrecedenceToken.GetValue().Symbol())
+		}
+		if rule.SemanticAction != "" {
+			b.WriteString(" { " + rule.SemanticAction + " }")
+		}
+		b.WriteRune('\n')
+
+		if i+1 == len(g.Rules) || g.Rules[i+1].Head != rule.Head {
+			b.WriteString(";\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
 // Helper function to print grammar for debugging
 func (g Grammar) PrintGrammar() {
 	fmt.Println("=== GRAMMAR ===")
@@ -1,6 +1,7 @@
 package grammar
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -153,6 +154,12 @@ func TestGetFirsts(t *testing.T) {
 		},
 	}
 
+	// The first of a terminal is always the terminal itself
+	for terminal := range grammar.Terminals {
+		expectedTable.AppendFirst(terminal, terminal)
+	}
+	expectedTable.AppendFirst(NewEndToken(), NewEndToken())
+
 	GetFirsts(&grammar, &table)
 
 	compareTables(t, &expectedTable, &table)
@@ -166,28 +173,25 @@ func TestGetFollows(t *testing.T) {
 		table: map[GrammarToken]FirstFollowRow{
 			NewNonTerminalToken("S"): FirstFollowRow{
 				Follow: lib.Set[GrammarToken]{
-					NewTerminalToken("$"):        struct{}{},
-					NewTerminalToken("^"):        struct{}{},
-					NewTerminalToken("sentence"): struct{}{},
-					NewTerminalToken("]"):        struct{}{},
+					NewEndToken():         struct{}{},
+					NewTerminalToken("^"): struct{}{},
+					NewTerminalToken("]"): struct{}{},
 				},
 			},
 			NewNonTerminalToken("P"): FirstFollowRow{
 				Follow: lib.Set[GrammarToken]{
-					NewTerminalToken("$"):        struct{}{},
-					NewTerminalToken("^"):        struct{}{},
-					NewTerminalToken("sentence"): struct{}{},
-					NewTerminalToken("]"):        struct{}{},
-					NewTerminalToken("v"):        struct{}{},
+					NewEndToken():         struct{}{},
+					NewTerminalToken("^"): struct{}{},
+					NewTerminalToken("]"): struct{}{},
+					NewTerminalToken("v"): struct{}{},
 				},
 			},
 			NewNonTerminalToken("Q"): FirstFollowRow{
 				Follow: lib.Set[GrammarToken]{
-					NewTerminalToken("$"):        struct{}{},
-					NewTerminalToken("^"):        struct{}{},
-					NewTerminalToken("sentence"): struct{}{},
-					NewTerminalToken("]"):        struct{}{},
-					NewTerminalToken("v"):        struct{}{},
+					NewEndToken():         struct{}{},
+					NewTerminalToken("^"): struct{}{},
+					NewTerminalToken("]"): struct{}{},
+					NewTerminalToken("v"): struct{}{},
 				},
 			},
 		},
@@ -197,3 +201,446 @@ func TestGetFollows(t *testing.T) {
 
 	compareTables(t, &expectedTable, &table)
 }
+
+// E → T E'
+// E' → + T E' | ε
+// T → F T'
+// T' → * F T' | ε
+// F → ( E ) | id
+func createEpsilonGrammar() Grammar {
+	E := NewNonTerminalToken("E")
+	E2 := NewNonTerminalToken("E'")
+	T := NewNonTerminalToken("T")
+	T2 := NewNonTerminalToken("T'")
+	F := NewNonTerminalToken("F")
+	plus := NewTerminalToken("+")
+	mult := NewTerminalToken("*")
+	lparen := NewTerminalToken("(")
+	rparen := NewTerminalToken(")")
+	id := NewTerminalToken("id")
+
+	return newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{T, E2}},
+		{Head: E2, Production: []GrammarToken{plus, T, E2}},
+		{Head: E2, Production: []GrammarToken{}},
+		{Head: T, Production: []GrammarToken{F, T2}},
+		{Head: T2, Production: []GrammarToken{mult, F, T2}},
+		{Head: T2, Production: []GrammarToken{}},
+		{Head: F, Production: []GrammarToken{lparen, E, rparen}},
+		{Head: F, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, mult, lparen, rparen, id})
+}
+
+func TestGetNullables(t *testing.T) {
+	grammar := createEpsilonGrammar()
+	expected := lib.Set[GrammarToken]{
+		NewNonTerminalToken("E'"): struct{}{},
+		NewNonTerminalToken("T'"): struct{}{},
+	}
+
+	compareSets(t, expected, GetNullables(&grammar))
+}
+
+func TestGetFirstsWithEpsilon(t *testing.T) {
+	grammar := createEpsilonGrammar()
+	table := NewFirstFollowTable()
+	expectedTable := NewFirstFollowTable()
+
+	for _, nonTerminal := range []string{"E", "T", "F"} {
+		expectedTable.AppendFirst(NewNonTerminalToken(nonTerminal), NewTerminalToken("("))
+		expectedTable.AppendFirst(NewNonTerminalToken(nonTerminal), NewTerminalToken("id"))
+	}
+	expectedTable.AppendFirst(NewNonTerminalToken("E'"), NewTerminalToken("+"))
+	expectedTable.AppendFirst(NewNonTerminalToken("E'"), CreateEpsilonToken())
+	expectedTable.AppendFirst(NewNonTerminalToken("T'"), NewTerminalToken("*"))
+	expectedTable.AppendFirst(NewNonTerminalToken("T'"), CreateEpsilonToken())
+	for terminal := range grammar.Terminals {
+		expectedTable.AppendFirst(terminal, terminal)
+	}
+	expectedTable.AppendFirst(NewEndToken(), NewEndToken())
+
+	GetFirsts(&grammar, &table)
+
+	compareTables(t, &expectedTable, &table)
+
+	// The symbols after a nullable one are part of the first of the sequence
+	sequence := []GrammarToken{NewNonTerminalToken("T'"), NewNonTerminalToken("E'"), NewTerminalToken(")")}
+	expected := lib.Set[GrammarToken]{
+		NewTerminalToken("*"): struct{}{},
+		NewTerminalToken("+"): struct{}{},
+		NewTerminalToken(")"): struct{}{},
+	}
+	compareSets(t, expected, table.FirstOfSequence(sequence))
+
+	expected = lib.Set[GrammarToken]{
+		NewTerminalToken("*"): struct{}{},
+		NewTerminalToken("+"): struct{}{},
+		CreateEpsilonToken():  struct{}{},
+	}
+	compareSets(t, expected, table.FirstOfSequence(sequence[:2]))
+}
+
+func TestGetFollowsWithEpsilon(t *testing.T) {
+	grammar := createEpsilonGrammar()
+	table := NewFirstFollowTable()
+	expectedTable := NewFirstFollowTable()
+
+	follows := map[string][]GrammarToken{
+		"E":  {NewTerminalToken(")"), NewEndToken()},
+		"E'": {NewTerminalToken(")"), NewEndToken()},
+		"T":  {NewTerminalToken("+"), NewTerminalToken(")"), NewEndToken()},
+		"T'": {NewTerminalToken("+"), NewTerminalToken(")"), NewEndToken()},
+		"F":  {NewTerminalToken("*"), NewTerminalToken("+"), NewTerminalToken(")"), NewEndToken()},
+	}
+	for nonTerminal, terminals := range follows {
+		for _, terminal := range terminals {
+			expectedTable.AppendFollow(NewNonTerminalToken(nonTerminal), terminal)
+		}
+	}
+
+	GetFollows(&grammar, &table)
+
+	compareTables(t, &expectedTable, &table)
+}
+
+func TestParseYalFileEmptyProductions(t *testing.T) {
+	g, _, err := parseYalString(t, `%token A B C
+%%
+s: opts list C ;
+opts:
+	/* empty */
+	| A
+;
+list:
+	ε
+	| list B
+;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"s -> opts list C",
+		"opts -> ε",
+		"opts -> A",
+		"list -> ε",
+		"list -> list B",
+	}
+	if len(g.Rules) != len(expected) {
+		t.Fatalf("Expected %d rules but got %d", len(expected), len(g.Rules))
+	}
+	for i, rule := range g.Rules {
+		actual := rule.Head.Symbol() + " -> " + productionToHuman(rule.Production)
+		if actual != expected[i] {
+			t.Errorf("Expected rule %d to be `%s` but got `%s`", i, expected[i], actual)
+		}
+	}
+
+	if issues := g.Validate(); len(issues) > 0 {
+		t.Fatalf("Expected no issues but got %v", issues)
+	}
+}
+
+func TestParseYalFilePrecedences(t *testing.T) {
+	g, err := ParseYalFile("../../example/precedence/grammar.yal")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %s", err)
+	}
+
+	expected := map[string]Precedence{
+		"PLUS":   {Level: 1, Associativity: LEFT_ASSOC},
+		"MINUS":  {Level: 1, Associativity: LEFT_ASSOC},
+		"MULT":   {Level: 2, Associativity: LEFT_ASSOC},
+		"DIV":    {Level: 2, Associativity: LEFT_ASSOC},
+		"UMINUS": {Level: 3, Associativity: RIGHT_ASSOC},
+	}
+	if len(g.Precedences) != len(expected) {
+		t.Errorf("Expected %d precedences but got %d: %v", len(expected), len(g.Precedences), g.Precedences)
+	}
+	for name, prec := range expected {
+		tk := NewTerminalToken(name)
+		if g.Precedences[tk] != prec {
+			t.Errorf("Expected %s to have precedence %v but got %v", name, prec, g.Precedences[tk])
+		}
+		if _, found := g.TokenIds[tk]; !found {
+			t.Errorf("Expected %s to have a token id", name)
+		}
+	}
+
+	minus := NewTerminalToken("MINUS")
+	uminus := NewTerminalToken("UMINUS")
+	foundPrecRule := false
+	for i, rule := range g.Rules {
+		if !rule.PrecedenceToken.HasValue() {
+			continue
+		}
+		foundPrecRule = true
+
+		if precToken := rule.PrecedenceToken.GetValue(); !precToken.Equal(&uminus) {
+			t.Errorf("Expected rule %d to take the precedence of UMINUS but got %s", i, precToken.String())
+		}
+		if len(rule.Production) != 2 || !rule.Production[0].Equal(&minus) {
+			t.Errorf("The %%prec should not be part of the production: %s", rule.ToString())
+		}
+		if prec, _ := g.RulePrecedence(i); prec.Level != 3 {
+			t.Errorf("Expected rule %d to have precedence level 3 but got %d", i, prec.Level)
+		}
+	}
+	if !foundPrecRule {
+		t.Errorf("No rule with %%prec was found!")
+	}
+}
+
+func TestParseYalFileSemanticActions(t *testing.T) {
+	g, err := ParseYalFile("../../example/calculator/grammar.yal")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %s", err)
+	}
+
+	expected := []struct {
+		productionLength int
+		action           string
+	}{
+		{3, "$$ = $1.(int) + $3.(int)"},
+		{3, "$$ = $1.(int) - $3.(int)"},
+		{3, "$$ = $1.(int) * $3.(int)"},
+		{3, "if $3.(int) == 0 {\npanic(\"Division by zero!\")\n}\n$$ = $1.(int) / $3.(int)"},
+		{2, "$$ = -$2.(int)"},
+		{3, "$$ = $2"},
+		{1, "$$, _ = strconv.Atoi($1.(string))"},
+	}
+
+	if len(g.Rules) != len(expected) {
+		t.Fatalf("Expected %d rules but got %d", len(expected), len(g.Rules))
+	}
+
+	for i, exp := range expected {
+		rule := g.Rules[i]
+		if len(rule.Production) != exp.productionLength {
+			t.Errorf("Rule %d: expected %d symbols but got %s", i, exp.productionLength, rule.ToString())
+		}
+		if rule.SemanticAction != exp.action {
+			t.Errorf("Rule %d: expected action %q but got %q", i, exp.action, rule.SemanticAction)
+		}
+	}
+}
+
+func TestSplitAlternativesIgnoresActions(t *testing.T) {
+	body := "a b { if x || y { return } } | c { s := \"}|\" } | d"
+	alternatives := splitAlternatives(body)
+	if len(alternatives) != 3 {
+		t.Fatalf("Expected 3 alternatives but got %d: %q", len(alternatives), alternatives)
+	}
+
+	symbols, action := extractSemanticAction(alternatives[0])
+	if strings.TrimSpace(symbols) != "a b" || action != "if x || y { return }" {
+		t.Errorf("Wrong split of first alternative: %q %q", symbols, action)
+	}
+
+	symbols, action = extractSemanticAction(alternatives[1])
+	if strings.TrimSpace(symbols) != "c" || action != "s := \"}|\"" {
+		t.Errorf("Wrong split of second alternative: %q %q", symbols, action)
+	}
+
+	symbols, action = extractSemanticAction(alternatives[2])
+	if strings.TrimSpace(symbols) != "d" || action != "" {
+		t.Errorf("Wrong split of third alternative: %q %q", symbols, action)
+	}
+}
+
+func TestParseYalFileSemanticValueDiagnostics(t *testing.T) {
+	_, path, err := parseYalString(t, `%token NUM PLUS
+%%
+e: e PLUS NUM { $$ = $0 } | NUM { $$ = $1 } ;
+t: NUM {
+		s := "$5"
+		$$ = $2
+	}
+	| PLUS NUM { $$ = $2 }
+	| { $$ = '$' } ;
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":3:22: `$0` isn't a symbol of the production, it has 3 symbols numbered from $1",
+		path + ":6:8: `$2` isn't a symbol of the production, it has 1 symbols numbered from $1",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestParseYalFileDiagnostics(t *testing.T) {
+	_, path, err := parseYalString(t, `%token NUMBER PLUS
+%lft PLUS
+%%
+expr: expr PLUS term { $$ = $1 }
+	| NUMBER %prec MINUS
+	;
+stray
+stmt : : NUMBER ;
+list: NUMBER {
+	$$ = $1
+} missing
+	;
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":2:1: unknown directive `%lft`",
+		path + ":4:17: unknown symbol `term`, it isn't a declared token and doesn't have rules",
+		path + ":5:17: unknown token `MINUS` on %prec",
+		path + ":7:1: expected a rule like `head: symbols ;` but found `stray`",
+		path + ":8:8: unknown symbol `:`, it isn't a declared token and doesn't have rules",
+		path + ":11:3: unknown symbol `missing`, it isn't a declared token and doesn't have rules",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	Loop := NewNonTerminalToken("Loop")
+	Unused := NewNonTerminalToken("Unused")
+	Typo := NewNonTerminalToken("IDENT")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	never := NewTerminalToken("NEVER")
+	uminus := NewTerminalToken("UMINUS")
+
+	g := Grammar{
+		InitialSimbol: S,
+		Rules: []GrammarRule{
+			{Head: S, Production: []GrammarToken{A, Loop}},
+			{Head: S, Production: []GrammarToken{b, Typo}},
+			{Head: S, Production: []GrammarToken{a}, PrecedenceToken: lib.CreateValue(uminus)},
+			{Head: A, Production: []GrammarToken{a}},
+			{Head: Loop, Production: []GrammarToken{Loop, a}},
+			{Head: Unused, Production: []GrammarToken{b}},
+		},
+		Terminals: lib.Set[GrammarToken]{
+			a:             struct{}{},
+			b:             struct{}{},
+			never:         struct{}{},
+			uminus:        struct{}{},
+			NewEndToken(): struct{}{},
+		},
+		NonTerminals: lib.Set[GrammarToken]{
+			S: struct{}{}, A: struct{}{}, Loop: struct{}{}, Unused: struct{}{}, Typo: struct{}{},
+		},
+	}
+
+	expected := []string{
+		"error: nonterminal `IDENT` doesn't have any production",
+		"warning: nonterminal `Unused` can't be reached from the initial symbol",
+		"error: nonterminal `Loop` never derives a string of only tokens",
+		"warning: token `NEVER` is declared but never used",
+	}
+
+	issues := g.Validate()
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues but got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if issue.String() != expected[i] {
+			t.Errorf("Issue %d = %s, want %s", i, issue.String(), expected[i])
+		}
+	}
+
+	example := createExampleGrammar()
+	if issues := example.Validate(); len(issues) != 0 {
+		t.Errorf("The example grammar shouldn't have issues but got: %v", issues)
+	}
+}
+
+// The issues of a grammar read from a yal file point to where their symbols are defined.
+func TestValidatePositions(t *testing.T) {
+	g, _, err := parseYalString(t, `%token a b NEVER
+%%
+S: A
+	| b
+	;
+A: a ;
+Loop: Loop a ;
+Unused: b ( a b )* ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"7:1: warning: nonterminal `Loop` can't be reached from the initial symbol",
+		"8:1: warning: nonterminal `Unused` can't be reached from the initial symbol",
+		"8:1: warning: nonterminal `Unused_group1` can't be reached from the initial symbol (generated for `( a b )` on the rule of Unused at line 8)",
+		"8:1: warning: nonterminal `Unused_group1_star` can't be reached from the initial symbol (generated for `( a b )*` on the rule of Unused at line 8)",
+		"7:1: error: nonterminal `Loop` never derives a string of only tokens",
+		"1:12: warning: token `NEVER` is declared but never used",
+	}
+	issues := g.Validate()
+	if len(issues) != len(expected) {
+		t.Fatalf("Expected %d issues but got %d: %v", len(expected), len(issues), issues)
+	}
+	for i, issue := range issues {
+		if !issue.Position.HasValue() {
+			t.Errorf("Issue %d doesn't have a position: %s", i, issue.String())
+			continue
+		}
+		position := issue.Position.GetValue()
+		if got := fmt.Sprintf("%d:%d: %s", position.Line, position.Col, issue.String()); got != expected[i] {
+			t.Errorf("Issue %d = %s, want %s", i, got, expected[i])
+		}
+	}
+
+	// The nonterminals created by the transforms are on the rules they come from
+	transformed := g.EliminateLeftRecursion()
+	loopTail := NewNonTerminalToken("Loop_tail")
+	if position := transformed.Grammar.Positions[loopTail]; position != g.Positions[NewNonTerminalToken("Loop")] {
+		t.Errorf("Expected %s on the rule of Loop but got %v", loopTail.Symbol(), position)
+	}
+}
+
+func TestParseYalFileErrorToken(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUMBER SEMI
+%%
+stmt: NUMBER SEMI
+	| error SEMI
+	;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errorToken := NewErrorToken()
+	if !g.Terminals.Contains(errorToken) {
+		t.Fatalf("The error token should be a terminal without declaring it: %v", g.Terminals)
+	}
+	if _, found := g.TokenIds[errorToken]; !found {
+		t.Fatalf("The error token doesn't have an id: %v", g.TokenIds)
+	}
+	if !g.Rules[1].Production[0].Equal(&errorToken) {
+		t.Fatalf("Expected the error token on the second rule but got %s", g.Rules[1].ToString())
+	}
+
+	if issues := g.Validate(); len(issues) > 0 {
+		t.Fatalf("Expected no issues but got %v", issues)
+	}
+}
@@ -0,0 +1,71 @@
+package grammar
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// Converts the automata to a graph where every state shows its items with their lookaheads.
+//
+// The states keep their ids, so they're the same ones the conflicts talk about,
+// and the states with conflicts say so on their title.
+func (auto *Automata) ToGraph(name string, conflicts []Conflict) lib.Graph {
+	graph := lib.Graph{Name: name, Shape: "box"}
+
+	ids := auto.sortedStateIds()
+
+	conflicts = slices.Clone(conflicts)
+	SortConflicts(conflicts)
+	conflictsByState := make(map[AutomataStateIndex][]Conflict)
+	for _, conflict := range conflicts {
+		conflictsByState[conflict.State] = append(conflictsByState[conflict.State], conflict)
+	}
+
+	// The augmented rule is always the first item of the initial state
+	augmentedHead := lib.CreateNull[GrammarToken]()
+	if initial, found := auto.Nodes[auto.InitialState]; found && len(initial.Items) > 0 {
+		augmentedHead = lib.CreateValue(initial.Items[0].Head)
+	}
+
+	for _, id := range ids {
+		node := lib.GraphNode{
+			Id:      id,
+			Title:   "State " + id,
+			Initial: id == auto.InitialState,
+		}
+		for _, conflict := range conflictsByState[id] {
+			node.Title += fmt.Sprintf(", %s conflict on %s", conflict.Type.String(), conflict.Lookahead.Symbol())
+		}
+
+		for _, item := range auto.Nodes[id].Items {
+			node.Lines = append(node.Lines, item.String())
+			if item.DotIsAtEnd() && augmentedHead.HasValue() && item.Head == augmentedHead.GetValue() {
+				node.Accepting = true
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+
+		inputs := make([]AlphabetInput, 0, len(auto.Transitions[id]))
+		for input := range auto.Transitions[id] {
+			inputs = append(inputs, input)
+		}
+		// Terminals go first
+		slices.SortFunc(inputs, func(a, b AlphabetInput) int {
+			if a.IsNonTerminal() != b.IsNonTerminal() {
+				if a.IsNonTerminal() {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(a.Symbol(), b.Symbol())
+		})
+		for _, input := range inputs {
+			graph.Edges = append(graph.Edges, lib.GraphEdge{From: id, To: auto.Transitions[id][input], Label: input.Symbol()})
+		}
+	}
+
+	return graph
+}
@@ -0,0 +1,19 @@
+package grammar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes the contents as a .yal file and parses it, returning the path of the file for the diagnostics.
+func parseYalString(t *testing.T, contents string) (Grammar, string, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "grammar.yal")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := ParseYalFile(path)
+	return g, path, err
+}
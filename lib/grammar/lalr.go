@@ -50,24 +50,53 @@ NodeLoop:
 	return finalIdx, foundSomething
 }
 
+// The ids of the states, they're numbers most of the time so shorter ones go first.
+func (auto *Automata) sortedStateIds() []AutomataStateIndex {
+	ids := make([]AutomataStateIndex, 0, len(auto.Nodes))
+	for id := range auto.Nodes {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b AutomataStateIndex) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a, b)
+	})
+
+	return ids
+}
+
 type AutomataState struct {
 	Items []AutomataItem
 }
 
+// Checks if both states have the same cores, that is the same items without taking into account their lookaheads.
+//
+// The items don't need to be in the same order,
+// and a state may have many items with the same core but different lookaheads.
 func (state *AutomataState) EQ_WithoutLookAhead(other *AutomataState) bool {
-	if len(state.Items) != len(other.Items) {
-		return false
-	}
+	return state.coresContainedIn(other) && other.coresContainedIn(state)
+}
 
-	for i, r := range state.Items {
-		other_r := other.Items[i]
-		if !r.EqualsWithoutLookahead(&other_r) {
+func (state *AutomataState) coresContainedIn(other *AutomataState) bool {
+	for _, r := range state.Items {
+		if !other.hasItemWithoutLookahead(&r) {
 			return false
 		}
 	}
 	return true
 }
 
+func (state *AutomataState) hasItemWithoutLookahead(item *AutomataItem) bool {
+	for _, r := range state.Items {
+		if r.EqualsWithoutLookahead(item) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type AutomataItem struct {
 	Head       GrammarToken
 	Production []GrammarToken
@@ -84,7 +113,7 @@ func (rule *AutomataItem) EqualsWithoutLookahead(other *AutomataItem) bool {
 		return false
 	}
 
-	if !rule.Head.Equal(&other.Head) || rule.Dot != rule.Dot {
+	if !rule.Head.Equal(&other.Head) || rule.Dot != other.Dot {
 		return false
 	}
 
@@ -106,8 +135,8 @@ func (rule *AutomataItem) Equals(other *AutomataItem) bool {
 		return false
 	}
 
-	for i := range other.Lookahead {
-		rule.Lookahead.Add(i)
+	if !rule.Lookahead.Equals(&other.Lookahead) {
+		return false
 	}
 
 	for i, prod := range rule.Production {
@@ -216,6 +245,58 @@ func (item *AutomataItem) ToUniqueString() string {
 	return b.String()
 }
 
+// Displays the item the way it's usually written on books:
+// A -> α • β [lookaheads]
+func (item AutomataItem) String() string {
+	b := strings.Builder{}
+
+	b.WriteString(item.Head.Symbol())
+	b.WriteString(" ->")
+	for i, prod := range item.Production {
+		if i == item.Dot {
+			b.WriteString(" •")
+		}
+		b.WriteString(" ")
+		b.WriteString(prod.Symbol())
+	}
+	if item.DotIsAtEnd() {
+		b.WriteString(" •")
+	}
+
+	lkSlices := make([]string, 0, len(item.Lookahead))
+	for lk := range item.Lookahead {
+		lkSlices = append(lkSlices, lk.Symbol())
+	}
+	slices.Sort(lkSlices)
+
+	b.WriteString(" [")
+	b.WriteString(strings.Join(lkSlices, " "))
+	b.WriteString("]")
+
+	return b.String()
+}
+
+// The lookaheads of the items the closure adds for the symbol after the dot of item.
+//
+// For an item [A → α • X β, c] they're the first of β c,
+// so the lookaheads of the item are only used when β is nullable.
+func closureLookahead(item *AutomataItem, firsts *FirstFollowTable) lib.Set[GrammarToken] {
+	lookAhead := lib.NewSet[GrammarToken]()
+	if item.Dot >= len(item.Production) {
+		return lookAhead
+	}
+
+	for terminal := range firsts.FirstOfSequence(item.Production[item.Dot+1:]) {
+		if IsEpsilon(terminal) {
+			lookAhead.Merge(&item.Lookahead)
+		} else {
+			lookAhead.Add(terminal)
+		}
+	}
+
+	return lookAhead
+}
+
 func closure(
 	state *AutomataState,
 	grammar *Grammar,
@@ -250,19 +331,11 @@ func closure(
 					continue
 				}
 
-				lookAhead := lib.NewSet[GrammarToken]()
-				if item.Dot+1 < len(item.Production) {
-					merge := firsts.table[item.Production[item.Dot+1]]
-					lookAhead.Merge(&merge.First)
-				} else {
-					lookAhead.Merge(&item.Lookahead)
-				}
-
 				newItem := AutomataItem{
 					Head:       dotToken,
 					Production: prod.Production,
 					Dot:        0,
-					Lookahead:  lookAhead,
+					Lookahead:  closureLookahead(&item, firsts),
 				}
 
 				if alreadyComputedItems.Add(newItem.ToUniqueString()) {
@@ -491,8 +564,11 @@ outerLoop:
 				simplifiedSomething = true
 				rules := state.Items
 				for i := range rules {
-					other_i := other.Items[i]
-					rules[i].Lookahead.Merge(&other_i.Lookahead)
+					for _, other_r := range other.Items {
+						if rules[i].EqualsWithoutLookahead(&other_r) {
+							rules[i].Lookahead.Merge(&other_r.Lookahead)
+						}
+					}
 				}
 				newState := AutomataState{
 					Items: rules,
@@ -515,6 +591,8 @@ outerLoop:
 					matchedInputState := false
 					for input, outState := range auto.Transitions[inputState] {
 						if outState == i || outState == j {
+							// Loops on the merged states must loop on the new one too
+							outState = newStateId
 							auto.Transitions[inputState][input] = newStateId
 						}
 
@@ -539,13 +617,47 @@ outerLoop:
 	}
 }
 
-func (auto *Automata) GenerateParsingTable(grammar *Grammar) ParsingTable {
+// Generates the parsing table of the automata.
+//
+// Rules are reduced on the lookaheads of their items,
+// so the table is LR(1) or LALR(1) depending on whether SimplifyStates was called before
+// or the automata was built with InitializeLALRAutomata.
+//
+// Every cell of the action table that more than one action wants to claim is
+// resolved the same way yacc does so the table is always usable:
+// shift/reduce conflicts use the declared precedences and fall back to shifting,
+// between reduces the rule defined first on the grammar wins.
+// Conflicts that the precedences couldn't resolve are reported.
+func (auto *Automata) GenerateParsingTable(grammar *Grammar) (ParsingTable, []Conflict) {
+	return auto.generateParsingTable(grammar, func(item *AutomataItem) lib.Set[GrammarToken] {
+		return item.Lookahead
+	})
+}
+
+// Generates an SLR(1) parsing table of the automata.
+//
+// Rules are reduced on the follows of their head instead of the lookaheads of their items,
+// the automata must be simplified first so its states are the same as the LR(0) ones.
+// Conflicts are resolved just like GenerateParsingTable does.
+func (auto *Automata) GenerateSLRParsingTable(grammar *Grammar) (ParsingTable, []Conflict) {
+	follows := NewFirstFollowTable()
+	GetFollows(grammar, &follows)
+
+	return auto.generateParsingTable(grammar, func(item *AutomataItem) lib.Set[GrammarToken] {
+		return follows.table[item.Head].Follow
+	})
+}
+
+// Generates a parsing table reducing every completed item on the terminals given by reduceLookaheads.
+func (auto *Automata) generateParsingTable(grammar *Grammar, reduceLookaheads func(item *AutomataItem) lib.Set[GrammarToken]) (ParsingTable, []Conflict) {
 	table := ParsingTable{
-		ActionTable:   make(map[AFDNodeId]map[GrammarToken]Action),
-		GoToTable:     make(map[AFDNodeId]map[GrammarToken]AFDNodeId),
-		Original:      *grammar,
-		InitialNodeId: auto.InitialState,
+		ActionTable:    make(map[AFDNodeId]map[GrammarToken]Action),
+		GoToTable:      make(map[AFDNodeId]map[GrammarToken]AFDNodeId),
+		Original:       *grammar,
+		InitialNodeId:  auto.InitialState,
+		ExplicitErrors: make(map[AFDNodeId]lib.Set[GrammarToken]),
 	}
+	conflicts := []Conflict{}
 
 	initialDefaultToken := NewNonTerminalToken("S'")
 	for nodeId, state := range auto.Nodes {
@@ -556,16 +668,23 @@ func (auto *Automata) GenerateParsingTable(grammar *Grammar) ParsingTable {
 			table.GoToTable[nodeId] = make(map[GrammarToken]AFDNodeId)
 		}
 
+		// All the actions that want to be on a cell of the action table
+		candidates := make(map[GrammarToken][]ConflictingAction)
+
 		for input, outNodeId := range auto.Transitions[nodeId] {
 			if input.IsNonTerminal() {
 				table.GoToTable[nodeId][input] = outNodeId
 			} else {
-				table.ActionTable[nodeId][input] = NewShiftAction(outNodeId)
+				candidates[input] = append(candidates[input], ConflictingAction{
+					Action: NewShiftAction(outNodeId),
+					Items:  state.itemsWithDotBefore(input),
+				})
 			}
 		}
 
 		for _, rule := range state.Items {
-			if len(rule.Lookahead) <= 0 {
+			lookaheads := reduceLookaheads(&rule)
+			if len(lookaheads) <= 0 {
 				continue
 			}
 
@@ -579,10 +698,30 @@ func (auto *Automata) GenerateParsingTable(grammar *Grammar) ParsingTable {
 
 			ruleId := grammar.FindIndexOfRule(&rule)
 			if ruleId == -1 {
-				panic(fmt.Sprintf("Failed to find rule: %#v\non grammar %s", rule, grammar))
+				panic(fmt.Sprintf("Failed to find rule: %#v\non grammar %v", rule, grammar))
 			}
-			for input := range rule.Lookahead {
-				table.ActionTable[nodeId][input] = NewReduceAction(ruleId)
+			for input := range lookaheads {
+				candidates[input] = appendReduceCandidate(candidates[input], ruleId, rule)
+			}
+		}
+
+		for input, actions := range candidates {
+			chosen, unresolved := resolveConflict(grammar, input, actions)
+			chosenAction := Action{}
+			if chosen.HasValue() {
+				chosenAction = chosen.GetValue()
+				table.ActionTable[nodeId][input] = chosenAction
+			} else {
+				errors, found := table.ExplicitErrors[nodeId]
+				if !found {
+					errors = lib.NewSet[GrammarToken]()
+					table.ExplicitErrors[nodeId] = errors
+				}
+				errors.Add(input)
+			}
+
+			if unresolved {
+				conflicts = append(conflicts, NewConflict(nodeId, input, actions, chosenAction))
 			}
 		}
 	}
@@ -594,7 +733,8 @@ func (auto *Automata) GenerateParsingTable(grammar *Grammar) ParsingTable {
 	}
 	table.ActionTable[acceptNodeId][NewEndToken()] = NewAcceptAction()
 
-	return table
+	SortConflicts(conflicts)
+	return table, conflicts
 }
 
 // func getTransitions(state AutomataState, grammar Grammar) map[string][]AutomataItem {
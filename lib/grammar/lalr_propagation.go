@@ -0,0 +1,319 @@
+package grammar
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// An item without lookaheads, the rule is an index of lalrBuilder.rules
+type lr0Item struct {
+	rule int
+	dot  int
+}
+
+// The lookahead used to find which lookaheads propagate from a kernel item to the items it generates,
+// it isn't a terminal, a nonterminal nor the end token so it can't be on any grammar.
+var propagationMarker = GrammarToken{}
+
+type lalrState struct {
+	// Sorted, so the same kernel is always written the same way
+	kernel []lr0Item
+	// The position of every kernel item on kernel
+	kernelIndex map[lr0Item]int
+	// The symbols the state has transitions on, in the order they're found on the closure
+	symbols     []GrammarToken
+	transitions map[GrammarToken]int
+}
+
+type lalrBuilder struct {
+	grammar *Grammar
+	firsts  *FirstFollowTable
+	// The augmented initial rule followed by the rules of the grammar
+	rules       []GrammarRule
+	rulesByHead map[GrammarToken][]int
+
+	states []lalrState
+	// The index of every state by the key of its kernel
+	statesByKernel map[string]int
+}
+
+// Builds the LALR(1) automata of the grammar without building the LR(1) one first.
+//
+// The states are the ones of the LR(0) automata, found by the key of their kernel.
+// The lookaheads of the kernel items are computed like the dragon book does:
+// the closure of every kernel item with a marker lookahead shows which lookaheads are generated spontaneously
+// and to which kernel items the lookaheads propagate, then they're propagated until nothing changes.
+//
+// The automata has the same states and lookaheads as calling SimplifyStates on the one built by InitializeAutomata.
+func InitializeLALRAutomata(initialRule GrammarRule, grammar Grammar) Automata {
+	firsts := NewFirstFollowTable()
+	GetFirsts(&grammar, &firsts)
+
+	builder := lalrBuilder{
+		grammar:        &grammar,
+		firsts:         &firsts,
+		rules:          append([]GrammarRule{initialRule}, grammar.Rules...),
+		rulesByHead:    make(map[GrammarToken][]int),
+		statesByKernel: make(map[string]int),
+	}
+	for idx, rule := range builder.rules {
+		builder.rulesByHead[rule.Head] = append(builder.rulesByHead[rule.Head], idx)
+	}
+
+	builder.buildLR0States()
+	lookaheads := builder.computeLookaheads()
+	return builder.toAutomata(lookaheads)
+}
+
+func kernelKey(kernel []lr0Item) string {
+	b := strings.Builder{}
+	for _, item := range kernel {
+		b.WriteString(strconv.Itoa(item.rule))
+		b.WriteRune('.')
+		b.WriteString(strconv.Itoa(item.dot))
+		b.WriteRune(',')
+	}
+
+	return b.String()
+}
+
+func (self *lalrBuilder) symbolAfterDot(item lr0Item) (GrammarToken, bool) {
+	production := self.rules[item.rule].Production
+	if item.dot >= len(production) {
+		return GrammarToken{}, false
+	}
+
+	return production[item.dot], true
+}
+
+// Returns the index of the state with the kernel, adding it if it's new.
+func (self *lalrBuilder) addState(kernel []lr0Item) int {
+	slices.SortFunc(kernel, func(a, b lr0Item) int {
+		if a.rule != b.rule {
+			return a.rule - b.rule
+		}
+		return a.dot - b.dot
+	})
+
+	key := kernelKey(kernel)
+	if idx, found := self.statesByKernel[key]; found {
+		return idx
+	}
+
+	state := lalrState{
+		kernel:      kernel,
+		kernelIndex: make(map[lr0Item]int),
+		transitions: make(map[GrammarToken]int),
+	}
+	for i, item := range kernel {
+		state.kernelIndex[item] = i
+	}
+
+	idx := len(self.states)
+	self.states = append(self.states, state)
+	self.statesByKernel[key] = idx
+	return idx
+}
+
+// The LR(0) closure of the kernel, the kernel items go first.
+func (self *lalrBuilder) lr0Closure(kernel []lr0Item) []lr0Item {
+	items := slices.Clone(kernel)
+	added := lib.NewSet[lr0Item]()
+	for _, item := range kernel {
+		added.Add(item)
+	}
+	expanded := lib.NewSet[GrammarToken]()
+
+	for i := 0; i < len(items); i++ {
+		symbol, found := self.symbolAfterDot(items[i])
+		if !found || !symbol.IsNonTerminal() || !expanded.Add(symbol) {
+			continue
+		}
+
+		for _, rule := range self.rulesByHead[symbol] {
+			item := lr0Item{rule: rule}
+			if added.Add(item) {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+func (self *lalrBuilder) buildLR0States() {
+	self.addState([]lr0Item{{rule: 0, dot: 0}})
+
+	for idx := 0; idx < len(self.states); idx++ {
+		kernels := make(map[GrammarToken][]lr0Item)
+		symbols := []GrammarToken{}
+		for _, item := range self.lr0Closure(self.states[idx].kernel) {
+			symbol, found := self.symbolAfterDot(item)
+			if !found {
+				continue
+			}
+
+			if _, seen := kernels[symbol]; !seen {
+				symbols = append(symbols, symbol)
+			}
+			kernels[symbol] = append(kernels[symbol], lr0Item{rule: item.rule, dot: item.dot + 1})
+		}
+
+		for _, symbol := range symbols {
+			next := self.addState(kernels[symbol])
+			self.states[idx].transitions[symbol] = next
+		}
+		self.states[idx].symbols = symbols
+	}
+}
+
+// The LR(1) closure of the kernel items with their lookaheads.
+//
+// Items with the same core are kept once with all their lookaheads,
+// the order of the items is the order they were found.
+func (self *lalrBuilder) lr1Closure(kernel []lr0Item, kernelLookaheads []lib.Set[GrammarToken]) ([]lr0Item, map[lr0Item]lib.Set[GrammarToken]) {
+	items := slices.Clone(kernel)
+	lookaheads := make(map[lr0Item]lib.Set[GrammarToken])
+	for i, item := range kernel {
+		lookaheads[item] = kernelLookaheads[i].Copy()
+	}
+
+	pending := slices.Clone(kernel)
+	for len(pending) > 0 {
+		item := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		symbol, found := self.symbolAfterDot(item)
+		if !found || !symbol.IsNonTerminal() {
+			continue
+		}
+
+		rule := self.rules[item.rule]
+		generated := closureLookahead(&AutomataItem{Head: rule.Head, Production: rule.Production, Dot: item.dot, Lookahead: lookaheads[item]}, self.firsts)
+		for _, ruleIdx := range self.rulesByHead[symbol] {
+			newItem := lr0Item{rule: ruleIdx}
+			current, found := lookaheads[newItem]
+			if !found {
+				current = lib.NewSet[GrammarToken]()
+				lookaheads[newItem] = current
+				items = append(items, newItem)
+			}
+
+			changed := false
+			for lookahead := range generated {
+				changed = current.Add(lookahead) || changed
+			}
+			if changed || !found {
+				pending = append(pending, newItem)
+			}
+		}
+	}
+
+	return items, lookaheads
+}
+
+// A kernel item of a state
+type kernelRef struct {
+	state int
+	item  int
+}
+
+// Computes the lookaheads of every kernel item, indexed by state and then by the position on the kernel.
+func (self *lalrBuilder) computeLookaheads() [][]lib.Set[GrammarToken] {
+	lookaheads := make([][]lib.Set[GrammarToken], len(self.states))
+	for idx, state := range self.states {
+		lookaheads[idx] = make([]lib.Set[GrammarToken], len(state.kernel))
+		for i := range state.kernel {
+			lookaheads[idx][i] = lib.NewSet[GrammarToken]()
+		}
+	}
+	lookaheads[0][0].Add(NewEndToken())
+
+	propagations := make(map[kernelRef][]kernelRef)
+	marker := lib.NewSet[GrammarToken]()
+	marker.Add(propagationMarker)
+	for idx, state := range self.states {
+		for i, kernelItem := range state.kernel {
+			from := kernelRef{state: idx, item: i}
+			items, itemLookaheads := self.lr1Closure([]lr0Item{kernelItem}, []lib.Set[GrammarToken]{marker})
+
+			for _, item := range items {
+				symbol, found := self.symbolAfterDot(item)
+				if !found {
+					continue
+				}
+
+				next := state.transitions[symbol]
+				to := kernelRef{state: next, item: self.states[next].kernelIndex[lr0Item{rule: item.rule, dot: item.dot + 1}]}
+				for lookahead := range itemLookaheads[item] {
+					if lookahead == propagationMarker {
+						propagations[from] = append(propagations[from], to)
+					} else {
+						lookaheads[to.state][to.item].Add(lookahead)
+					}
+				}
+			}
+		}
+	}
+
+	pending := []kernelRef{}
+	for idx, state := range self.states {
+		for i := range state.kernel {
+			pending = append(pending, kernelRef{state: idx, item: i})
+		}
+	}
+	for len(pending) > 0 {
+		from := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		for _, to := range propagations[from] {
+			changed := false
+			for lookahead := range lookaheads[from.state][from.item] {
+				changed = lookaheads[to.state][to.item].Add(lookahead) || changed
+			}
+			if changed {
+				pending = append(pending, to)
+			}
+		}
+	}
+
+	return lookaheads
+}
+
+func (self *lalrBuilder) toAutomata(lookaheads [][]lib.Set[GrammarToken]) Automata {
+	auto := Automata{
+		InitialState:     "0",
+		Transitions:      make(map[AutomataStateIndex]map[AlphabetInput]AutomataStateIndex),
+		AcceptanceStates: lib.NewSet[AutomataStateIndex](),
+		Nodes:            make(map[AutomataStateIndex]AutomataState),
+	}
+
+	for idx, state := range self.states {
+		id := strconv.Itoa(idx)
+		items, itemLookaheads := self.lr1Closure(state.kernel, lookaheads[idx])
+
+		node := AutomataState{Items: make([]AutomataItem, 0, len(items))}
+		for _, item := range items {
+			rule := self.rules[item.rule]
+			node.Items = append(node.Items, AutomataItem{
+				Head:       rule.Head,
+				Production: rule.Production,
+				Dot:        item.dot,
+				Lookahead:  itemLookaheads[item],
+			})
+		}
+		auto.Nodes[id] = node
+
+		if len(state.symbols) > 0 {
+			auto.Transitions[id] = make(map[AlphabetInput]AutomataStateIndex)
+		}
+		for _, symbol := range state.symbols {
+			auto.Transitions[id][symbol] = strconv.Itoa(state.transitions[symbol])
+		}
+	}
+
+	return auto
+}
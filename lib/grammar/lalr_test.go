@@ -1,7 +1,12 @@
 package grammar
 
 import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Jose-Prince/UWUCompiler/lib"
 )
@@ -81,38 +86,22 @@ func TestInitializeAutomata(t *testing.T) {
 	auto := InitializeAutomata(extendedRule, grammar)
 
 	// Obtenemos el estado inicial
-	state0, exists := auto.Nodes[0]
+	state0, exists := auto.Nodes[auto.InitialState]
 	if !exists {
 		t.Fatalf("No se encontró el estado inicial")
 	}
 
 	// Verificamos que existan las producciones esperadas en el estado inicial
 	expectedItems := []AutomataItem{
-		{
-			Rule:        extendedRule,
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
-		{
-			Rule:        rules[0], // S → . A
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
-		{
-			Rule:        rules[1], // A → . a A
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
-		{
-			Rule:        rules[2], // A → . b
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
+		newTestItem(extendedRule, 0, NewEndToken()),
+		newTestItem(rules[0], 0, NewEndToken()), // S → . A
+		newTestItem(rules[1], 0, NewEndToken()), // A → . a A
+		newTestItem(rules[2], 0, NewEndToken()), // A → . b
 	}
 
-	for key, expected := range expectedItems {
-		if _, found := state0.Items[key]; !found {
-			t.Errorf("No se encontró el item esperado: %s", expected.Rule.ToString())
+	for _, expected := range expectedItems {
+		if !containsItem(state0.Items, &expected) {
+			t.Errorf("No se encontró el item esperado: %s", expected.String())
 		}
 	}
 }
@@ -166,38 +155,22 @@ func TestInitializeAutomataBrolo(t *testing.T) {
 	auto := InitializeAutomata(extendedRule, grammar)
 
 	// Obtenemos el estado inicial
-	state0, exists := auto.Nodes[0]
+	state0, exists := auto.Nodes[auto.InitialState]
 	if !exists {
 		t.Fatalf("No se encontró el estado inicial")
 	}
 
 	// Verificamos que existan las producciones esperadas en el estado inicial
 	expectedItems := []AutomataItem{
-		{
-			Rule:        extendedRule,
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
-		{
-			Rule:        rules[0], // S → . A
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{NewEndToken()},
-		},
-		{
-			Rule:        rules[1], // A → . a A
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{c, d},
-		},
-		{
-			Rule:        rules[2], // A → . b
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{c, d},
-		},
+		newTestItem(extendedRule, 0, NewEndToken()),
+		newTestItem(rules[0], 0, NewEndToken()), // S → . A
+		newTestItem(rules[1], 0, c, d),          // A → . a A
+		newTestItem(rules[2], 0, c, d),          // A → . b
 	}
 
-	for key, expected := range expectedItems {
-		if _, found := state0.Items[key]; !found {
-			t.Errorf("No se encontró el item esperado: %s", expected.Rule.ToString())
+	for _, expected := range expectedItems {
+		if !containsItem(state0.Items, &expected) {
+			t.Errorf("No se encontró el item esperado: %s", expected.String())
 		}
 	}
 }
@@ -239,69 +212,79 @@ func TestGeneratedStatesFromInitial(t *testing.T) {
 	auto := InitializeAutomata(extended, grammar)
 
 	// Crear estado esperado 1 (después de transición sobre S)
-	expectedItemsS := map[int]AutomataItem{
-		0: {
-			Rule:        rules[0], // A -> a A
-			DotPosition: 1,
-			Lookahead:   []GrammarToken{end},
-		},
+	expectedItemsS := []AutomataItem{
+		newTestItem(rules[0], 1, end), // S' -> S .
 	}
 
-	expectedItemsA := map[int]AutomataItem{
-		0: {
-			Rule:        rules[1], // A -> a A
-			DotPosition: 1,
-			Lookahead:   []GrammarToken{end},
-		},
+	expectedItemsA := []AutomataItem{
+		newTestItem(rules[1], 1, end), // S -> A .
 	}
 
-	expectedItemsa := map[int]AutomataItem{
-		0: {
-			Rule:        rules[2], // A -> a A
-			DotPosition: 1,
-			Lookahead:   []GrammarToken{end},
-		},
-		1: {
-			Rule:        rules[3], // A -> a A
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{end},
-		},
+	expectedItemsa := []AutomataItem{
+		newTestItem(rules[2], 1, end), // A -> a . A
+		newTestItem(rules[2], 0, end), // A -> . a A
+		newTestItem(rules[3], 0, end), // A -> . b
 	}
 
-	expectedItemsb := map[int]AutomataItem{
-		0: {
-			Rule:        rules[3], // A -> a A
-			DotPosition: 1,
-			Lookahead:   []GrammarToken{end},
-		},
+	expectedItemsb := []AutomataItem{
+		newTestItem(rules[3], 1, end), // A -> b .
 	}
 
 	// Verificar si el estado generado contiene exactamente esos ítems
-	initial := auto.Nodes[0]
-	targetStateID, ok := initial.Productions[S.String()]
-	if !ok {
-		t.Fatalf("No se encontró transición sobre símbolo %s", S.String())
+	transitions := auto.Transitions[auto.InitialState]
+	expectedStates := []struct {
+		input GrammarToken
+		items []AutomataItem
+	}{
+		{S, expectedItemsS},
+		{A, expectedItemsA},
+		{a, expectedItemsa},
+		{b, expectedItemsb},
 	}
 
-	targetState := auto.Nodes[targetStateID]
-	if !compareStateItems(targetState.Items, expectedItemsS) {
-		t.Errorf("Los ítems del estado %d no coinciden con los esperados", targetStateID)
+	for _, expected := range expectedStates {
+		targetStateID, ok := transitions[expected.input]
+		if !ok {
+			t.Fatalf("No se encontró transición sobre símbolo %s", expected.input.String())
+		}
+
+		targetState := auto.Nodes[targetStateID]
+		if !compareStateItems(targetState.Items, expected.items) {
+			t.Errorf("Los ítems del estado %s no coinciden con los esperados", targetStateID)
+		}
 	}
-	targetState = auto.Nodes[targetStateID+1]
-	if !compareStateItems(targetState.Items, expectedItemsA) {
-		t.Errorf("Los ítems del estado %d no coinciden con los esperados", targetStateID)
+}
+
+func newTestItem(rule GrammarRule, dot int, lookahead ...GrammarToken) AutomataItem {
+	lk := lib.NewSet[GrammarToken]()
+	for _, tk := range lookahead {
+		lk.Add(tk)
 	}
-	targetState = auto.Nodes[targetStateID+2]
-	if !compareStateItems(targetState.Items, expectedItemsa) {
-		t.Errorf("Los ítems del estado %d no coinciden con los esperados", targetStateID)
+
+	return AutomataItem{
+		Head:       rule.Head,
+		Production: rule.Production,
+		Dot:        dot,
+		Lookahead:  lk,
 	}
-	targetState = auto.Nodes[targetStateID+3]
-	if !compareStateItems(targetState.Items, expectedItemsb) {
-		t.Errorf("Los ítems del estado %d no coinciden con los esperados", targetStateID)
+}
+
+func sameItemCore(a, b *AutomataItem) bool {
+	rA := GrammarRule{Head: a.Head, Production: a.Production}
+	rB := GrammarRule{Head: b.Head, Production: b.Production}
+	return rA.EqualRule(&rB) && a.Dot == b.Dot
+}
+
+func containsItem(items []AutomataItem, expected *AutomataItem) bool {
+	for _, item := range items {
+		if sameItemCore(&item, expected) && item.Lookahead.Equals(&expected.Lookahead) {
+			return true
+		}
 	}
+	return false
 }
 
-func compareStateItems(actual, expected map[int]AutomataItem) bool {
+func compareStateItems(actual, expected []AutomataItem) bool {
 	if len(actual) != len(expected) {
 		return false
 	}
@@ -309,8 +292,7 @@ func compareStateItems(actual, expected map[int]AutomataItem) bool {
 	for _, e := range expected {
 		found := false
 		for _, a := range actual {
-			if a.Rule.EqualRule(&e.Rule) &&
-				a.DotPosition == e.DotPosition {
+			if sameItemCore(&a, &e) {
 				found = true
 				break
 			}
@@ -354,78 +336,31 @@ func TestClosure(t *testing.T) {
 	grammar.NonTerminals.Add(T)
 
 	// Item inicial: E → .E + T, $
-	initialItem := AutomataItem{
-		Rule:        GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{E}},
-		DotPosition: 0,
-		Lookahead:   []GrammarToken{end},
-	}
-
-	initialItems := map[int]AutomataItem{
-		0: initialItem,
-	}
+	initialItem := newTestItem(GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{E}}, 0, end)
 
 	instialState := AutomataState{
-		Items:       initialItems,
-		Productions: make(map[string]int),
+		Items: []AutomataItem{initialItem},
 	}
 
 	// Ejecutar closure
-	closure(instialState, grammar)
+	firsts := NewFirstFollowTable()
+	GetFirsts(&grammar, &firsts)
+	closure(&instialState, &grammar, &firsts)
 
 	// Esperamos ver los siguientes ítems:
 	expected := []AutomataItem{
 		initialItem,
-		{
-			Rule:        rules[0], // E → . E + T
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{plus},
-		},
-		{
-			Rule:        rules[1], // E → . T
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{plus},
-		},
-		{
-			Rule:        rules[2], // T → . id
-			DotPosition: 0,
-			Lookahead:   []GrammarToken{plus},
-		},
+		newTestItem(rules[0], 0, plus), // E → . E + T
+		newTestItem(rules[1], 0, plus), // E → . T
+		newTestItem(rules[2], 0, plus), // T → . id
 	}
 
 	// Validación
 	for _, exp := range expected {
-		found := false
-		for _, item := range instialState.Items {
-			if item.Rule.EqualRule(&exp.Rule) &&
-				item.DotPosition == exp.DotPosition &&
-				lookaheadsEqual(item.Lookahead, exp.Lookahead) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			t.Errorf("No se encontró el item esperado: %v", exp.Rule.ToString())
-		}
-	}
-}
-
-func lookaheadsEqual(a, b []GrammarToken) bool {
-	if a[0].IsEnd && b[0].IsEnd {
-		return true
-	}
-	if len(a) != len(b) {
-		return false
-	}
-	m := make(map[string]bool)
-	for _, tok := range a {
-		m[tok.String()] = true
-	}
-	for _, tok := range b {
-		if !m[tok.String()] {
-			return false
+		if !containsItem(instialState.Items, &exp) {
+			t.Errorf("No se encontró el item esperado: %v", exp.String())
 		}
 	}
-	return true
 }
 
 func TestConvertToLALR(t *testing.T) {
@@ -517,7 +452,7 @@ func TestGenerateParsingTable(t *testing.T) {
 	lalr := lr1
 	lalr.SimplifyStates()
 
-	parsingTable := lalr.GenerateParsingTable(&g)
+	parsingTable, _ := lalr.GenerateParsingTable(&g)
 
 	foundShift := false
 	foundReduce := false
@@ -549,3 +484,798 @@ func TestGenerateParsingTable(t *testing.T) {
 		t.Error("No se encontró ninguna acción Reduce en la tabla de parseo")
 	}
 }
+
+func newGrammarFromRules(initial GrammarToken, rules []GrammarRule, terminals []GrammarToken) Grammar {
+	g := Grammar{
+		InitialSimbol: initial,
+		Rules:         rules,
+		Terminals:     lib.NewSet[GrammarToken](),
+		NonTerminals:  lib.NewSet[GrammarToken](),
+	}
+	for _, t := range terminals {
+		g.Terminals.Add(t)
+	}
+	for _, r := range rules {
+		g.NonTerminals.Add(r.Head)
+	}
+
+	return g
+}
+
+// Builds the LR(1) automata of the grammar, merges it into the LALR(1) one and generates its parsing table.
+func buildAutomataAndTable(g *Grammar) (Automata, ParsingTable, []Conflict) {
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+	lalr := InitializeAutomata(initialRule, *g)
+	lalr.SimplifyStates()
+	table, conflicts := lalr.GenerateParsingTable(g)
+	return lalr, table, conflicts
+}
+
+func TestGenerateParsingTableWithoutConflicts(t *testing.T) {
+	S := NewNonTerminalToken("S")
+	C := NewNonTerminalToken("C")
+	c := NewTerminalToken("c")
+	d := NewTerminalToken("d")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{C, C}},
+		{Head: C, Production: []GrammarToken{c, C}},
+		{Head: C, Production: []GrammarToken{d}},
+	}, []GrammarToken{c, d})
+	_, _, conflicts := buildAutomataAndTable(&g)
+
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts but found %d:\n%v", len(conflicts), conflicts)
+	}
+}
+
+func TestGenerateParsingTableShiftReduceConflict(t *testing.T) {
+	// E → E + E | id
+	E := NewNonTerminalToken("E")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+
+	g := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, E}},
+		{Head: E, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, id})
+	_, table, conflicts := buildAutomataAndTable(&g)
+
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected exactly one conflict but found %d:\n%v", len(conflicts), conflicts)
+	}
+
+	conflict := conflicts[0]
+	if conflict.Type != SHIFT_REDUCE {
+		t.Errorf("Expected a shift/reduce conflict but got %s", conflict.Type.String())
+	}
+	if !conflict.Lookahead.Equal(&plus) {
+		t.Errorf("Expected the conflict to be on `+` but got %s", conflict.Lookahead.String())
+	}
+	if len(conflict.Actions) != 2 {
+		t.Fatalf("Expected two competing actions but got %d", len(conflict.Actions))
+	}
+	for _, a := range conflict.Actions {
+		if len(a.Items) == 0 {
+			t.Errorf("The action %s doesn't have the items that caused it", a.Action.String())
+		}
+	}
+
+	// Shift should win, just like yacc
+	if !conflict.Chosen.Shift.HasValue() {
+		t.Errorf("Expected shift to be chosen but got %s", conflict.Chosen.String())
+	}
+	if cell := table.ActionTable[conflict.State][plus]; !cell.Shift.HasValue() {
+		t.Errorf("Expected the table to have the shift but got %s", cell.String())
+	}
+}
+
+func TestGenerateParsingTableReduceReduceConflict(t *testing.T) {
+	// S → A | B
+	// A → x
+	// B → x
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	B := NewNonTerminalToken("B")
+	x := NewTerminalToken("x")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{A}},
+		{Head: S, Production: []GrammarToken{B}},
+		{Head: A, Production: []GrammarToken{x}},
+		{Head: B, Production: []GrammarToken{x}},
+	}, []GrammarToken{x})
+	_, table, conflicts := buildAutomataAndTable(&g)
+
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected exactly one conflict but found %d:\n%v", len(conflicts), conflicts)
+	}
+
+	conflict := conflicts[0]
+	if conflict.Type != REDUCE_REDUCE {
+		t.Errorf("Expected a reduce/reduce conflict but got %s", conflict.Type.String())
+	}
+	if !conflict.Lookahead.IsEnd {
+		t.Errorf("Expected the conflict to be on `$` but got %s", conflict.Lookahead.String())
+	}
+
+	// The first rule defined should win
+	if !conflict.Chosen.Reduce.HasValue() || conflict.Chosen.Reduce.GetValue() != 2 {
+		t.Errorf("Expected reduce(2) to be chosen but got %s", conflict.Chosen.String())
+	}
+	if cell := table.ActionTable[conflict.State][NewEndToken()]; cell.String() != conflict.Chosen.String() {
+		t.Errorf("Expected the table to have %s but got %s", conflict.Chosen.String(), cell.String())
+	}
+}
+
+// Finds the state that contains the completed item of the rule
+func findStateWithCompletedRule(auto *Automata, rule GrammarRule) (AutomataStateIndex, bool) {
+	completed := AutomataItem{Head: rule.Head, Production: rule.Production, Dot: len(rule.Production)}
+	for idx, state := range auto.Nodes {
+		for _, item := range state.Items {
+			if sameItemCore(&item, &completed) {
+				return idx, true
+			}
+		}
+	}
+	return "", false
+}
+
+func TestGenerateParsingTableResolvesWithPrecedence(t *testing.T) {
+	g, err := ParseYalFile("../../example/precedence/grammar.yal")
+	if err != nil {
+		t.Fatalf("Failed to parse grammar: %s", err)
+	}
+
+	auto, table, conflicts := buildAutomataAndTable(&g)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected precedences to resolve all conflicts but found %d:\n%v", len(conflicts), conflicts)
+	}
+
+	expr := NewNonTerminalToken("expr")
+	plus := NewTerminalToken("PLUS")
+	mult := NewTerminalToken("MULT")
+	minus := NewTerminalToken("MINUS")
+
+	// expr PLUS expr •
+	state, found := findStateWithCompletedRule(&auto, GrammarRule{Head: expr, Production: []GrammarToken{expr, plus, expr}})
+	if !found {
+		t.Fatalf("State for `expr PLUS expr •` not found!")
+	}
+	if action := table.ActionTable[state][mult]; !action.Shift.HasValue() {
+		t.Errorf("MULT binds tighter than PLUS, expected a shift but got %s", action.String())
+	}
+	if action := table.ActionTable[state][plus]; !action.Reduce.HasValue() {
+		t.Errorf("PLUS is left associative, expected a reduce but got %s", action.String())
+	}
+
+	// MINUS expr • %prec UMINUS
+	state, found = findStateWithCompletedRule(&auto, GrammarRule{Head: expr, Production: []GrammarToken{minus, expr}})
+	if !found {
+		t.Fatalf("State for `MINUS expr •` not found!")
+	}
+	if action := table.ActionTable[state][mult]; !action.Reduce.HasValue() {
+		t.Errorf("UMINUS binds tighter than MULT, expected a reduce but got %s", action.String())
+	}
+}
+
+func TestGenerateParsingTableNonAssociative(t *testing.T) {
+	// E → E < E | id
+	// %nonassoc <
+	E := NewNonTerminalToken("E")
+	less := NewTerminalToken("<")
+	id := NewTerminalToken("id")
+
+	g := Grammar{
+		InitialSimbol: E,
+		Rules: []GrammarRule{
+			{Head: E, Production: []GrammarToken{E, less, E}},
+			{Head: E, Production: []GrammarToken{id}},
+		},
+		Terminals:    lib.Set[GrammarToken]{less: struct{}{}, id: struct{}{}},
+		NonTerminals: lib.Set[GrammarToken]{E: struct{}{}},
+		Precedences: map[GrammarToken]Precedence{
+			less: {Level: 1, Associativity: NON_ASSOC},
+		},
+	}
+
+	auto, table, conflicts := buildAutomataAndTable(&g)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts but found %d:\n%v", len(conflicts), conflicts)
+	}
+
+	state, found := findStateWithCompletedRule(&auto, g.Rules[0])
+	if !found {
+		t.Fatalf("State for `E < E •` not found!")
+	}
+	if action, found := table.ActionTable[state][less]; found {
+		t.Errorf("`a < b < c` should be a syntax error but the table has %s", action.String())
+	}
+	if errors := table.ExplicitErrors[state]; !errors.Contains(less) {
+		t.Errorf("The error of `a < b < c` should be explicit but got %v", table.ExplicitErrors)
+	}
+}
+
+func TestGenerateSLRParsingTable(t *testing.T) {
+	// S → L = R | R
+	// L → * R | id
+	// R → L
+	// Is LALR(1) but not SLR(1), since `=` is on the follow of R
+	S := NewNonTerminalToken("S")
+	L := NewNonTerminalToken("L")
+	R := NewNonTerminalToken("R")
+	eq := NewTerminalToken("=")
+	star := NewTerminalToken("*")
+	id := NewTerminalToken("id")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{L, eq, R}},
+		{Head: S, Production: []GrammarToken{R}},
+		{Head: L, Production: []GrammarToken{star, R}},
+		{Head: L, Production: []GrammarToken{id}},
+		{Head: R, Production: []GrammarToken{L}},
+	}, []GrammarToken{eq, star, id})
+
+	auto, _, lalrConflicts := buildAutomataAndTable(&g)
+	if len(lalrConflicts) != 0 {
+		t.Fatalf("The LALR table shouldn't have conflicts but found:\n%v", lalrConflicts)
+	}
+
+	slrTable, slrConflicts := auto.GenerateSLRParsingTable(&g)
+	if len(slrConflicts) != 1 {
+		t.Fatalf("Expected exactly one SLR conflict but found %d:\n%v", len(slrConflicts), slrConflicts)
+	}
+
+	conflict := slrConflicts[0]
+	if conflict.Type != SHIFT_REDUCE || !conflict.Lookahead.Equal(&eq) {
+		t.Errorf("Expected a shift/reduce conflict on `=` but got:\n%s", conflict.String())
+	}
+
+	// R → L must be reduced on everything on the follow of R
+	state, found := findStateWithCompletedRule(&auto, GrammarRule{Head: R, Production: []GrammarToken{L}})
+	if !found {
+		t.Fatalf("No state reduces R → L")
+	}
+	for _, lookahead := range []GrammarToken{NewEndToken(), eq} {
+		if _, found := slrTable.ActionTable[state][lookahead]; !found {
+			t.Errorf("The SLR table should have an action for %s on state %s", lookahead.String(), state)
+		}
+	}
+}
+
+func TestCanonicalLR1AvoidsMergeConflicts(t *testing.T) {
+	// S → a A d | b B d | a B e | b A e
+	// A → c
+	// B → c
+	// Is LR(1) but merging the states after `c` creates reduce/reduce conflicts
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	B := NewNonTerminalToken("B")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	c := NewTerminalToken("c")
+	d := NewTerminalToken("d")
+	e := NewTerminalToken("e")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{a, A, d}},
+		{Head: S, Production: []GrammarToken{b, B, d}},
+		{Head: S, Production: []GrammarToken{a, B, e}},
+		{Head: S, Production: []GrammarToken{b, A, e}},
+		{Head: A, Production: []GrammarToken{c}},
+		{Head: B, Production: []GrammarToken{c}},
+	}, []GrammarToken{a, b, c, d, e})
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{S}}
+	lr1 := InitializeAutomata(initialRule, g)
+	_, lr1Conflicts := lr1.GenerateParsingTable(&g)
+	if len(lr1Conflicts) != 0 {
+		t.Fatalf("The LR(1) table shouldn't have conflicts but found:\n%v", lr1Conflicts)
+	}
+
+	_, _, lalrConflicts := buildAutomataAndTable(&g)
+	if len(lalrConflicts) == 0 {
+		t.Fatalf("The LALR table should have reduce/reduce conflicts")
+	}
+
+	for _, conflict := range lalrConflicts {
+		if conflict.Type != REDUCE_REDUCE {
+			t.Errorf("Expected only reduce/reduce conflicts but got:\n%s", conflict.String())
+		}
+	}
+}
+
+// Describes the table with its states numbered the same way on every automata,
+// so tables built from different automatas can be compared.
+func describeRenumberedTable(g *Grammar, table *ParsingTable) string {
+	runtimeTable := table.ToParserTable()
+	numbers := runtimeTable.StateNumbers()
+	lines := []string{}
+	for node, row := range runtimeTable.ActionTable {
+		for token, action := range row {
+			description := "accept"
+			if action.IsShift() {
+				description = fmt.Sprintf("shift %d", numbers[action.GetShift()])
+			} else if action.IsReduce() {
+				description = fmt.Sprintf("reduce %d", action.GetReduce())
+			}
+			lines = append(lines, fmt.Sprintf("%d %s: %s", numbers[node], runtimeTable.TokenToHuman(token), description))
+		}
+	}
+	for node, row := range runtimeTable.GoToTable {
+		for token, to := range row {
+			lines = append(lines, fmt.Sprintf("%d %s: goto %d", numbers[node], runtimeTable.TokenToHuman(token), numbers[to]))
+		}
+	}
+	for node, tokens := range runtimeTable.ExplicitErrors {
+		for token := range tokens {
+			lines = append(lines, fmt.Sprintf("%d %s: error", numbers[node], runtimeTable.TokenToHuman(token)))
+		}
+	}
+
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
+
+// The automata built by propagating the lookaheads must give the same tables as merging the LR(1) states.
+func TestInitializeLALRAutomataMatchesMergedLR1(t *testing.T) {
+	// The LR(1) automata of the go example takes minutes to build, TestInitializeLALRAutomataGoExample checks it instead
+	examples := []string{"calculator", "lalr", "medium", "precedence", "slr"}
+	for _, example := range examples {
+		t.Run(example, func(t *testing.T) {
+			g, err := ParseYalFile(filepath.Join("../../example", example, "grammar.yal"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+
+			start := time.Now()
+			merged := InitializeAutomata(initialRule, g)
+			merged.SimplifyStates()
+			t.Logf("Merging LR(1) states took %v", time.Since(start))
+
+			start = time.Now()
+			propagated := InitializeLALRAutomata(initialRule, g)
+			t.Logf("Propagating lookaheads took %v", time.Since(start))
+
+			if len(merged.Nodes) != len(propagated.Nodes) {
+				t.Fatalf("Expected %d states but got %d", len(merged.Nodes), len(propagated.Nodes))
+			}
+
+			generators := map[string]func(*Automata, *Grammar) (ParsingTable, []Conflict){
+				"lalr": (*Automata).GenerateParsingTable,
+				"slr":  (*Automata).GenerateSLRParsingTable,
+			}
+			for name, generate := range generators {
+				expected, expectedConflicts := generate(&merged, &g)
+				actual, actualConflicts := generate(&propagated, &g)
+
+				if len(expectedConflicts) != len(actualConflicts) {
+					t.Errorf("%s: expected %d conflicts but got %d", name, len(expectedConflicts), len(actualConflicts))
+				}
+				expectedDescription := describeRenumberedTable(&g, &expected)
+				actualDescription := describeRenumberedTable(&g, &actual)
+				if expectedDescription != actualDescription {
+					t.Errorf("%s: the tables are different!\nExpected:\n%s\nBut got:\n%s", name, expectedDescription, actualDescription)
+				}
+			}
+		})
+	}
+}
+
+// An item of the reference LALR(1) automata, the rule indexes count the initial rule as 0.
+type referenceItem struct{ rule, dot int }
+
+// Builds the LALR(1) lookaheads of every item of the grammar in the simplest way,
+// written apart from InitializeLALRAutomata so it can check the automata that one builds.
+//
+// The LR(0) states are found by their kernels and the lookaheads are spread through the closures
+// and the transitions until nothing changes, without telling propagated and spontaneous lookaheads apart.
+// Returns the lookaheads of the items of every state by the key of its kernel.
+func referenceLALRLookaheads(initialRule GrammarRule, g *Grammar) map[string]map[referenceItem]lib.Set[GrammarToken] {
+	rules := append([]GrammarRule{initialRule}, g.Rules...)
+	firsts := NewFirstFollowTable()
+	GetFirsts(g, &firsts)
+
+	afterDot := func(it referenceItem) (GrammarToken, bool) {
+		if it.dot >= len(rules[it.rule].Production) {
+			return GrammarToken{}, false
+		}
+		return rules[it.rule].Production[it.dot], true
+	}
+	closure := func(kernel []referenceItem) []referenceItem {
+		items := slices.Clone(kernel)
+		added := lib.NewSet[referenceItem]()
+		for _, it := range items {
+			added.Add(it)
+		}
+		for i := 0; i < len(items); i++ {
+			symbol, found := afterDot(items[i])
+			if !found || !symbol.IsNonTerminal() {
+				continue
+			}
+			for idx, rule := range rules {
+				if rule.Head.Equal(&symbol) && added.Add(referenceItem{idx, 0}) {
+					items = append(items, referenceItem{idx, 0})
+				}
+			}
+		}
+		return items
+	}
+
+	// The LR(0) states with their transitions
+	keys := []string{referenceKernelKey([]referenceItem{{0, 0}})}
+	closures := [][]referenceItem{closure([]referenceItem{{0, 0}})}
+	transitions := []map[GrammarToken]int{{}}
+	indexes := map[string]int{keys[0]: 0}
+	for idx := 0; idx < len(closures); idx++ {
+		kernels := make(map[GrammarToken][]referenceItem)
+		symbols := []GrammarToken{}
+		for _, it := range closures[idx] {
+			if symbol, found := afterDot(it); found {
+				if _, seen := kernels[symbol]; !seen {
+					symbols = append(symbols, symbol)
+				}
+				kernels[symbol] = append(kernels[symbol], referenceItem{it.rule, it.dot + 1})
+			}
+		}
+		for _, symbol := range symbols {
+			key := referenceKernelKey(kernels[symbol])
+			target, found := indexes[key]
+			if !found {
+				target = len(closures)
+				indexes[key] = target
+				keys = append(keys, key)
+				closures = append(closures, closure(kernels[symbol]))
+				transitions = append(transitions, map[GrammarToken]int{})
+			}
+			transitions[idx][symbol] = target
+		}
+	}
+
+	lookaheads := make([]map[referenceItem]lib.Set[GrammarToken], len(closures))
+	for idx, items := range closures {
+		lookaheads[idx] = make(map[referenceItem]lib.Set[GrammarToken])
+		for _, it := range items {
+			lookaheads[idx][it] = lib.NewSet[GrammarToken]()
+		}
+	}
+	initial := lookaheads[0][referenceItem{0, 0}]
+	initial.Add(NewEndToken())
+
+	for changed := true; changed; {
+		changed = false
+		for idx, items := range closures {
+			for _, it := range items {
+				symbol, found := afterDot(it)
+				if !found {
+					continue
+				}
+
+				current := lookaheads[idx][it]
+				next := lookaheads[transitions[idx][symbol]][referenceItem{it.rule, it.dot + 1}]
+				for token := range current {
+					changed = next.Add(token) || changed
+				}
+
+				if !symbol.IsNonTerminal() {
+					continue
+				}
+				spread := lib.NewSet[GrammarToken]()
+				for token := range firsts.FirstOfSequence(rules[it.rule].Production[it.dot+1:]) {
+					if IsEpsilon(token) {
+						spread.Merge(&current)
+					} else {
+						spread.Add(token)
+					}
+				}
+				for ruleIdx, rule := range rules {
+					if !rule.Head.Equal(&symbol) {
+						continue
+					}
+					expanded := lookaheads[idx][referenceItem{ruleIdx, 0}]
+					for token := range spread {
+						changed = expanded.Add(token) || changed
+					}
+				}
+			}
+		}
+	}
+
+	result := make(map[string]map[referenceItem]lib.Set[GrammarToken])
+	for idx, key := range keys {
+		result[key] = lookaheads[idx]
+	}
+	return result
+}
+
+func referenceKernelKey(kernel []referenceItem) string {
+	parts := []string{}
+	for _, it := range kernel {
+		parts = append(parts, fmt.Sprintf("%d.%d", it.rule, it.dot))
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ",")
+}
+
+// The go example is the grammar propagating the lookaheads is meant for, its LR(1) automata is too slow to build.
+// The lookaheads are checked against the ones of referenceLALRLookaheads instead.
+func TestInitializeLALRAutomataGoExample(t *testing.T) {
+	if testing.Short() {
+		t.Skip("The go example has many states")
+	}
+
+	g, err := ParseYalFile("../../example/go/grammar.yac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+
+	start := time.Now()
+	auto := InitializeLALRAutomata(initialRule, g)
+	t.Logf("Propagating lookaheads took %v", time.Since(start))
+
+	expected := referenceLALRLookaheads(initialRule, &g)
+	if len(auto.Nodes) != len(expected) {
+		t.Fatalf("Expected the %d states of the LR(0) automata but got %d", len(expected), len(auto.Nodes))
+	}
+
+	ruleIndexes := map[string]int{initialRule.ToString(): 0}
+	for idx, rule := range g.Rules {
+		ruleIndexes[rule.ToString()] = idx + 1
+	}
+	toReferenceItem := func(item *AutomataItem) referenceItem {
+		rule := GrammarRule{Head: item.Head, Production: item.Production}
+		return referenceItem{ruleIndexes[rule.ToString()], item.Dot}
+	}
+
+	// The items of every state by the key of its kernel
+	kernelKeys := make(map[AutomataStateIndex]string)
+	for id, node := range auto.Nodes {
+		kernel := []referenceItem{}
+		for _, item := range node.Items {
+			if it := toReferenceItem(&item); it.dot > 0 || it.rule == 0 {
+				kernel = append(kernel, it)
+			}
+		}
+		kernelKeys[id] = referenceKernelKey(kernel)
+
+		expectedLookaheads, found := expected[kernelKeys[id]]
+		if !found {
+			t.Errorf("State %s doesn't have the kernel of any LR(0) state: %s", id, kernelKeys[id])
+			continue
+		}
+		for _, item := range node.Items {
+			lookahead := expectedLookaheads[toReferenceItem(&item)]
+			if !lookahead.Equals(&item.Lookahead) {
+				t.Errorf("State %s: expected the lookaheads %s of %s but got %s", id, lookahead.String(), item.String(), item.Lookahead.String())
+			}
+		}
+	}
+
+	// Every conflict must come from the lookaheads of the grammar
+	_, conflicts := auto.GenerateParsingTable(&g)
+	for _, conflict := range conflicts {
+		lookaheads := expected[kernelKeys[conflict.State]]
+		for _, action := range conflict.Actions {
+			for _, item := range action.Items {
+				lookahead, found := lookaheads[toReferenceItem(&item)]
+				if !found {
+					t.Errorf("The item %s of the conflict isn't on state %s", item.String(), conflict.State)
+				} else if action.Action.Reduce.HasValue() && !lookahead.Contains(conflict.Lookahead) {
+					t.Errorf("%s can't be reduced on state %s with %s", item.String(), conflict.State, conflict.Lookahead.String())
+				} else if action.Action.Shift.HasValue() && !item.Production[item.Dot].Equal(&conflict.Lookahead) {
+					t.Errorf("%s can't shift %s on state %s", item.String(), conflict.Lookahead.String(), conflict.State)
+				}
+			}
+		}
+	}
+}
+
+func TestInitializeLALRAutomata(t *testing.T) {
+	// S → L = R | R
+	// L → * R | id
+	// R → L
+	S := NewNonTerminalToken("S")
+	L := NewNonTerminalToken("L")
+	R := NewNonTerminalToken("R")
+	eq := NewTerminalToken("=")
+	star := NewTerminalToken("*")
+	id := NewTerminalToken("id")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{L, eq, R}},
+		{Head: S, Production: []GrammarToken{R}},
+		{Head: L, Production: []GrammarToken{star, R}},
+		{Head: L, Production: []GrammarToken{id}},
+		{Head: R, Production: []GrammarToken{L}},
+	}, []GrammarToken{eq, star, id})
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{S}}
+	auto := InitializeLALRAutomata(initialRule, g)
+	if len(auto.Nodes) != 10 {
+		t.Fatalf("Expected the 10 states of the LR(0) automata but got %d", len(auto.Nodes))
+	}
+
+	_, conflicts := auto.GenerateParsingTable(&g)
+	if len(conflicts) != 0 {
+		t.Fatalf("The LALR table shouldn't have conflicts but found:\n%v", conflicts)
+	}
+
+	// On the state after L from the initial one, the lookaheads of R → L • only propagate from S → • R
+	state, found := auto.Transitions[auto.InitialState][L]
+	if !found {
+		t.Fatalf("The initial state has no transition with L")
+	}
+	for _, item := range auto.Nodes[state].Items {
+		if item.Head.Equal(&R) && item.DotIsAtEnd() && len(item.Lookahead) != 1 {
+			t.Errorf("Expected R → L • to only be reduced on $ after S but got %s", item.String())
+		}
+	}
+}
+
+func TestSimplifyStatesKeepsLoops(t *testing.T) {
+	// S → ( S ) | x
+	// The states after ( only differ on their lookaheads and loop on (
+	S := NewNonTerminalToken("S")
+	open := NewTerminalToken("(")
+	close := NewTerminalToken(")")
+	x := NewTerminalToken("x")
+
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{open, S, close}},
+		{Head: S, Production: []GrammarToken{x}},
+	}, []GrammarToken{open, close, x})
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{S}}
+	auto := InitializeAutomata(initialRule, g)
+	auto.SimplifyStates()
+
+	for from := range auto.Nodes {
+		for input, to := range auto.Transitions[from] {
+			if _, found := auto.Nodes[to]; !found {
+				t.Errorf("The transition from %s with %s goes to %s, which was merged", from, input.Symbol(), to)
+			}
+		}
+	}
+}
+
+func TestAutomataToGraph(t *testing.T) {
+	// E → E + E | id
+	E := NewNonTerminalToken("E")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+
+	g := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, E}},
+		{Head: E, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, id})
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{E}}
+	auto := InitializeLALRAutomata(initialRule, g)
+	_, conflicts := auto.GenerateParsingTable(&g)
+	graph := auto.ToGraph("E", conflicts)
+
+	if len(graph.Nodes) != len(auto.Nodes) {
+		t.Fatalf("Expected a node for each of the %d states but got %d", len(auto.Nodes), len(graph.Nodes))
+	}
+	if graph.Nodes[0].Id != auto.InitialState || !graph.Nodes[0].Initial {
+		t.Errorf("Expected the initial state first but got %v", graph.Nodes[0])
+	}
+
+	// Only the state after E from the initial one can accept
+	acceptState := auto.Transitions[auto.InitialState][E]
+	for _, node := range graph.Nodes {
+		if node.Accepting != (node.Id == acceptState) {
+			t.Errorf("The accepting state is %s but %s is marked as %v", acceptState, node.Id, node.Accepting)
+		}
+		if node.Id == acceptState && !slices.Contains(node.Lines, "S' -> E • [$]") {
+			t.Errorf("Expected the items of the state but got %v", node.Lines)
+		}
+	}
+
+	// The conflict is shown on its state
+	conflictTitle := fmt.Sprintf("State %s, shift/reduce conflict on +", conflicts[0].State)
+	found := false
+	for _, node := range graph.Nodes {
+		found = found || node.Title == conflictTitle
+	}
+	if !found {
+		t.Errorf("No state has the title %s: %v", conflictTitle, graph.Nodes)
+	}
+
+	edgeCount := 0
+	for _, transitions := range auto.Transitions {
+		edgeCount += len(transitions)
+	}
+	if len(graph.Edges) != edgeCount {
+		t.Errorf("Expected %d edges but got %d", edgeCount, len(graph.Edges))
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUMBER PLUS LT
+%nonassoc LT
+%%
+expr:
+	expr PLUS expr
+	| expr LT expr
+	| NUMBER
+;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+	auto := InitializeLALRAutomata(initialRule, g)
+	table, conflicts := auto.GenerateParsingTable(&g)
+
+	b := strings.Builder{}
+	if err := auto.WriteReport(&b, &table, conflicts); err != nil {
+		t.Fatal(err)
+	}
+	report := b.String()
+
+	expectedStart := `State 5 conflicts: 2 shift/reduce
+State 6 conflicts: 1 shift/reduce
+
+
+Grammar
+
+    0 expr -> expr PLUS expr
+    1 expr -> expr LT expr
+    2 expr -> NUMBER
+
+
+Terminals, with rules where they appear
+
+    NUMBER (0) 2
+    PLUS (1) 0
+    LT (2) 1
+    $ (4)
+
+
+Nonterminals, with rules where they appear
+
+    expr (3)
+        on left: 0 1 2
+        on right: 0 1
+
+
+State 0
+
+      S' -> • expr [$]
+
+    0 expr -> • expr PLUS expr [$ LT PLUS]
+    1 expr -> • expr LT expr [$ LT PLUS]
+    2 expr -> • NUMBER [$ LT PLUS]
+
+    NUMBER  shift, and go to state 2
+
+    expr  go to state 1
+`
+	if !strings.HasPrefix(report, expectedStart) {
+		t.Fatalf("Expected the report to start with:\n%s\nBut got:\n%s", expectedStart, report)
+	}
+
+	// The reduce that lost is between brackets and the nonassociative cell is an error
+	expectedState := `State 6
+
+    0 expr -> expr • PLUS expr [$ LT PLUS]
+    1 expr -> expr • LT expr [$ LT PLUS]
+    1 expr -> expr LT expr • [$ LT PLUS]
+
+    PLUS  shift, and go to state 3
+
+    PLUS  [reduce using rule 1 (expr)]
+    LT    error (nonassociative)
+    $     reduce using rule 1 (expr)
+`
+	if !strings.Contains(report, expectedState) {
+		t.Fatalf("Expected the report to contain:\n%s\nBut got:\n%s", expectedState, report)
+	}
+	if !strings.Contains(report, "    $  accept\n") {
+		t.Errorf("Expected the accept action on the report:\n%s", report)
+	}
+}
@@ -0,0 +1,125 @@
+package grammar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Creates the terminal of a quoted literal like '+' or "==" used on a production.
+//
+// Literals are always written with single quotes, so '+' and "+" are the same terminal.
+func NewLiteralToken(text string) GrammarToken {
+	return NewTerminalToken(quoteLiteral(text))
+}
+
+func quoteLiteral(text string) string {
+	simple := !strings.ContainsAny(text, `'\`) && !strings.ContainsFunc(text, func(r rune) bool {
+		return !unicode.IsPrint(r)
+	})
+	if simple {
+		return "'" + text + "'"
+	}
+
+	// Go has no strings with single quotes, so it's quoted as a string and the quotes are swapped
+	quoted := strconv.Quote(text)
+	inner := strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(inner, "'", `\'`) + "'"
+}
+
+// The text a literal terminal matches, false if the token isn't a literal.
+func (self GrammarToken) LiteralText() (string, bool) {
+	if !self.IsTerminal() || IsEpsilon(self) {
+		return "", false
+	}
+
+	return unquoteLiteral(self.Symbol())
+}
+
+// Reads a literal written like '+' or "==" with the escapes of Go strings, false if it isn't a valid literal.
+func unquoteLiteral(written string) (string, bool) {
+	if len(written) < 2 || (written[0] != '\'' && written[0] != '"') || written[len(written)-1] != written[0] {
+		return "", false
+	}
+
+	// Converted to a Go string with double quotes, where \' isn't valid and " must be escaped
+	b := strings.Builder{}
+	inner := written[1 : len(written)-1]
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case inner[i] == '\\' && i+1 < len(inner):
+			if inner[i+1] == '\'' {
+				b.WriteByte('\'')
+			} else {
+				b.WriteString(inner[i : i+2])
+			}
+			i++
+		case inner[i] == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(inner[i])
+		}
+	}
+
+	text, err := strconv.Unquote(`"` + b.String() + `"`)
+	if err != nil || text == "" {
+		return "", false
+	}
+	return text, true
+}
+
+// Finds the quote that closes the literal that starts at start, -1 if it isn't closed.
+func literalEnd(runes []rune, start int) int {
+	for i := start + 1; i < len(runes); i++ {
+		if runes[i] == '\\' {
+			i++
+		} else if runes[i] == runes[start] {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Checks if a quote at this point of a rule starts a literal instead of being part of a name like E'.
+func startsLiteral(r rune, previous rune) bool {
+	return (r == '\'' || r == '"') && !(previous == '_' || previous == '\'' || previous == '"' || unicode.IsLetter(previous) || unicode.IsDigit(previous))
+}
+
+var literalRuneNames = map[rune]string{
+	'+': "PLUS", '-': "MINUS", '*': "STAR", '/': "SLASH", '%': "PERCENT",
+	'=': "EQUAL", '<': "LESS", '>': "GREATER", '!': "BANG", '?': "QUESTION",
+	'&': "AMPERSAND", '|': "PIPE", '^': "CARET", '~': "TILDE",
+	'(': "LPAREN", ')': "RPAREN", '[': "LBRACKET", ']': "RBRACKET", '{': "LBRACE", '}': "RBRACE",
+	',': "COMMA", ';': "SEMICOLON", ':': "COLON", '.': "DOT",
+	'#': "HASH", '@': "AT", '$': "DOLLAR", '\\': "BACKSLASH", '\'': "QUOTE", '"': "DOUBLE_QUOTE", '`': "BACKQUOTE",
+	'_': "UNDERSCORE", ' ': "SPACE", '\t': "TAB", '\n': "NEWLINE", '\r': "CARRIAGE_RETURN",
+}
+
+// A name for the text of a literal that can be used on identifiers, like EQUAL_EQUAL for "==" or WHILE for 'while'.
+func LiteralName(text string) string {
+	parts := []string{}
+	word := strings.Builder{}
+	for _, r := range text {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			word.WriteRune(unicode.ToUpper(r))
+			continue
+		}
+
+		if word.Len() > 0 {
+			parts = append(parts, word.String())
+			word.Reset()
+		}
+		if name, found := literalRuneNames[r]; found {
+			parts = append(parts, name)
+		} else {
+			parts = append(parts, fmt.Sprintf("U%04X", r))
+		}
+	}
+	if word.Len() > 0 {
+		parts = append(parts, word.String())
+	}
+
+	return strings.Join(parts, "_")
+}
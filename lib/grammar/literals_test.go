@@ -0,0 +1,151 @@
+package grammar
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+func TestParseYalFileLiterals(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUM ID
+%left '+' "-"
+%%
+stmt: ID ":=" expr ';'
+	| '{' stmt* '}'
+	| ID '|' ID
+	| "'" ' ' "\"" ;
+expr: expr '+' expr | expr '-' expr | '-' expr %prec '+' | NUM | E' ;
+E': ID ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"stmt -> ID ':=' expr ';'",
+		"stmt -> '{' stmt_star '}'",
+		"stmt -> ID '|' ID",
+		`stmt -> '\'' ' ' '"'`,
+		"expr -> expr '+' expr",
+		"expr -> expr '-' expr",
+		"expr -> '-' expr",
+		"expr -> NUM",
+		"expr -> E'",
+		"E' -> ID",
+		"stmt_star -> ε",
+		"stmt_star -> stmt_star stmt",
+	}
+	if rules := rulesToHuman(&g); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("Expected the rules\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(rules, "\n"))
+	}
+
+	// The declared tokens go first, then the literals of the precedence lines and then the ones of the rules
+	literalIds := []struct {
+		text string
+		id   int
+	}{{"+", 2}, {"-", 3}, {":=", 4}, {";", 5}, {"{", 6}, {"}", 7}, {"|", 8}, {"'", 9}, {" ", 10}, {`"`, 11}}
+	for _, literal := range literalIds {
+		token := NewLiteralToken(literal.text)
+		if !g.Terminals.Contains(token) {
+			t.Errorf("Expected %s to be a terminal", token.Symbol())
+		}
+		if id, found := g.TokenIds[token]; !found || id != literal.id {
+			t.Errorf("Expected %s to have the id %d but got %d", token.Symbol(), literal.id, id)
+		}
+	}
+
+	if precedence := g.Precedences[NewLiteralToken("-")]; precedence.Level != 1 {
+		t.Errorf("Expected \"-\" to have the precedence of its %%left line but got %v", precedence)
+	}
+	if precedenceToken := g.Rules[6].PrecedenceToken; !precedenceToken.HasValue() || precedenceToken.GetValue() != NewLiteralToken("+") {
+		t.Errorf("Expected %%prec to take a literal but got %v", precedenceToken)
+	}
+	if text, isLiteral := NewTerminalToken("ID").LiteralText(); isLiteral {
+		t.Errorf("ID isn't a literal but got the text %q", text)
+	}
+}
+
+func TestParseYalFileLiteralDiagnostics(t *testing.T) {
+	_, path, err := parseYalString(t, `%token A
+%%
+s: A 'b ;
+t: A '' ;
+u: A '\q' ;
+`)
+	var diagnostics lib.Diagnostics
+	if !errors.As(err, &diagnostics) {
+		t.Fatalf("Expected diagnostics but got: %v", err)
+	}
+
+	expected := []string{
+		path + ":3:1: invalid production `A 'b` of s: unterminated literal 'b",
+		path + ":4:1: invalid production `A ''` of t: invalid literal ''",
+		path + ":5:1: invalid production `A '\\q'` of u: invalid literal '\\q'",
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics but got %d:\n%s", len(expected), len(diagnostics), diagnostics.Error())
+	}
+	for i, diagnostic := range diagnostics {
+		if diagnostic.String() != expected[i] {
+			t.Errorf("Diagnostic %d = %s, want %s", i, diagnostic.String(), expected[i])
+		}
+	}
+}
+
+func TestLiteralsWriteYal(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUM
+%left '+'
+%%
+e: e '+' e | '(' e ')' | NUM | "\n" ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := strings.Builder{}
+	if err := g.WriteYal(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `%token NUM
+%left '+'
+%%
+
+e:
+	e '+' e
+	| '(' e ')'
+	| NUM
+	| '\n'
+;
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\nBut got:\n%s", expected, b.String())
+	}
+
+	read, _, err := parseYalString(t, b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.TokenIds, g.TokenIds) {
+		t.Errorf("Expected the ids %v but read %v", g.TokenIds, read.TokenIds)
+	}
+}
+
+func TestLiteralName(t *testing.T) {
+	names := map[string]string{
+		"+":     "PLUS",
+		"==":    "EQUAL_EQUAL",
+		"while": "WHILE",
+		"else2": "ELSE2",
+		"->":    "MINUS_GREATER",
+		"a.b":   "A_DOT_B",
+		"ñ":     "U00F1",
+	}
+	for text, expected := range names {
+		if name := LiteralName(text); name != expected {
+			t.Errorf("Expected the name of %q to be %s but got %s", text, expected, name)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package grammar
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+type LLConflictType int
+
+const (
+	// Two productions of the nonterminal start with the lookahead
+	FIRST_FIRST LLConflictType = iota
+	// A nullable production is predicted by the follow of the nonterminal
+	// and another production starts with the lookahead
+	FIRST_FOLLOW
+)
+
+func (self LLConflictType) String() string {
+	switch self {
+	case FIRST_FIRST:
+		return "FIRST/FIRST"
+	case FIRST_FOLLOW:
+		return "FIRST/FOLLOW"
+	}
+
+	return "invalid"
+}
+
+// A rule that wanted a cell of the predictive table.
+type LLPrediction struct {
+	Rule       int
+	Definition GrammarRule
+	// The rule is nullable and the lookahead is on the follow of its head
+	FromFollow bool
+}
+
+// Represents a cell of the predictive table that more than one rule tried to claim.
+type LLConflict struct {
+	Type        LLConflictType
+	NonTerminal GrammarToken
+	// The terminal that triggers the conflict
+	Lookahead   GrammarToken
+	Predictions []LLPrediction
+	// The rule that ended up on the table, the one defined first on the grammar
+	Chosen int
+}
+
+func (self LLConflict) String() string {
+	b := strings.Builder{}
+	b.WriteString(fmt.Sprintf("%s: %s conflict on %s (chose rule %d)\n", self.NonTerminal.Symbol(), self.Type.String(), self.Lookahead.Symbol(), self.Chosen))
+
+	for _, p := range self.Predictions {
+		rule := p.Definition
+		b.WriteString(fmt.Sprintf("\trule %d: %s -> %s", p.Rule, rule.Head.Symbol(), productionToHuman(rule.Production)))
+		if p.FromFollow {
+			b.WriteString(" (on the follow of " + rule.Head.Symbol() + ")")
+		}
+		b.WriteRune('\n')
+	}
+
+	return b.String()
+}
+
+// Sorts the conflicts by nonterminal and lookahead so they're always reported on the same order.
+func SortLLConflicts(conflicts []LLConflict) {
+	slices.SortFunc(conflicts, func(a, b LLConflict) int {
+		if c := cmp.Compare(a.NonTerminal.Symbol(), b.NonTerminal.Symbol()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Lookahead.Symbol(), b.Lookahead.Symbol())
+	})
+}
+
+type LLParsingTable struct {
+	// The rule to expand every nonterminal with every lookahead.
+	Table map[GrammarToken]map[GrammarToken]int
+	// The original grammar, IT MUST NOT BE EXPANDED!
+	Original Grammar
+}
+
+// Generates the predictive parsing table of an LL(1) grammar.
+//
+// A rule A -> α is predicted on the first of α, and if α is nullable also on the follow of A.
+// When more than one rule wants a cell the one defined first on the grammar wins
+// and the conflict is reported, left recursive and not factored grammars always have them.
+func GenerateLLParsingTable(grammar *Grammar) (LLParsingTable, []LLConflict) {
+	firsts := NewFirstFollowTable()
+	GetFirsts(grammar, &firsts)
+	follows := NewFirstFollowTable()
+	GetFollows(grammar, &follows)
+
+	candidates := make(map[GrammarToken]map[GrammarToken][]LLPrediction)
+	predict := func(head GrammarToken, lookahead GrammarToken, prediction LLPrediction) {
+		if _, found := candidates[head]; !found {
+			candidates[head] = make(map[GrammarToken][]LLPrediction)
+		}
+
+		cell := candidates[head][lookahead]
+		if len(cell) > 0 && cell[len(cell)-1].Rule == prediction.Rule {
+			return
+		}
+		candidates[head][lookahead] = append(cell, prediction)
+	}
+
+	for i, rule := range grammar.Rules {
+		nullable := false
+		for terminal := range firsts.FirstOfSequence(rule.Production) {
+			if IsEpsilon(terminal) {
+				nullable = true
+			} else {
+				predict(rule.Head, terminal, LLPrediction{Rule: i, Definition: rule})
+			}
+		}
+
+		if nullable {
+			for terminal := range follows.table[rule.Head].Follow {
+				predict(rule.Head, terminal, LLPrediction{Rule: i, Definition: rule, FromFollow: true})
+			}
+		}
+	}
+
+	table := LLParsingTable{
+		Table:    make(map[GrammarToken]map[GrammarToken]int),
+		Original: *grammar,
+	}
+	conflicts := []LLConflict{}
+	for head, row := range candidates {
+		table.Table[head] = make(map[GrammarToken]int)
+		for lookahead, predictions := range row {
+			// The rules were checked in order, so the first one is the lowest
+			table.Table[head][lookahead] = predictions[0].Rule
+			if len(predictions) == 1 {
+				continue
+			}
+
+			fromFirst := 0
+			for _, p := range predictions {
+				if !p.FromFollow {
+					fromFirst++
+				}
+			}
+			conflictType := FIRST_FOLLOW
+			if fromFirst > 1 {
+				conflictType = FIRST_FIRST
+			}
+
+			conflicts = append(conflicts, LLConflict{
+				Type:        conflictType,
+				NonTerminal: head,
+				Lookahead:   lookahead,
+				Predictions: predictions,
+				Chosen:      predictions[0].Rule,
+			})
+		}
+	}
+
+	return table, conflicts
+}
+
+func (s *LLParsingTable) ToParserTable() parsertypes.LLTable {
+	grammar := convertGrammar(&s.Original)
+	table := parsertypes.LLTable{
+		InitialSymbol:  grammar.InitialSimbol,
+		TokenNames:     s.Original.TransposeTokenIds(),
+		RuleHeads:      make([]int, 0, len(grammar.Rules)),
+		Productions:    make([][]int, 0, len(grammar.Rules)),
+		PredictionRows: make([]int, len(s.Original.TokenIds)),
+		Predictions:    []int{},
+	}
+
+	endToken := NewEndToken()
+	table.EndToken = s.Original.TokenToParserType(&endToken)
+
+	for _, rule := range grammar.Rules {
+		table.RuleHeads = append(table.RuleHeads, rule.Head)
+		table.Productions = append(table.Productions, rule.Production)
+	}
+
+	// Every nonterminal gets a full row, ordered by id
+	for i := range table.PredictionRows {
+		table.PredictionRows[i] = -1
+	}
+	_, nonTerminals := s.Original.tokensById()
+	for _, nonTerminal := range nonTerminals {
+		row := len(table.Predictions)
+		table.PredictionRows[s.Original.TokenToParserType(&nonTerminal)] = row
+		for range table.TokenNames {
+			table.Predictions = append(table.Predictions, -1)
+		}
+
+		for lookahead, rule := range s.Table[nonTerminal] {
+			table.Predictions[row+s.Original.TokenToParserType(&lookahead)] = rule
+		}
+	}
+
+	return table
+}
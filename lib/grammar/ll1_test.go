@@ -0,0 +1,151 @@
+package grammar
+
+import (
+	"testing"
+
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+func TestGenerateLLParsingTable(t *testing.T) {
+	grammar := createEpsilonGrammar()
+	table, conflicts := GenerateLLParsingTable(&grammar)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts but found %v", conflicts)
+	}
+
+	E := NewNonTerminalToken("E")
+	E2 := NewNonTerminalToken("E'")
+	T := NewNonTerminalToken("T")
+	T2 := NewNonTerminalToken("T'")
+	F := NewNonTerminalToken("F")
+	plus := NewTerminalToken("+")
+	mult := NewTerminalToken("*")
+	lparen := NewTerminalToken("(")
+	rparen := NewTerminalToken(")")
+	id := NewTerminalToken("id")
+	end := NewEndToken()
+
+	// The table of the dragon book, with the rules numbered like createEpsilonGrammar
+	expected := map[GrammarToken]map[GrammarToken]int{
+		E:  {id: 0, lparen: 0},
+		E2: {plus: 1, rparen: 2, end: 2},
+		T:  {id: 3, lparen: 3},
+		T2: {plus: 5, mult: 4, rparen: 5, end: 5},
+		F:  {id: 7, lparen: 6},
+	}
+	for head, row := range expected {
+		if len(table.Table[head]) != len(row) {
+			t.Errorf("Expected %d predictions for %s but got %v", len(row), head.Symbol(), table.Table[head])
+		}
+		for lookahead, rule := range row {
+			if got, found := table.Table[head][lookahead]; !found || got != rule {
+				t.Errorf("Expected %s with %s to predict rule %d but got %v", head.Symbol(), lookahead.Symbol(), rule, table.Table[head])
+			}
+		}
+	}
+}
+
+func TestGenerateLLParsingTableConflicts(t *testing.T) {
+	E := NewNonTerminalToken("E")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+	leftRecursive := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, id}},
+		{Head: E, Production: []GrammarToken{id}},
+	}, []GrammarToken{plus, id})
+
+	_, conflicts := GenerateLLParsingTable(&leftRecursive)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected a single conflict but got %v", conflicts)
+	}
+	expected := "E: FIRST/FIRST conflict on id (chose rule 0)\n" +
+		"\trule 0: E -> E + id\n" +
+		"\trule 1: E -> id\n"
+	if conflicts[0].String() != expected {
+		t.Errorf("Expected the conflict\n%s\nbut got\n%s", expected, conflicts[0].String())
+	}
+
+	// A can be empty and be followed by the a it starts with
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	nullable := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{A, a, b}},
+		{Head: A, Production: []GrammarToken{a}},
+		{Head: A, Production: []GrammarToken{}},
+	}, []GrammarToken{a, b})
+
+	table, conflicts := GenerateLLParsingTable(&nullable)
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected a single conflict but got %v", conflicts)
+	}
+	expected = "A: FIRST/FOLLOW conflict on a (chose rule 1)\n" +
+		"\trule 1: A -> a\n" +
+		"\trule 2: A -> ε (on the follow of A)\n"
+	if conflicts[0].String() != expected {
+		t.Errorf("Expected the conflict\n%s\nbut got\n%s", expected, conflicts[0].String())
+	}
+	if table.Table[A][a] != 1 {
+		t.Errorf("Expected the first rule to stay on the table but got %d", table.Table[A][a])
+	}
+}
+
+func TestLLToParserTableParse(t *testing.T) {
+	g, _, err := parseYalString(t, `%token PLUS TIMES LPAREN RPAREN ID
+%%
+e: t ep ;
+ep: PLUS t ep | ;
+t: f tp ;
+tp: TIMES f tp | ;
+f: LPAREN e RPAREN | ID ;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, conflicts := GenerateLLParsingTable(&g)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts but found %v", conflicts)
+	}
+	runtimeTable := table.ToParserTable()
+
+	plus := NewTerminalToken("PLUS")
+	times := NewTerminalToken("TIMES")
+	lparen := NewTerminalToken("LPAREN")
+	rparen := NewTerminalToken("RPAREN")
+	id := NewTerminalToken("ID")
+	end := NewEndToken()
+	parse := func(symbols ...GrammarToken) (*parsertypes.TreeNode, error) {
+		tokens := &tokenSlice{}
+		for i, token := range append(symbols, end) {
+			tokens.tokens = append(tokens.tokens, parsertypes.Token{Start: i, End: i + 1, Line: 1, Col: i + 1, Type: g.TokenToParserType(&token)})
+		}
+		return runtimeTable.Parse(tokens)
+	}
+
+	for _, input := range [][]GrammarToken{{id}, {id, plus, id, times, id}, {lparen, id, plus, id, rparen, times, id}} {
+		if _, err := parse(input...); err != nil {
+			t.Errorf("Expected %v to be accepted but got %v", input, err)
+		}
+	}
+	for _, input := range [][]GrammarToken{{}, {id, id}, {lparen, id}, {id, plus}} {
+		if _, err := parse(input...); err == nil {
+			t.Errorf("Expected %v to be rejected", input)
+		}
+	}
+
+	tree, err := parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<e> -> <t> <ep> (1:1) [0, 1)
+  <t> -> <f> <tp> (1:1) [0, 1)
+    <f> -> ID (1:1) [0, 1)
+      ID "" (1:1) [0, 1)
+    <tp> -> (1:2) [1, 1)
+  <ep> -> (1:2) [1, 1)
+`
+	if tree.String() != expected {
+		t.Errorf("Expected:\n%s\nBut got:\n%s", expected, tree.String())
+	}
+}
@@ -45,6 +45,19 @@ func NewAcceptAction() Action {
 	}
 }
 
+func (a Action) String() string {
+	if a.Accept {
+		return "accept"
+	}
+	if a.Shift.HasValue() {
+		return fmt.Sprintf("shift(%s)", a.Shift.GetValue())
+	}
+	if a.Reduce.HasValue() {
+		return fmt.Sprintf("reduce(%d)", a.Reduce.GetValue())
+	}
+	return "error"
+}
+
 type ParsingTable struct {
 	// The Action table contains all the reduce and shifts of the parsing table.
 	ActionTable map[AFDNodeId]map[GrammarToken]Action
@@ -55,6 +68,10 @@ type ParsingTable struct {
 
 	// The node to start parsing from
 	InitialNodeId AFDNodeId
+
+	// The cells the precedences left as syntax errors, like `a < b < c` with a %nonassoc <.
+	// Unlike the empty cells they must stay errors when the table is packed with default reductions.
+	ExplicitErrors map[AFDNodeId]lib.Set[GrammarToken]
 }
 
 func (g *Grammar) TokenToParserType(token *GrammarToken) parsertypes.GrammarToken {
@@ -107,10 +124,20 @@ func (a Action) ToParserType() parsertypes.Action {
 
 func (s *ParsingTable) ToParserTable() parsertypes.ParsingTable {
 	table := parsertypes.ParsingTable{
-		InitialNodeId: s.InitialNodeId,
-		Original:      convertGrammar(&s.Original),
-		ActionTable:   make(map[parsertypes.AFDNodeId]map[parsertypes.GrammarToken]parsertypes.Action),
-		GoToTable:     make(map[parsertypes.AFDNodeId]map[parsertypes.GrammarToken]parsertypes.AFDNodeId),
+		InitialNodeId:  s.InitialNodeId,
+		Original:       convertGrammar(&s.Original),
+		ActionTable:    make(map[parsertypes.AFDNodeId]map[parsertypes.GrammarToken]parsertypes.Action),
+		GoToTable:      make(map[parsertypes.AFDNodeId]map[parsertypes.GrammarToken]parsertypes.AFDNodeId),
+		ErrorToken:     parsertypes.UNRECOGNIZABLE,
+		TokenNames:     s.Original.TransposeTokenIds(),
+		ExplicitErrors: make(map[parsertypes.AFDNodeId]parsertypes.Set[parsertypes.GrammarToken]),
+	}
+
+	endToken := NewEndToken()
+	table.EndToken = s.Original.TokenToParserType(&endToken)
+	errorToken := NewErrorToken()
+	if s.Original.Terminals.Contains(errorToken) {
+		table.ErrorToken = s.Original.TokenToParserType(&errorToken)
 	}
 
 	for nodeId, row := range s.ActionTable {
@@ -136,5 +163,13 @@ func (s *ParsingTable) ToParserTable() parsertypes.ParsingTable {
 		}
 	}
 
+	for nodeId, tokens := range s.ExplicitErrors {
+		errors := parsertypes.NewSet[parsertypes.GrammarToken]()
+		for token := range tokens {
+			errors.Add(s.Original.TokenToParserType(&token))
+		}
+		table.ExplicitErrors[nodeId] = errors
+	}
+
 	return table
 }
@@ -0,0 +1,199 @@
+package grammar
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+// Generating the same grammar twice must give the same packed table, since it's written on the generated code.
+func TestPackedTableIsReproducible(t *testing.T) {
+	generate := func() parsertypes.PackedTable {
+		g, err := ParseYalFile("../../example/medium/grammar.yal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+		auto := InitializeLALRAutomata(initialRule, g)
+		table, _ := auto.GenerateParsingTable(&g)
+		runtimeTable := table.ToParserTable()
+		return runtimeTable.Pack()
+	}
+
+	first := generate()
+	second := generate()
+	if !reflect.DeepEqual(first.TokenNames, second.TokenNames) {
+		t.Fatalf("The tokens were numbered differently!\nFirst: %v\nSecond: %v", first.TokenNames, second.TokenNames)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("The packed tables are different!\nFirst: %+v\nSecond: %+v", first, second)
+	}
+}
+
+// The table converted for the runtime parses and recovers from syntax errors with the error token.
+func TestToParserTableParse(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUMBER SEMI
+%%
+stmts: stmts stmt
+	| stmt
+	;
+stmt: NUMBER SEMI
+	| error SEMI
+	;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, table, conflicts := buildAutomataAndTable(&g)
+	if len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts but found %v", conflicts)
+	}
+	runtimeTable := table.ToParserTable()
+
+	number := NewTerminalToken("NUMBER")
+	semi := NewTerminalToken("SEMI")
+	tokens := &tokenSlice{}
+	for i, token := range []GrammarToken{number, semi, number, number, semi, number, semi} {
+		tokens.tokens = append(tokens.tokens, parsertypes.Token{Start: i, End: i + 1, Line: 1, Col: i + 1, Type: g.TokenToParserType(&token)})
+	}
+	end := NewEndToken()
+	tokens.tokens = append(tokens.tokens, parsertypes.Token{Start: 7, End: 7, Line: 1, Col: 8, Type: g.TokenToParserType(&end)})
+
+	tree, err := parsertypes.Parse(&runtimeTable, tokens)
+	var syntaxErrors parsertypes.SyntaxErrors
+	if !errors.As(err, &syntaxErrors) || len(syntaxErrors) != 1 {
+		t.Fatalf("Expected a syntax error but got %v", err)
+	}
+	if syntaxErrors[0].Error() != `1:4: unexpected token "" (`+runtimeTable.TokenToHuman(g.TokenToParserType(&number))+`)` {
+		t.Errorf("Unexpected error: %v", syntaxErrors[0])
+	}
+
+	// The error token covers the NUMBER popped to recover
+	expected := `<stmts> -> <stmts> <stmt> (1:1) [0, 7)
+  <stmts> -> <stmts> <stmt> (1:1) [0, 5)
+    <stmts> -> <stmt> (1:1) [0, 2)
+      <stmt> -> NUMBER SEMI (1:1) [0, 2)
+        NUMBER "" (1:1) [0, 1)
+        SEMI "" (1:2) [1, 2)
+    <stmt> -> error SEMI (1:3) [2, 5)
+      error "" (1:3) [2, 3)
+      SEMI "" (1:5) [4, 5)
+  <stmt> -> NUMBER SEMI (1:6) [5, 7)
+    NUMBER "" (1:6) [5, 6)
+    SEMI "" (1:7) [6, 7)
+`
+	if tree.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, tree.String())
+	}
+}
+
+// The packed table must not reduce by default where a %nonassoc terminal leaves an error.
+func TestPackKeepsNonAssocErrors(t *testing.T) {
+	g, _, err := parseYalString(t, `%token ID LESS
+%nonassoc LESS
+%%
+expr: expr LESS expr
+	| ID
+	;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, table, _ := buildAutomataAndTable(&g)
+	runtimeTable := table.ToParserTable()
+	packed := runtimeTable.Pack()
+
+	id := NewTerminalToken("ID")
+	less := NewTerminalToken("LESS")
+	end := NewEndToken()
+	parse := func(symbols ...GrammarToken) error {
+		tokens := &tokenSlice{}
+		for i, token := range append(symbols, end) {
+			tokens.tokens = append(tokens.tokens, parsertypes.Token{Start: i, End: i + 1, Line: 1, Col: i + 1, Type: g.TokenToParserType(&token)})
+		}
+		_, err := packed.Parse(tokens)
+		return err
+	}
+
+	if err := parse(id, less, id); err != nil {
+		t.Fatalf("Expected `a < b` to be accepted but got %v", err)
+	}
+	err = parse(id, less, id, less, id)
+	if err == nil || err.Error() != `1:4: unexpected token "" (`+runtimeTable.TokenToHuman(g.TokenToParserType(&less))+`)` {
+		t.Fatalf("Expected `a < b < c` to fail on the second LESS but got %v", err)
+	}
+}
+
+// The tables of every mode reduce the empty productions on the terminals that can follow them.
+func TestToParserTableParseEmptyProductions(t *testing.T) {
+	g, _, err := parseYalString(t, `%token A B C
+%%
+s: opts list C ;
+opts:
+	/* empty */
+	| A
+;
+list:
+	ε
+	| list B
+;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initialRule := GrammarRule{Head: NewNonTerminalToken("S'"), Production: []GrammarToken{g.InitialSimbol}}
+	lr1 := InitializeAutomata(initialRule, g)
+	lr1Table, lr1Conflicts := lr1.GenerateParsingTable(&g)
+	lalr := InitializeLALRAutomata(initialRule, g)
+	lalrTable, lalrConflicts := lalr.GenerateParsingTable(&g)
+	slrTable, slrConflicts := lalr.GenerateSLRParsingTable(&g)
+
+	a := NewTerminalToken("A")
+	b := NewTerminalToken("B")
+	c := NewTerminalToken("C")
+	end := NewEndToken()
+	for mode, table := range map[string]ParsingTable{"lr1": lr1Table, "lalr": lalrTable, "slr": slrTable} {
+		runtimeTable := table.ToParserTable()
+		parse := func(symbols ...GrammarToken) error {
+			tokens := &tokenSlice{}
+			for i, token := range append(symbols, end) {
+				tokens.tokens = append(tokens.tokens, parsertypes.Token{Start: i, End: i + 1, Line: 1, Col: i + 1, Type: g.TokenToParserType(&token)})
+			}
+			_, err := parsertypes.Parse(&runtimeTable, tokens)
+			return err
+		}
+
+		for _, input := range [][]GrammarToken{{c}, {a, c}, {b, b, c}, {a, b, c}} {
+			if err := parse(input...); err != nil {
+				t.Errorf("%s: expected %v to be accepted but got %v", mode, input, err)
+			}
+		}
+		for _, input := range [][]GrammarToken{{a, a, c}, {c, b}, {b, a, c}} {
+			if err := parse(input...); err == nil {
+				t.Errorf("%s: expected %v to be rejected", mode, input)
+			}
+		}
+	}
+
+	for _, conflicts := range [][]Conflict{lr1Conflicts, lalrConflicts, slrConflicts} {
+		if len(conflicts) != 0 {
+			t.Errorf("Expected no conflicts but found %v", conflicts)
+		}
+	}
+}
+
+// Supplies the tokens of a slice, the last one is repeated forever.
+type tokenSlice struct {
+	tokens []parsertypes.Token
+}
+
+func (self *tokenSlice) Next() (parsertypes.Token, error) {
+	token := self.tokens[0]
+	if len(self.tokens) > 1 {
+		self.tokens = self.tokens[1:]
+	}
+	return token, nil
+}
@@ -0,0 +1,108 @@
+package grammar
+
+import (
+	"strings"
+)
+
+type Associativity int
+
+const (
+	LEFT_ASSOC  Associativity = iota // %left
+	RIGHT_ASSOC                      // %right
+	NON_ASSOC                        // %nonassoc
+)
+
+func (self Associativity) String() string {
+	switch self {
+	case LEFT_ASSOC:
+		return "%left"
+	case RIGHT_ASSOC:
+		return "%right"
+	case NON_ASSOC:
+		return "%nonassoc"
+	}
+
+	return "invalid"
+}
+
+// The precedence of a terminal.
+type Precedence struct {
+	// The higher the level the tighter the terminal binds.
+	Level         int
+	Associativity Associativity
+}
+
+// Checks if the line is a %left, %right or %nonassoc declaration.
+func associativityFromDirective(line string) (Associativity, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return LEFT_ASSOC, false
+	}
+
+	switch fields[0] {
+	case "%left":
+		return LEFT_ASSOC, true
+	case "%right":
+		return RIGHT_ASSOC, true
+	case "%nonassoc":
+		return NON_ASSOC, true
+	}
+
+	return LEFT_ASSOC, false
+}
+
+// Gets the precedence of a rule.
+//
+// It's the precedence of the %prec token if the rule has one,
+// otherwise the precedence of the last terminal on the production.
+func (g *Grammar) RulePrecedence(ruleIdx int) (Precedence, bool) {
+	rule := g.Rules[ruleIdx]
+	if rule.PrecedenceToken.HasValue() {
+		prec, found := g.Precedences[rule.PrecedenceToken.GetValue()]
+		return prec, found
+	}
+
+	for i := len(rule.Production) - 1; i >= 0; i-- {
+		tk := rule.Production[i]
+		if tk.IsTerminal() && !IsEpsilon(tk) {
+			prec, found := g.Precedences[tk]
+			return prec, found
+		}
+	}
+
+	return Precedence{}, false
+}
+
+type precedenceResolution int
+
+const (
+	UNRESOLVED precedenceResolution = iota
+	PREFER_SHIFT
+	PREFER_REDUCE
+	PREFER_ERROR
+)
+
+// Decides between shifting the lookahead and reducing by the rule
+// the same way yacc does with the declared precedences.
+func (g *Grammar) resolveByPrecedence(lookahead GrammarToken, ruleIdx int) precedenceResolution {
+	tokenPrec, tokenFound := g.Precedences[lookahead]
+	rulePrec, ruleFound := g.RulePrecedence(ruleIdx)
+	if !tokenFound || !ruleFound {
+		return UNRESOLVED
+	}
+
+	if rulePrec.Level > tokenPrec.Level {
+		return PREFER_REDUCE
+	} else if rulePrec.Level < tokenPrec.Level {
+		return PREFER_SHIFT
+	}
+
+	switch tokenPrec.Associativity {
+	case LEFT_ASSOC:
+		return PREFER_REDUCE
+	case RIGHT_ASSOC:
+		return PREFER_SHIFT
+	}
+
+	return PREFER_ERROR
+}
@@ -0,0 +1,275 @@
+package grammar
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A line of the actions of a state on the report
+type reportAction struct {
+	token GrammarToken
+	text  string
+	// The action lost a conflict, so it isn't on the table
+	lost bool
+}
+
+// Writes a report of the automata and its parsing table like the one of `bison -v`.
+//
+// It starts with the states that have conflicts and the grammar with its rules numbered like Grammar.Rules,
+// then every state shows its kernel items, its closure items, its actions and its gotos.
+// The actions that lost a conflict are shown between brackets after the one that won,
+// and the counterexamples of the conflicts are shown after the actions of their state.
+func (auto *Automata) WriteReport(w io.Writer, table *ParsingTable, conflicts []Conflict) error {
+	grammar := &table.Original
+	b := strings.Builder{}
+
+	conflicts = slices.Clone(conflicts)
+	SortConflicts(conflicts)
+	conflictsByState := make(map[AutomataStateIndex][]Conflict)
+	for _, conflict := range conflicts {
+		conflictsByState[conflict.State] = append(conflictsByState[conflict.State], conflict)
+	}
+
+	ids := auto.sortedStateIds()
+	for _, id := range ids {
+		if stateConflicts, found := conflictsByState[id]; found {
+			fmt.Fprintf(&b, "State %s conflicts: %s\n", id, countConflicts(stateConflicts))
+		}
+	}
+	if len(conflicts) > 0 {
+		b.WriteString("\n\n")
+	}
+
+	writeReportGrammar(&b, grammar)
+
+	for _, id := range ids {
+		b.WriteString("\n\n")
+		auto.writeReportState(&b, id, table, conflictsByState[id])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Counts the conflicts by type like `1 shift/reduce, 2 reduce/reduce`
+func countConflicts(conflicts []Conflict) string {
+	counts := make(map[ConflictType]int)
+	for _, conflict := range conflicts {
+		counts[conflict.Type]++
+	}
+
+	parts := []string{}
+	for _, conflictType := range []ConflictType{SHIFT_REDUCE, REDUCE_REDUCE} {
+		if counts[conflictType] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[conflictType], conflictType.String()))
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// The production of a rule as it's written on the report, ε if it's empty.
+func productionToHuman(production []GrammarToken) string {
+	if len(production) == 0 {
+		return "ε"
+	}
+
+	symbols := make([]string, 0, len(production))
+	for _, token := range production {
+		symbols = append(symbols, token.Symbol())
+	}
+	return strings.Join(symbols, " ")
+}
+
+// The tokens of the grammar ordered by their id, terminals first.
+func (g *Grammar) tokensById() (terminals []GrammarToken, nonTerminals []GrammarToken) {
+	for token := range g.TokenIds {
+		if token.IsNonTerminal() {
+			nonTerminals = append(nonTerminals, token)
+		} else {
+			terminals = append(terminals, token)
+		}
+	}
+
+	byId := func(a, b GrammarToken) int {
+		return cmp.Compare(g.TokenIds[a], g.TokenIds[b])
+	}
+	slices.SortFunc(terminals, byId)
+	slices.SortFunc(nonTerminals, byId)
+	return terminals, nonTerminals
+}
+
+func joinRuleIndexes(indexes []int) string {
+	parts := make([]string, 0, len(indexes))
+	for _, idx := range indexes {
+		parts = append(parts, strconv.Itoa(idx))
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeReportGrammar(b *strings.Builder, grammar *Grammar) {
+	b.WriteString("Grammar\n\n")
+	onLeft := make(map[GrammarToken][]int)
+	onRight := make(map[GrammarToken][]int)
+	for i, rule := range grammar.Rules {
+		fmt.Fprintf(b, "%5d %s -> %s", i, rule.Head.Symbol(), productionToHuman(rule.Production))
+		if rule.Generated.HasValue() {
+			fmt.Fprintf(b, "  (%s)", rule.Generated.GetValue().String())
+		}
+		b.WriteString("\n")
+
+		onLeft[rule.Head] = append(onLeft[rule.Head], i)
+		for _, token := range rule.Production {
+			if len(onRight[token]) == 0 || onRight[token][len(onRight[token])-1] != i {
+				onRight[token] = append(onRight[token], i)
+			}
+		}
+	}
+
+	terminals, nonTerminals := grammar.tokensById()
+	b.WriteString("\n\nTerminals, with rules where they appear\n\n")
+	for _, token := range terminals {
+		fmt.Fprintf(b, "    %s (%d)", token.Symbol(), grammar.TokenIds[token])
+		if len(onRight[token]) > 0 {
+			b.WriteString(" " + joinRuleIndexes(onRight[token]))
+		}
+		b.WriteRune('\n')
+	}
+
+	b.WriteString("\n\nNonterminals, with rules where they appear\n\n")
+	for _, token := range nonTerminals {
+		fmt.Fprintf(b, "    %s (%d)\n", token.Symbol(), grammar.TokenIds[token])
+		if len(onLeft[token]) > 0 {
+			fmt.Fprintf(b, "        on left: %s\n", joinRuleIndexes(onLeft[token]))
+		}
+		if len(onRight[token]) > 0 {
+			fmt.Fprintf(b, "        on right: %s\n", joinRuleIndexes(onRight[token]))
+		}
+	}
+}
+
+// Describes an action like bison does.
+func reportActionText(grammar *Grammar, action Action) string {
+	switch {
+	case action.Accept:
+		return "accept"
+	case action.Shift.HasValue():
+		return "shift, and go to state " + action.Shift.GetValue()
+	case action.Reduce.HasValue():
+		rule := action.Reduce.GetValue()
+		return fmt.Sprintf("reduce using rule %d (%s)", rule, grammar.Rules[rule].Head.Symbol())
+	}
+
+	return "error"
+}
+
+func (auto *Automata) writeReportState(b *strings.Builder, id AutomataStateIndex, table *ParsingTable, conflicts []Conflict) {
+	grammar := &table.Original
+	fmt.Fprintf(b, "State %s\n\n", id)
+
+	// The kernel items are the ones that were moved into the state, the initial item is the kernel of the first state
+	kernel := []AutomataItem{}
+	closure := []AutomataItem{}
+	for i, item := range auto.Nodes[id].Items {
+		if item.Dot > 0 || (id == auto.InitialState && i == 0) {
+			kernel = append(kernel, item)
+		} else {
+			closure = append(closure, item)
+		}
+	}
+	for i, items := range [][]AutomataItem{kernel, closure} {
+		if i > 0 && len(items) > 0 {
+			b.WriteRune('\n')
+		}
+		for _, item := range items {
+			// The augmented rule isn't one of the rules of the grammar, so it doesn't have a number
+			number := ""
+			if idx := grammar.FindIndexOfRule(&item); idx != -1 {
+				number = strconv.Itoa(idx)
+			}
+			fmt.Fprintf(b, "%5s %s\n", number, item.String())
+		}
+	}
+
+	conflictsByToken := make(map[GrammarToken]Conflict)
+	for _, conflict := range conflicts {
+		conflictsByToken[conflict.Lookahead] = conflict
+	}
+
+	explicitErrors := table.ExplicitErrors[id]
+	shifts := []reportAction{}
+	others := []reportAction{}
+	for token, action := range table.ActionTable[id] {
+		if action.Shift.HasValue() {
+			shifts = append(shifts, reportAction{token: token, text: reportActionText(grammar, action)})
+		} else {
+			others = append(others, reportAction{token: token, text: reportActionText(grammar, action)})
+		}
+	}
+	for token := range explicitErrors {
+		others = append(others, reportAction{token: token, text: "error (nonassociative)"})
+	}
+	for token, conflict := range conflictsByToken {
+		// The cells without an action still need a line to show what lost
+		if _, found := table.ActionTable[id][token]; !found && !explicitErrors.Contains(token) {
+			others = append(others, reportAction{token: token, text: "error"})
+		}
+		for _, a := range conflict.Actions {
+			if a.Action != conflict.Chosen {
+				others = append(others, reportAction{token: token, text: "[" + reportActionText(grammar, a.Action) + "]", lost: true})
+			}
+		}
+	}
+
+	gotos := []reportAction{}
+	for token, to := range table.GoToTable[id] {
+		gotos = append(gotos, reportAction{token: token, text: "go to state " + to})
+	}
+
+	for _, actions := range [][]reportAction{shifts, others, gotos} {
+		if len(actions) == 0 {
+			continue
+		}
+		b.WriteRune('\n')
+
+		// Sorted by token, the bracketed actions go after the chosen one
+		slices.SortFunc(actions, func(x, y reportAction) int {
+			if c := cmp.Compare(grammar.TokenIds[x.token], grammar.TokenIds[y.token]); c != 0 {
+				return c
+			}
+			if x.lost != y.lost {
+				if x.lost {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Compare(x.text, y.text)
+		})
+		width := 0
+		for _, action := range actions {
+			width = max(width, utf8.RuneCountInString(action.token.Symbol()))
+		}
+		for _, action := range actions {
+			fmt.Fprintf(b, "    %-*s  %s\n", width, action.token.Symbol(), action.text)
+		}
+	}
+
+	for _, conflict := range conflicts {
+		if !conflict.Counterexample.HasValue() {
+			continue
+		}
+
+		fmt.Fprintf(b, "\n    %s conflict on %s:\n", conflict.Type.String(), conflict.Lookahead.Symbol())
+		lines := strings.TrimSuffix(conflict.Counterexample.GetValue().String(), "\n")
+		for _, line := range strings.Split(lines, "\n") {
+			b.WriteString("    ")
+			b.WriteString(strings.ReplaceAll(line, "\t", "    "))
+			b.WriteRune('\n')
+		}
+	}
+}
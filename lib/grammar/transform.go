@@ -0,0 +1,328 @@
+package grammar
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+// A grammar rewritten by a transformation along with where its rules came from.
+type TransformedGrammar struct {
+	Grammar Grammar
+	// The index of the rule of the original grammar every rule comes from,
+	// -1 for the rules that don't come from a single rule like the empty productions of the new nonterminals.
+	OriginalRules []int
+}
+
+// A rule being rewritten and the rule of the original grammar it comes from.
+type transformedRule struct {
+	rule   GrammarRule
+	origin int
+}
+
+// The productions of every nonterminal in the order their heads first appear.
+type transformState struct {
+	heads []GrammarToken
+	rules map[GrammarToken][]transformedRule
+	// Every name used by a symbol, so the new nonterminals don't clash with them
+	names lib.Set[string]
+	// The nonterminal of the original grammar every new nonterminal was created for
+	createdFor map[GrammarToken]GrammarToken
+}
+
+func newTransformState(g *Grammar) transformState {
+	state := transformState{
+		rules:      make(map[GrammarToken][]transformedRule),
+		names:      lib.NewSet[string](),
+		createdFor: make(map[GrammarToken]GrammarToken),
+	}
+
+	for i, rule := range g.Rules {
+		if _, found := state.rules[rule.Head]; !found {
+			state.heads = append(state.heads, rule.Head)
+		}
+		state.rules[rule.Head] = append(state.rules[rule.Head], transformedRule{rule: rule, origin: i})
+	}
+	for token := range g.Terminals {
+		state.names.Add(token.Symbol())
+	}
+	for token := range g.NonTerminals {
+		state.names.Add(token.Symbol())
+	}
+
+	return state
+}
+
+// Creates a nonterminal named like base with the suffix, numbered if the name is already used.
+//
+// It goes after base and the other nonterminals created for the same nonterminal of the original grammar.
+func (self *transformState) newNonTerminal(base GrammarToken, suffix string) GrammarToken {
+	name := base.Symbol() + suffix
+	for i := 2; self.names.Contains(name); i++ {
+		name = base.Symbol() + suffix + strconv.Itoa(i)
+	}
+	self.names.Add(name)
+
+	token := NewNonTerminalToken(name)
+	root := base
+	if createdFor, found := self.createdFor[base]; found {
+		root = createdFor
+	}
+	self.createdFor[token] = root
+
+	idx := slices.Index(self.heads, root)
+	for idx+1 < len(self.heads) && self.createdFor[self.heads[idx+1]] == root {
+		idx++
+	}
+	self.heads = slices.Insert(self.heads, idx+1, token)
+	return token
+}
+
+// A rule that isn't the same as the original one anymore,
+// so it loses the semantic action and %prec that were written for the original.
+func rewrittenRule(head GrammarToken, production []GrammarToken, origin int) transformedRule {
+	return transformedRule{
+		rule: GrammarRule{
+			Head:            head,
+			Production:      production,
+			PrecedenceToken: lib.CreateNull[GrammarToken](),
+		},
+		origin: origin,
+	}
+}
+
+// Builds the new grammar from the rules of the state, the symbols keep their ids and the new ones go after them.
+func (self *transformState) toGrammar(g *Grammar) TransformedGrammar {
+	result := TransformedGrammar{
+		Grammar: Grammar{
+			InitialSimbol: g.InitialSimbol,
+			Terminals:     g.Terminals,
+			NonTerminals:  lib.NewSet[GrammarToken](),
+			Precedences:   g.Precedences,
+		},
+		OriginalRules: []int{},
+	}
+
+	for _, head := range self.heads {
+		result.Grammar.NonTerminals.Add(head)
+		for _, rule := range self.rules[head] {
+			result.Grammar.Rules = append(result.Grammar.Rules, rule.rule)
+			result.OriginalRules = append(result.OriginalRules, rule.origin)
+		}
+	}
+	// The nonterminals without rules are kept so Validate still reports them
+	for nonTerminal := range g.NonTerminals {
+		result.Grammar.NonTerminals.Add(nonTerminal)
+	}
+
+	// The new nonterminals are on the rules of the nonterminal they were created for
+	if g.Positions != nil {
+		result.Grammar.Positions = maps.Clone(g.Positions)
+		for _, head := range self.heads {
+			if _, defined := result.Grammar.Positions[head]; defined {
+				continue
+			}
+			if position, found := g.Positions[self.createdFor[head]]; found {
+				result.Grammar.Positions[head] = position
+			}
+		}
+	}
+
+	if g.TokenIds != nil {
+		result.Grammar.TokenIds = make(map[GrammarToken]parsertypes.GrammarToken)
+		endToken := NewEndToken()
+		nextId := 0
+		for token, id := range g.TokenIds {
+			if token != endToken {
+				result.Grammar.TokenIds[token] = id
+				nextId = max(nextId, id+1)
+			}
+		}
+		for _, head := range self.heads {
+			if _, found := result.Grammar.TokenIds[head]; !found {
+				result.Grammar.TokenIds[head] = nextId
+				nextId++
+			}
+		}
+		result.Grammar.TokenIds[endToken] = nextId
+	}
+
+	return result
+}
+
+// Checks if from can derive a string that starts with target, only following the first symbol of the productions.
+func (self *transformState) leftReaches(from GrammarToken, target GrammarToken) bool {
+	visited := lib.NewSet[GrammarToken]()
+	pending := []GrammarToken{from}
+	for len(pending) > 0 {
+		current := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if !visited.Add(current) {
+			continue
+		}
+
+		for _, r := range self.rules[current] {
+			if len(r.rule.Production) == 0 || !r.rule.Production[0].IsNonTerminal() {
+				continue
+			}
+			first := r.rule.Production[0]
+			if first == target {
+				return true
+			}
+			pending = append(pending, first)
+		}
+	}
+
+	return false
+}
+
+// Rewrites the grammar so no nonterminal derives a string that starts with itself,
+// like `statement_list: statement_list statement`.
+//
+// Direct recursion `A: A α | β` becomes `A: β A_tail` and `A_tail: α A_tail | ε`.
+// Indirect recursion is first made direct by replacing the leading nonterminal with its productions,
+// only on the rules where that nonterminal leads back to the head, so the rest of the grammar is kept as it is.
+// Rules like `A: A` are dropped and recursion hidden behind nullable symbols isn't removed.
+//
+// The rules that are rewritten lose their semantic actions and %prec, since the values of their symbols move around.
+func (g *Grammar) EliminateLeftRecursion() TransformedGrammar {
+	state := newTransformState(g)
+	original := slices.Clone(state.heads)
+
+	for i, head := range original {
+		earlier := lib.NewSet[GrammarToken]()
+		for _, other := range original[:i] {
+			earlier.Add(other)
+		}
+
+		// Substitutes the earlier nonterminals that lead back to the head until none is left
+		for changed := true; changed; {
+			changed = false
+			rules := []transformedRule{}
+			for _, r := range state.rules[head] {
+				production := r.rule.Production
+				if len(production) == 0 || !earlier.Contains(production[0]) || !state.leftReaches(production[0], head) {
+					rules = append(rules, r)
+					continue
+				}
+
+				for _, replacement := range state.rules[production[0]] {
+					newProduction := slices.Concat(replacement.rule.Production, production[1:])
+					rules = append(rules, rewrittenRule(head, newProduction, r.origin))
+				}
+				changed = true
+			}
+			state.rules[head] = rules
+		}
+
+		recursive := []transformedRule{}
+		others := []transformedRule{}
+		for _, r := range state.rules[head] {
+			production := r.rule.Production
+			if len(production) > 0 && production[0] == head {
+				if len(production) > 1 {
+					recursive = append(recursive, r)
+				}
+			} else {
+				others = append(others, r)
+			}
+		}
+		if len(recursive) == 0 {
+			state.rules[head] = others
+			continue
+		}
+
+		tail := state.newNonTerminal(head, "_tail")
+		rules := []transformedRule{}
+		for _, r := range others {
+			rules = append(rules, rewrittenRule(head, slices.Concat(r.rule.Production, []GrammarToken{tail}), r.origin))
+		}
+		state.rules[head] = rules
+
+		tailRules := []transformedRule{}
+		for _, r := range recursive {
+			tailRules = append(tailRules, rewrittenRule(tail, slices.Concat(r.rule.Production[1:], []GrammarToken{tail}), r.origin))
+		}
+		tailRules = append(tailRules, rewrittenRule(tail, []GrammarToken{}, -1))
+		state.rules[tail] = tailRules
+	}
+
+	return state.toGrammar(g)
+}
+
+// Rewrites the grammar so no two productions of a nonterminal start with the same symbol.
+//
+// The longest prefix α shared by `A: α β1 | α β2` is factored as `A: α A_factor` and `A_factor: β1 | β2`,
+// the new nonterminals are factored again until every production starts with a different symbol.
+// Repeated productions of a nonterminal are dropped.
+//
+// The rules that are rewritten lose their semantic actions and %prec, since the values of their symbols move around.
+func (g *Grammar) LeftFactor() TransformedGrammar {
+	state := newTransformState(g)
+
+	pending := slices.Clone(state.heads)
+	for len(pending) > 0 {
+		head := pending[0]
+		pending = pending[1:]
+
+		rules := []transformedRule{}
+		for _, r := range state.rules[head] {
+			repeated := slices.ContainsFunc(rules, func(other transformedRule) bool {
+				return slices.Equal(other.rule.Production, r.rule.Production)
+			})
+			if !repeated {
+				rules = append(rules, r)
+			}
+		}
+
+		factored := []transformedRule{}
+		done := make([]bool, len(rules))
+		for i, r := range rules {
+			if done[i] {
+				continue
+			}
+			done[i] = true
+			if len(r.rule.Production) == 0 {
+				factored = append(factored, r)
+				continue
+			}
+
+			group := []transformedRule{r}
+			for j := i + 1; j < len(rules); j++ {
+				other := rules[j].rule.Production
+				if !done[j] && len(other) > 0 && other[0] == r.rule.Production[0] {
+					group = append(group, rules[j])
+					done[j] = true
+				}
+			}
+			if len(group) == 1 {
+				factored = append(factored, r)
+				continue
+			}
+
+			prefix := r.rule.Production
+			for _, other := range group[1:] {
+				length := 0
+				for length < len(prefix) && length < len(other.rule.Production) && prefix[length] == other.rule.Production[length] {
+					length++
+				}
+				prefix = prefix[:length]
+			}
+
+			suffix := state.newNonTerminal(head, "_factor")
+			factored = append(factored, rewrittenRule(head, slices.Concat(prefix, []GrammarToken{suffix}), -1))
+			suffixRules := []transformedRule{}
+			for _, other := range group {
+				suffixRules = append(suffixRules, rewrittenRule(suffix, slices.Clone(other.rule.Production[len(prefix):]), other.origin))
+			}
+			state.rules[suffix] = suffixRules
+			pending = append(pending, suffix)
+		}
+		state.rules[head] = factored
+	}
+
+	return state.toGrammar(g)
+}
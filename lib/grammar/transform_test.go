@@ -0,0 +1,218 @@
+package grammar
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Shows the rules like `E -> T E_tail` to compare them on the tests.
+func rulesToHuman(g *Grammar) []string {
+	rules := []string{}
+	for _, rule := range g.Rules {
+		rules = append(rules, rule.Head.Symbol()+" -> "+productionToHuman(rule.Production))
+	}
+	return rules
+}
+
+func checkTransformed(t *testing.T, result TransformedGrammar, expectedRules []string, expectedOrigins []int) {
+	t.Helper()
+	if rules := rulesToHuman(&result.Grammar); !reflect.DeepEqual(rules, expectedRules) {
+		t.Errorf("Expected the rules\n%s\nbut got\n%s", strings.Join(expectedRules, "\n"), strings.Join(rules, "\n"))
+	}
+	if !reflect.DeepEqual(result.OriginalRules, expectedOrigins) {
+		t.Errorf("Expected the original rules %v but got %v", expectedOrigins, result.OriginalRules)
+	}
+}
+
+func TestEliminateDirectLeftRecursion(t *testing.T) {
+	E := NewNonTerminalToken("E")
+	T := NewNonTerminalToken("T")
+	plus := NewTerminalToken("+")
+	id := NewTerminalToken("id")
+	g := newGrammarFromRules(E, []GrammarRule{
+		{Head: E, Production: []GrammarToken{E, plus, T}, SemanticAction: "$$ = $1 + $3"},
+		{Head: E, Production: []GrammarToken{T}},
+		{Head: T, Production: []GrammarToken{id}, SemanticAction: "$$ = 1"},
+	}, []GrammarToken{plus, id})
+
+	result := g.EliminateLeftRecursion()
+	checkTransformed(t, result, []string{
+		"E -> T E_tail",
+		"E_tail -> + T E_tail",
+		"E_tail -> ε",
+		"T -> id",
+	}, []int{1, 0, -1, 2})
+
+	if result.Grammar.Rules[1].SemanticAction != "" {
+		t.Errorf("Expected the rewritten rules to lose their semantic actions")
+	}
+	if result.Grammar.Rules[3].SemanticAction != "$$ = 1" {
+		t.Errorf("Expected the rules that weren't rewritten to keep their semantic actions")
+	}
+	if !result.Grammar.NonTerminals.Contains(NewNonTerminalToken("E_tail")) {
+		t.Errorf("Expected E_tail to be a nonterminal of the new grammar")
+	}
+}
+
+func TestEliminateIndirectLeftRecursion(t *testing.T) {
+	// S -> A a | b
+	// A -> A c | S d | e
+	S := NewNonTerminalToken("S")
+	A := NewNonTerminalToken("A")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	c := NewTerminalToken("c")
+	d := NewTerminalToken("d")
+	e := NewTerminalToken("e")
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{A, a}},
+		{Head: S, Production: []GrammarToken{b}},
+		{Head: A, Production: []GrammarToken{A, c}},
+		{Head: A, Production: []GrammarToken{S, d}},
+		{Head: A, Production: []GrammarToken{e}},
+	}, []GrammarToken{a, b, c, d, e})
+
+	result := g.EliminateLeftRecursion()
+	checkTransformed(t, result, []string{
+		"S -> A a",
+		"S -> b",
+		"A -> b d A_tail",
+		"A -> e A_tail",
+		"A_tail -> c A_tail",
+		"A_tail -> a d A_tail",
+		"A_tail -> ε",
+	}, []int{0, 1, 3, 4, 2, 3, -1})
+
+	// Nothing changes without left recursion
+	createdGrammar := createEpsilonGrammar()
+	result = createdGrammar.EliminateLeftRecursion()
+	if !reflect.DeepEqual(result.Grammar.Rules, createdGrammar.Rules) {
+		t.Errorf("Expected the rules to stay the same but got\n%s", strings.Join(rulesToHuman(&result.Grammar), "\n"))
+	}
+}
+
+func TestLeftFactor(t *testing.T) {
+	// S -> i E t S | i E t S e S | a
+	// E -> b
+	S := NewNonTerminalToken("S")
+	E := NewNonTerminalToken("E")
+	i := NewTerminalToken("i")
+	tToken := NewTerminalToken("t")
+	e := NewTerminalToken("e")
+	a := NewTerminalToken("a")
+	b := NewTerminalToken("b")
+	g := newGrammarFromRules(S, []GrammarRule{
+		{Head: S, Production: []GrammarToken{i, E, tToken, S}},
+		{Head: S, Production: []GrammarToken{i, E, tToken, S, e, S}},
+		{Head: S, Production: []GrammarToken{a}},
+		{Head: E, Production: []GrammarToken{b}},
+	}, []GrammarToken{i, tToken, e, a, b})
+
+	checkTransformed(t, g.LeftFactor(), []string{
+		"S -> i E t S S_factor",
+		"S -> a",
+		"S_factor -> ε",
+		"S_factor -> e S",
+		"E -> b",
+	}, []int{-1, 2, 0, 1, 3})
+
+	// The new nonterminals are factored again
+	A := NewNonTerminalToken("A")
+	c := NewTerminalToken("c")
+	d := NewTerminalToken("d")
+	g = newGrammarFromRules(A, []GrammarRule{
+		{Head: A, Production: []GrammarToken{a, b, c}},
+		{Head: A, Production: []GrammarToken{a, b, d}},
+		{Head: A, Production: []GrammarToken{a, e}},
+		{Head: A, Production: []GrammarToken{a, e}},
+	}, []GrammarToken{a, b, c, d, e})
+
+	checkTransformed(t, g.LeftFactor(), []string{
+		"A -> a A_factor",
+		"A_factor -> b A_factor_factor",
+		"A_factor -> e",
+		"A_factor_factor -> c",
+		"A_factor_factor -> d",
+	}, []int{-1, -1, 2, 0, 1})
+}
+
+func TestTransformMediumExampleToLL(t *testing.T) {
+	g, err := ParseYalFile("../../example/medium/grammar.yal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, conflicts := GenerateLLParsingTable(&g); len(conflicts) == 0 {
+		t.Fatalf("Expected the left recursive grammar to have LL(1) conflicts")
+	}
+
+	withoutRecursion := g.EliminateLeftRecursion()
+	transformed := withoutRecursion.Grammar.LeftFactor()
+	if _, conflicts := GenerateLLParsingTable(&transformed.Grammar); len(conflicts) != 0 {
+		t.Errorf("Expected the transformed grammar to be LL(1) but found %v", conflicts)
+	}
+
+	// Written and read back it's the same grammar
+	b := strings.Builder{}
+	if err := transformed.Grammar.WriteYal(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	read, _, err := parseYalString(t, b.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rulesToHuman(&read), rulesToHuman(&transformed.Grammar)) {
+		t.Errorf("Expected the rules\n%s\nbut read\n%s", strings.Join(rulesToHuman(&transformed.Grammar), "\n"), strings.Join(rulesToHuman(&read), "\n"))
+	}
+	for _, terminal := range []string{"LET", "WS", "NUMBER"} {
+		token := NewTerminalToken(terminal)
+		if read.TokenIds[token] != g.TokenIds[token] {
+			t.Errorf("Expected %s to keep its id %d but got %d", terminal, g.TokenIds[token], read.TokenIds[token])
+		}
+	}
+}
+
+func TestWriteYal(t *testing.T) {
+	g, _, err := parseYalString(t, `%token NUM PLUS TIMES
+%left PLUS
+%left TIMES
+%start e
+%%
+t: NUM { $$ = $1 } ;
+e: e PLUS e { $$ = $1 + $3 }
+	| e TIMES e %prec TIMES
+	| t
+	| ε
+;
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := strings.Builder{}
+	if err := g.WriteYal(&b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `%token NUM PLUS TIMES
+%left PLUS
+%left TIMES
+%start e
+%%
+
+t:
+	NUM { $$ = $1 }
+;
+
+e:
+	e PLUS e { $$ = $1 + $3 }
+	| e TIMES e %prec TIMES
+	| t
+	| ε
+;
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\nBut got:\n%s", expected, b.String())
+	}
+}
@@ -0,0 +1,233 @@
+package grammar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+type GrammarIssueType int
+
+const (
+	// A nonterminal that is used but doesn't have any production
+	UNDEFINED_NONTERMINAL GrammarIssueType = iota
+	// A nonterminal that can't be derived from the initial symbol
+	UNREACHABLE_NONTERMINAL
+	// A nonterminal that can never derive a string of only terminals
+	UNPRODUCTIVE_NONTERMINAL
+	// A token declared with %token that no production uses
+	UNUSED_TOKEN
+)
+
+func (self GrammarIssueType) String() string {
+	switch self {
+	case UNDEFINED_NONTERMINAL:
+		return "undefined nonterminal"
+	case UNREACHABLE_NONTERMINAL:
+		return "unreachable nonterminal"
+	case UNPRODUCTIVE_NONTERMINAL:
+		return "unproductive nonterminal"
+	case UNUSED_TOKEN:
+		return "unused token"
+	}
+
+	return "invalid"
+}
+
+// A problem found on a grammar by Validate.
+type GrammarIssue struct {
+	Type   GrammarIssueType
+	Symbol GrammarToken
+	// The expression Symbol was generated for if it isn't written on the grammar
+	Generated lib.Optional[EBNFSource]
+	// Where the problem is on the yal file, if the grammar was read from one
+	Position lib.Optional[SymbolPosition]
+}
+
+// Undefined and unproductive nonterminals make the grammar unusable,
+// the other issues are only warnings.
+func (self GrammarIssue) IsError() bool {
+	return self.Type == UNDEFINED_NONTERMINAL || self.Type == UNPRODUCTIVE_NONTERMINAL
+}
+
+func (self GrammarIssue) String() string {
+	severity := "warning"
+	if self.IsError() {
+		severity = "error"
+	}
+
+	symbol := self.Symbol.Symbol()
+	message := fmt.Sprintf("%s: %s `%s`", severity, self.Type.String(), symbol)
+	switch self.Type {
+	case UNDEFINED_NONTERMINAL:
+		message = fmt.Sprintf("%s: nonterminal `%s` doesn't have any production", severity, symbol)
+	case UNREACHABLE_NONTERMINAL:
+		message = fmt.Sprintf("%s: nonterminal `%s` can't be reached from the initial symbol", severity, symbol)
+	case UNPRODUCTIVE_NONTERMINAL:
+		message = fmt.Sprintf("%s: nonterminal `%s` never derives a string of only tokens", severity, symbol)
+	case UNUSED_TOKEN:
+		message = fmt.Sprintf("%s: token `%s` is declared but never used", severity, symbol)
+	}
+
+	if self.Generated.HasValue() {
+		message += " (" + self.Generated.GetValue().String() + ")"
+	}
+	return message
+}
+
+// Checks the grammar for symbols that are undefined, unreachable, unproductive or unused.
+//
+// The issues are sorted by type and then by symbol.
+func (g *Grammar) Validate() []GrammarIssue {
+	issues := []GrammarIssue{}
+
+	heads := lib.NewSet[GrammarToken]()
+	for _, rule := range g.Rules {
+		heads.Add(rule.Head)
+	}
+
+	undefined := lib.NewSet[GrammarToken]()
+	for _, nonTerminal := range g.usedNonTerminals() {
+		if !heads.Contains(nonTerminal) && undefined.Add(nonTerminal) {
+			issues = append(issues, GrammarIssue{Type: UNDEFINED_NONTERMINAL, Symbol: nonTerminal})
+		}
+	}
+
+	reachable := g.reachableSymbols()
+	for head := range heads {
+		if !reachable.Contains(head) {
+			issues = append(issues, GrammarIssue{Type: UNREACHABLE_NONTERMINAL, Symbol: head})
+		}
+	}
+
+	productive := g.productiveNonTerminals()
+	for head := range heads {
+		if !productive.Contains(head) {
+			issues = append(issues, GrammarIssue{Type: UNPRODUCTIVE_NONTERMINAL, Symbol: head})
+		}
+	}
+
+	used := lib.NewSet[GrammarToken]()
+	for _, rule := range g.Rules {
+		for _, token := range rule.Production {
+			used.Add(token)
+		}
+		if rule.PrecedenceToken.HasValue() {
+			used.Add(rule.PrecedenceToken.GetValue())
+		}
+	}
+	for terminal := range g.Terminals {
+		if !terminal.IsEnd && !used.Contains(terminal) {
+			issues = append(issues, GrammarIssue{Type: UNUSED_TOKEN, Symbol: terminal})
+		}
+	}
+
+	sources := g.generatedSources()
+	for i, issue := range issues {
+		if source, found := sources[issue.Symbol]; found {
+			issues[i].Generated = lib.CreateValue(source)
+		}
+		issues[i].Position = g.issuePosition(issue.Symbol, sources)
+	}
+
+	slices.SortFunc(issues, func(a, b GrammarIssue) int {
+		if a.Type != b.Type {
+			return int(a.Type) - int(b.Type)
+		}
+		return strings.Compare(a.Symbol.Symbol(), b.Symbol.Symbol())
+	})
+	return issues
+}
+
+// Where the issues of symbol are reported on the yal file.
+//
+// That's where the symbol is defined, or the head of the rule that generated it
+// or that uses it for the first time if it doesn't have a definition.
+func (g *Grammar) issuePosition(symbol GrammarToken, sources map[GrammarToken]EBNFSource) lib.Optional[SymbolPosition] {
+	if position, found := g.Positions[symbol]; found {
+		return lib.CreateValue(position)
+	}
+	if source, found := sources[symbol]; found {
+		if position, found := g.Positions[source.Head]; found {
+			return lib.CreateValue(position)
+		}
+	}
+	for _, rule := range g.Rules {
+		if position, found := g.Positions[rule.Head]; found && slices.Contains(rule.Production, symbol) {
+			return lib.CreateValue(position)
+		}
+	}
+
+	return lib.CreateNull[SymbolPosition]()
+}
+
+// The initial symbol and every nonterminal used on a production, in order of appearance.
+func (g *Grammar) usedNonTerminals() []GrammarToken {
+	used := []GrammarToken{g.InitialSimbol}
+	for _, rule := range g.Rules {
+		for _, token := range rule.Production {
+			if token.IsNonTerminal() {
+				used = append(used, token)
+			}
+		}
+	}
+
+	return used
+}
+
+// All the symbols that can appear on a derivation of the initial symbol.
+func (g *Grammar) reachableSymbols() lib.Set[GrammarToken] {
+	reachable := lib.NewSet[GrammarToken]()
+	pending := lib.NewStack[GrammarToken]()
+	pending.Push(g.InitialSimbol)
+
+	for !pending.Empty() {
+		current := pending.Pop().GetValue()
+		if !reachable.Add(current) {
+			continue
+		}
+
+		for _, rule := range g.Rules {
+			if rule.Head.Equal(&current) {
+				for _, token := range rule.Production {
+					pending.Push(token)
+				}
+			}
+		}
+	}
+
+	return reachable
+}
+
+// All the nonterminals that can derive a string of only terminals.
+func (g *Grammar) productiveNonTerminals() lib.Set[GrammarToken] {
+	productive := lib.NewSet[GrammarToken]()
+
+	changed := true
+	for changed {
+		changed = false
+
+		for _, rule := range g.Rules {
+			if productive.Contains(rule.Head) {
+				continue
+			}
+
+			allProductive := true
+			for _, token := range rule.Production {
+				if token.IsNonTerminal() && !productive.Contains(token) {
+					allProductive = false
+					break
+				}
+			}
+
+			if allProductive {
+				productive.Add(rule.Head)
+				changed = true
+			}
+		}
+	}
+
+	return productive
+}
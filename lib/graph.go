@@ -0,0 +1,155 @@
+package lib
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// A directed graph ready to be drawn, the automatas are converted to it to export them.
+type Graph struct {
+	Name string
+	// The graphviz shape of the nodes, like box or circle
+	Shape string
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+type GraphNode struct {
+	Id    string
+	Title string
+	// Every line is shown on its own row below the title
+	Lines     []string
+	Initial   bool
+	Accepting bool
+}
+
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+var dotReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Quotes a string to be used as an id or a label on a DOT file.
+func dotString(s string) string {
+	return `"` + dotReplacer.Replace(s) + `"`
+}
+
+// Writes the graph on the DOT language of graphviz.
+//
+// The lines of every node are left aligned and the accepting nodes have a double border.
+func (self *Graph) WriteDOT(w io.Writer) error {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "digraph %s {\n", dotString(self.Name))
+	b.WriteString("\trankdir=LR;\n")
+	fmt.Fprintf(&b, "\tnode [shape=%s, fontname=\"monospace\"];\n", self.Shape)
+
+	for _, node := range self.Nodes {
+		label := dotString(node.Title)
+		if len(node.Lines) > 0 {
+			// Every line ends with \l so they're left aligned
+			lines := strings.Builder{}
+			lines.WriteString(dotReplacer.Replace(node.Title))
+			for _, line := range node.Lines {
+				lines.WriteString(`\l`)
+				lines.WriteString(dotReplacer.Replace(line))
+			}
+			label = `"` + lines.String() + `\l"`
+		}
+
+		fmt.Fprintf(&b, "\t%s [label=%s", dotString(node.Id), label)
+		if node.Accepting {
+			b.WriteString(", peripheries=2")
+		}
+		b.WriteString("];\n")
+
+		if node.Initial {
+			start := dotString("start " + node.Id)
+			fmt.Fprintf(&b, "\t%s [shape=point, label=\"\"];\n", start)
+			fmt.Fprintf(&b, "\t%s -> %s;\n", start, dotString(node.Id))
+		}
+	}
+
+	for _, edge := range self.Edges {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", dotString(edge.From), dotString(edge.To), dotString(edge.Label))
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type htmlGraphNode struct {
+	GraphNode
+	Edges []GraphEdge
+}
+
+var htmlGraphTemplate = template.Must(template.New("graph").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+section { border: 1px solid #999; border-radius: 4px; margin: 1em 0; padding: 0.5em 1em; }
+section:target { border-color: #c60; box-shadow: 0 0 6px #c60; }
+section.accepting { border-width: 3px; border-style: double; }
+h2 { font-size: 1.1em; margin: 0.3em 0; }
+pre { background: #f4f4f4; padding: 0.5em; overflow-x: auto; }
+.badge { font-size: 0.8em; background: #ddd; border-radius: 3px; padding: 0 0.4em; margin-left: 0.5em; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p><input id="filter" type="search" placeholder="Filter nodes..." size="40"> {{len .Nodes}} nodes</p>
+{{range .Nodes}}<section id="node-{{.Id}}" class="{{if .Accepting}}accepting{{end}}">
+<h2>{{.Title}}{{if .Initial}}<span class="badge">initial</span>{{end}}{{if .Accepting}}<span class="badge">accepting</span>{{end}}</h2>
+{{if .Lines}}<pre>{{range .Lines}}{{.}}
+{{end}}</pre>
+{{end}}{{if .Edges}}<ul>
+{{range .Edges}}<li><code>{{.Label}}</code> → <a href="#node-{{.To}}">{{.To}}</a></li>
+{{end}}</ul>
+{{end}}</section>
+{{end}}<details>
+<summary>DOT source</summary>
+<pre>{{.DOT}}</pre>
+</details>
+<script>
+document.getElementById("filter").addEventListener("input", function (e) {
+	var text = e.target.value.toLowerCase();
+	document.querySelectorAll("section").forEach(function (section) {
+		section.style.display = section.textContent.toLowerCase().includes(text) ? "" : "none";
+	});
+});
+</script>
+</body>
+</html>
+`))
+
+// Writes a single HTML page that shows every node with its lines and links to the nodes it goes to,
+// it doesn't need anything else to be opened.
+func (self *Graph) WriteHTML(w io.Writer) error {
+	dot := strings.Builder{}
+	err := self.WriteDOT(&dot)
+	if err != nil {
+		return err
+	}
+
+	edges := make(map[string][]GraphEdge)
+	for _, edge := range self.Edges {
+		edges[edge.From] = append(edges[edge.From], edge)
+	}
+	nodes := make([]htmlGraphNode, 0, len(self.Nodes))
+	for _, node := range self.Nodes {
+		nodes = append(nodes, htmlGraphNode{GraphNode: node, Edges: edges[node.Id]})
+	}
+
+	return htmlGraphTemplate.Execute(w, struct {
+		Name  string
+		Nodes []htmlGraphNode
+		DOT   string
+	}{self.Name, nodes, dot.String()})
+}
@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func createExampleGraph() Graph {
+	return Graph{
+		Name:  `The "example"`,
+		Shape: "box",
+		Nodes: []GraphNode{
+			{Id: "0", Title: "Start", Initial: true},
+			{Id: "1", Title: "End", Lines: []string{`a -> "b" •`, `c\d`}, Accepting: true},
+		},
+		Edges: []GraphEdge{
+			{From: "0", To: "1", Label: "<b>"},
+			{From: "1", To: "1", Label: "c"},
+		},
+	}
+}
+
+func TestGraphWriteDOT(t *testing.T) {
+	graph := createExampleGraph()
+	b := strings.Builder{}
+	if err := graph.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `digraph "The \"example\"" {
+	rankdir=LR;
+	node [shape=box, fontname="monospace"];
+	"0" [label="Start"];
+	"start 0" [shape=point, label=""];
+	"start 0" -> "0";
+	"1" [label="End\la -> \"b\" •\lc\\d\l", peripheries=2];
+	"0" -> "1" [label="<b>"];
+	"1" -> "1" [label="c"];
+}
+`
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, b.String())
+	}
+}
+
+func TestGraphWriteHTML(t *testing.T) {
+	graph := createExampleGraph()
+	b := strings.Builder{}
+	if err := graph.WriteHTML(&b); err != nil {
+		t.Fatal(err)
+	}
+	html := b.String()
+
+	for _, expected := range []string{
+		`<section id="node-1" class="accepting">`,
+		`<li><code>&lt;b&gt;</code> → <a href="#node-1">1</a></li>`,
+		`a -&gt; &#34;b&#34; •`,
+		// The DOT source is included too
+		`digraph &#34;The \&#34;example\&#34;&#34;`,
+	} {
+		if !strings.Contains(html, expected) {
+			t.Errorf("Expected the page to contain %s but got:\n%s", expected, html)
+		}
+	}
+	if strings.Contains(html, "<b>") {
+		t.Errorf("The labels must be escaped:\n%s", html)
+	}
+}
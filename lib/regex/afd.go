@@ -2,6 +2,8 @@ package regex
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/Jose-Prince/UWUCompiler/lib"
@@ -51,8 +53,6 @@ func (self *AFD) String() string {
 	return b.String()
 }
 
-type AFDPairType int
-
 func (self *AFD) GetAllStates() []AFDState {
 	out := []AFDState{}
 
@@ -68,146 +68,6 @@ func (self *AFD) IsAccepted(state *AFDState) bool {
 	return found
 }
 
-func (self *AFD) MarkIfDistinguishable(aState *AFDState, bState *AFDState, table *AFDStateTable[AFDPairType]) AFDPairType {
-	afd := *self
-
-	if pairType, found := table.Get(aState, bState); found {
-		return pairType
-	}
-
-	aTransitions := afd.Transitions[*aState]
-	bTransitions := afd.Transitions[*bState]
-
-	if len(aTransitions) != len(bTransitions) {
-		msg := fmt.Sprintf("Supplied AFD is not really an AFD! The transitions length of these states didn't match\nAFD: %#v\nState A:%s\nState B: %s", afd, *aState, *bState)
-		panic(msg)
-	}
-
-	if *aState == *bState {
-		table.AddOrUpdate(*aState, *bState, EQUIVALENT)
-		return EQUIVALENT
-	}
-
-	if afd.IsAccepted(aState) && !afd.IsAccepted(bState) ||
-		afd.IsAccepted(bState) && !afd.IsAccepted(aState) {
-		table.AddOrUpdate(*aState, *bState, DISTINCT)
-		return DISTINCT
-	}
-
-	for input, aOutState := range aTransitions {
-		bOutState, foundbOutState := bTransitions[input]
-		if !foundbOutState {
-			msg := fmt.Sprintf("B state doesn't contains the same input transition as A state!\nState B: %s\nState A: %s\nAFD: %#v", *bState, *aState, afd)
-			panic(msg)
-		}
-
-		if aOutState == *aState && bOutState == *bState ||
-			bOutState == *aState && aOutState == *bState {
-			continue
-		}
-
-		derivedType := self.MarkIfDistinguishable(&aOutState, &bOutState, table)
-		if DISTINCT == derivedType {
-			table.AddOrUpdate(*aState, *bState, DISTINCT)
-			return DISTINCT
-		}
-	}
-
-	if pairType, found := table.Get(aState, bState); found {
-		return pairType
-	} else {
-		table.AddOrUpdate(*aState, *bState, EQUIVALENT)
-		return EQUIVALENT
-	}
-}
-
-const (
-	DISTINCT AFDPairType = iota
-	EQUIVALENT
-)
-
-type AFDStateTable[T any] map[AFDState]map[AFDState]T
-
-func (self *AFDStateTable[T]) PairAlreadyExists(a *AFDState, b *AFDState) bool {
-	s := *self
-	_, topLevelAFound := s[*a]
-	_, topLevelBFound := s[*b]
-
-	if !topLevelAFound || !topLevelBFound {
-		return false
-	}
-
-	if topLevelAFound {
-		if _, bFound := s[*a][*b]; bFound {
-			return true
-		}
-	}
-
-	if topLevelBFound {
-		if _, aFound := s[*b][*a]; aFound {
-			return true
-		}
-	}
-
-	return false
-}
-
-func (self *AFDStateTable[T]) AddIfNotExists(a AFDState, b AFDState, stateType T) {
-	s := *self
-
-	_, aFound := s[a]
-	if !aFound {
-		s[a] = make(map[AFDState]T)
-	}
-
-	_, bFound := s[b]
-	if !bFound {
-		s[a][b] = stateType
-	}
-
-	_, bFound = s[b]
-	if !bFound {
-		s[b] = make(map[AFDState]T)
-	}
-
-	_, aFound = s[a]
-	if !aFound {
-		s[b][a] = stateType
-	}
-}
-
-func (self *AFDStateTable[T]) AddOrUpdate(a AFDState, b AFDState, stateType T) {
-	s := *self
-
-	if _, found := s[a]; !found {
-		s[a] = map[AFDState]T{}
-	}
-	s[a][b] = stateType
-
-	if _, found := s[b]; !found {
-		s[b] = map[AFDState]T{}
-	}
-	s[b][a] = stateType
-}
-
-func (self *AFDStateTable[T]) Get(a *AFDState, b *AFDState) (T, bool) {
-	var defaultPairType T
-
-	if !self.PairAlreadyExists(a, b) {
-		return defaultPairType, false
-	}
-
-	if pairType, found := (*self)[*a][*b]; found {
-		return pairType, true
-	}
-
-	if pairType, found := (*self)[*b][*a]; found {
-		return pairType, true
-	}
-
-	return defaultPairType, false
-}
-
 func (table ASTTable) ToAFD() AFD {
 	afd := AFD{
 		Transitions:      make(map[AFDState]map[AlphabetInput]AFDState),
@@ -270,6 +130,167 @@ func (table ASTTable) ToAFD() AFD {
 	return afd
 }
 
+// Returns an equivalent AFD with the least amount of states.
+//
+// States are merged by partition refinement, starting with the acceptance states apart from the rest.
+// Dummy inputs are treated like any other input, so states that finish different lexer rules are never merged.
+// A missing transition is treated as a transition into an implicit dead state.
+func (self *AFD) Minimize() AFD {
+	states := self.getReachableStates()
+
+	// Maps each state into the id of the block it belongs to
+	blocks := make(map[AFDState]int, len(states))
+	for _, state := range states {
+		if self.AcceptanceStates.Contains(state) {
+			blocks[state] = 1
+		} else {
+			blocks[state] = 0
+		}
+	}
+
+	blockCount := countBlocks(blocks)
+	for {
+		signatures := make(map[string]int)
+		newBlocks := make(map[AFDState]int, len(states))
+		for _, state := range states {
+			signature := self.blockSignature(state, blocks)
+			id, found := signatures[signature]
+			if !found {
+				id = len(signatures)
+				signatures[signature] = id
+			}
+			newBlocks[state] = id
+		}
+
+		blocks = newBlocks
+		if len(signatures) == blockCount {
+			break
+		}
+		blockCount = len(signatures)
+	}
+
+	// Every block is named after one of its states, the initial state keeps its name
+	names := make(map[int]AFDState, blockCount)
+	names[blocks[self.InitialState]] = self.InitialState
+	for _, state := range states {
+		block := blocks[state]
+		if name, found := names[block]; !found || (name != self.InitialState && state < name) {
+			names[block] = state
+		}
+	}
+
+	minimized := AFD{
+		InitialState:     self.InitialState,
+		Transitions:      make(map[AFDState]map[AlphabetInput]AFDState),
+		AcceptanceStates: lib.NewSet[AFDState](),
+	}
+	for _, state := range states {
+		name := names[blocks[state]]
+		if self.AcceptanceStates.Contains(state) {
+			minimized.AcceptanceStates.Add(name)
+		}
+
+		if _, found := minimized.Transitions[name]; !found {
+			minimized.Transitions[name] = make(map[AlphabetInput]AFDState)
+		}
+		for input, nextState := range self.Transitions[state] {
+			minimized.Transitions[name][input] = names[blocks[nextState]]
+		}
+	}
+
+	return minimized
+}
+
+// Returns all the states reachable from the initial state, sorted by name.
+func (self *AFD) getReachableStates() []AFDState {
+	visited := lib.NewSet[AFDState]()
+	pending := lib.NewStack[AFDState]()
+	pending.Push(self.InitialState)
+
+	for !pending.Empty() {
+		state := pending.Pop().GetValue()
+		if !visited.Add(state) {
+			continue
+		}
+
+		for _, nextState := range self.Transitions[state] {
+			pending.Push(nextState)
+		}
+	}
+
+	states := visited.ToSlice()
+	slices.Sort(states)
+	return states
+}
+
+// Numbers the states from 0 in the order they're reached from the initial state,
+// following the runes in order and then the rules by priority.
+func (self *AFD) StateNumbers() map[AFDState]int {
+	numbers := map[AFDState]int{self.InitialState: 0}
+	queue := []AFDState{self.InitialState}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		inputs := make([]AlphabetInput, 0, len(self.Transitions[state]))
+		for input := range self.Transitions[state] {
+			inputs = append(inputs, input)
+		}
+		slices.SortFunc(inputs, compareInputs)
+
+		for _, input := range inputs {
+			nextState := self.Transitions[state][input]
+			if _, found := numbers[nextState]; !found {
+				numbers[nextState] = len(numbers)
+				queue = append(queue, nextState)
+			}
+		}
+	}
+
+	return numbers
+}
+
+// Runes go first, then the rules by priority.
+func compareInputs(a, b AlphabetInput) int {
+	if a.IsDummy() != b.IsDummy() {
+		if b.IsDummy() {
+			return -1
+		}
+		return 1
+	}
+
+	if a.IsDummy() {
+		return int(a.GetDummy().Priority) - int(b.GetDummy().Priority)
+	}
+	if a.IsValue() && b.IsValue() && a.GetValue().HasValue() && b.GetValue().HasValue() {
+		return int(a.GetValue().GetValue() - b.GetValue().GetValue())
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// Describes a state by its block and the blocks its transitions go to.
+//
+// Two states with the same signature can't be distinguished using the current blocks.
+func (self *AFD) blockSignature(state AFDState, blocks map[AFDState]int) string {
+	transitions := make([]string, 0, len(self.Transitions[state]))
+	for input, nextState := range self.Transitions[state] {
+		transitions = append(transitions, fmt.Sprintf("%#v->%d", input, blocks[nextState]))
+	}
+	slices.Sort(transitions)
+
+	return strconv.Itoa(blocks[state]) + "|" + strings.Join(transitions, "|")
+}
+
+func countBlocks(blocks map[AFDState]int) int {
+	ids := lib.NewSet[int]()
+	for _, id := range blocks {
+		ids.Add(id)
+	}
+
+	return len(ids)
+}
+
 func (self *AFD) Derivation(w string) bool {
 	state := self.InitialState
 	for _, ch := range w {
@@ -3,6 +3,7 @@ package regex
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/Jose-Prince/UWUCompiler/lib"
@@ -127,3 +128,143 @@ func TestFullRegexToAFDFlow(t *testing.T) {
 		t.Fatal(err.Error())
 	}
 }
+
+func TestMinimizeMergesEquivalentStates(t *testing.T) {
+	// Both "A" and "B" only accept a single "b" afterwards, so they're equivalent
+	afd := AFD{
+		InitialState:     "0",
+		AcceptanceStates: lib.Set[AFDState]{"C": struct{}{}, "D": struct{}{}},
+		Transitions: map[AFDState]map[AlphabetInput]AFDState{
+			"0": {
+				CreateValueToken('a'): "A",
+				CreateValueToken('c'): "B",
+			},
+			"A": {CreateValueToken('b'): "C"},
+			"B": {CreateValueToken('b'): "D"},
+			"C": {},
+			"D": {},
+		},
+	}
+
+	minimized := afd.Minimize()
+	if len(minimized.Transitions) != 3 {
+		t.Fatalf("Expected 3 states but got %d:\n%s", len(minimized.Transitions), minimized.String())
+	}
+
+	if minimized.InitialState != "0" {
+		t.Fatalf("The initial state should keep its name, got %s", minimized.InitialState)
+	}
+
+	for _, word := range []string{"ab", "cb"} {
+		if !minimized.Derivation(word) {
+			t.Errorf("The minimized AFD should accept %q", word)
+		}
+	}
+
+	for _, word := range []string{"a", "c", "abb", ""} {
+		if minimized.Derivation(word) {
+			t.Errorf("The minimized AFD shouldn't accept %q", word)
+		}
+	}
+}
+
+func TestMinimizeKeepsCanvasExample(t *testing.T) {
+	afd := CreateCanvasExampleAFD()
+	minimized := afd.Minimize()
+
+	err := validateAFD(&afd, &minimized)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(minimized.Transitions) != len(afd.Transitions) {
+		t.Fatalf("The canvas example is already minimal, but got:\n%s", minimized.String())
+	}
+}
+
+func TestMinimizeKeepsDifferentRulesApart(t *testing.T) {
+	ruleA := CreateDummyToken(DummyInfo{Regex: "a", Code: "return A", Priority: 1})
+	ruleB := CreateDummyToken(DummyInfo{Regex: "b", Code: "return B", Priority: 2})
+
+	// "A" and "B" only differ on the rule they finish
+	afd := AFD{
+		InitialState:     "0",
+		AcceptanceStates: lib.Set[AFDState]{"F": struct{}{}},
+		Transitions: map[AFDState]map[AlphabetInput]AFDState{
+			"0": {
+				CreateValueToken('a'): "A",
+				CreateValueToken('b'): "B",
+			},
+			"A": {ruleA: "F"},
+			"B": {ruleB: "F"},
+			"F": {},
+		},
+	}
+
+	minimized := afd.Minimize()
+	if len(minimized.Transitions) != 4 {
+		t.Fatalf("States that finish different rules shouldn't be merged:\n%s", minimized.String())
+	}
+
+	aState := minimized.Transitions["0"][CreateValueToken('a')]
+	bState := minimized.Transitions["0"][CreateValueToken('b')]
+	if aState == bState {
+		t.Fatalf("The states after 'a' and 'b' were merged:\n%s", minimized.String())
+	}
+}
+
+func TestStateNumbers(t *testing.T) {
+	afd := CreateCanvasExampleAFD()
+	numbers := afd.StateNumbers()
+
+	// Breadth first from the initial state, following the runes in order
+	expected := map[AFDState]int{"0": 0, "2": 1, "3": 2, "1": 3}
+	if len(numbers) != len(expected) {
+		t.Fatalf("Expected %v but got %v", expected, numbers)
+	}
+	for state, number := range expected {
+		if numbers[state] != number {
+			t.Fatalf("Expected %v but got %v", expected, numbers)
+		}
+	}
+}
+
+func TestAFDToGraph(t *testing.T) {
+	ruleNumber := CreateDummyToken(DummyInfo{Regex: "[0-9]+", Code: " return NUMBER ", Priority: 1})
+	ruleKeyword := CreateDummyToken(DummyInfo{Regex: "0", Code: "return ZERO", Priority: 0})
+
+	afd := AFD{
+		InitialState:     "A",
+		AcceptanceStates: lib.Set[AFDState]{"F": struct{}{}},
+		Transitions: map[AFDState]map[AlphabetInput]AFDState{
+			"A": {CreateValueToken('0'): "B", CreateValueToken('1'): "C", CreateValueToken('2'): "C", CreateValueToken('3'): "C", CreateValueToken('5'): "C", CreateValueToken(' '): "C"},
+			"B": {ruleNumber: "F", ruleKeyword: "F", CreateValueToken('0'): "C"},
+			"C": {ruleNumber: "F", CreateValueToken('0'): "C", CreateValueToken('1'): "C"},
+			"F": {},
+		},
+	}
+
+	// The space goes before 0, so C is numbered first
+	graph := afd.ToGraph("numbers")
+	expected := `digraph "numbers" {
+	rankdir=LR;
+	node [shape=ellipse, fontname="monospace"];
+	"0" [label="0"];
+	"start 0" [shape=point, label=""];
+	"start 0" -> "0";
+	"1" [label="1\l[0-9]+ => return NUMBER\l", peripheries=2];
+	"2" [label="2\l0 => return ZERO\l[0-9]+ => return NUMBER\l", peripheries=2];
+	"0" -> "1" [label="[␣1-35]"];
+	"0" -> "2" [label="0"];
+	"1" -> "1" [label="[01]"];
+	"2" -> "1" [label="0"];
+}
+`
+	b := strings.Builder{}
+	if err := graph.WriteDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, b.String())
+	}
+}
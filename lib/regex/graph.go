@@ -0,0 +1,117 @@
+package regex
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// Converts the AFD to a graph with the states numbered by StateNumbers,
+// the same numbers the generated lexer uses.
+//
+// The transitions between two states are joined into a single edge labelled like a character class,
+// and the states that finish a rule list them by priority, the first one is the one that wins.
+// The transitions with the rules aren't drawn.
+func (self *AFD) ToGraph(name string) lib.Graph {
+	graph := lib.Graph{Name: name, Shape: "ellipse"}
+
+	numbers := self.StateNumbers()
+	states := make([]AFDState, len(numbers))
+	for state, number := range numbers {
+		states[number] = state
+	}
+
+	for number, state := range states {
+		// The rules all go to a state without transitions, it doesn't add anything to the drawing
+		if len(self.Transitions[state]) == 0 && state != self.InitialState {
+			continue
+		}
+
+		id := strconv.Itoa(number)
+		node := lib.GraphNode{Id: id, Title: id, Initial: state == self.InitialState}
+
+		rules := []DummyInfo{}
+		runes := make(map[AFDState][]rune)
+		targets := []AFDState{}
+		for input, nextState := range self.Transitions[state] {
+			if input.IsDummy() {
+				rules = append(rules, input.GetDummy())
+			} else if input.IsValue() && input.GetValue().HasValue() {
+				if _, found := runes[nextState]; !found {
+					targets = append(targets, nextState)
+				}
+				runes[nextState] = append(runes[nextState], input.GetValue().GetValue())
+			}
+		}
+
+		slices.SortFunc(rules, func(a, b DummyInfo) int {
+			return int(a.Priority) - int(b.Priority)
+		})
+		for _, rule := range rules {
+			node.Lines = append(node.Lines, rule.Regex+" => "+strings.TrimSpace(rule.Code))
+		}
+		node.Accepting = len(rules) > 0
+		graph.Nodes = append(graph.Nodes, node)
+
+		slices.SortFunc(targets, func(a, b AFDState) int {
+			return numbers[a] - numbers[b]
+		})
+		for _, target := range targets {
+			graph.Edges = append(graph.Edges, lib.GraphEdge{
+				From:  id,
+				To:    strconv.Itoa(numbers[target]),
+				Label: describeRunes(runes[target]),
+			})
+		}
+	}
+
+	return graph
+}
+
+// Writes the runes like a character class, joining the consecutive ones into ranges: [0-9a-f].
+//
+// A single rune is written by itself.
+func describeRunes(runes []rune) string {
+	slices.Sort(runes)
+	if len(runes) == 1 {
+		return escapeRune(runes[0], false)
+	}
+
+	b := strings.Builder{}
+	b.WriteRune('[')
+	for i := 0; i < len(runes); {
+		end := i
+		for end+1 < len(runes) && runes[end+1] == runes[end]+1 {
+			end++
+		}
+
+		b.WriteString(escapeRune(runes[i], true))
+		if end-i > 1 {
+			b.WriteRune('-')
+		}
+		if end > i {
+			b.WriteString(escapeRune(runes[end], true))
+		}
+		i = end + 1
+	}
+	b.WriteRune(']')
+
+	return b.String()
+}
+
+// Escapes the runes that can't be seen and the ones with a meaning inside a character class.
+func escapeRune(r rune, inClass bool) string {
+	switch {
+	case r == ' ':
+		return "␣"
+	case inClass && (r == ']' || r == '[' || r == '-' || r == '\\' || r == '^'):
+		return `\` + string(r)
+	case !strconv.IsPrint(r):
+		quoted := strconv.QuoteRune(r)
+		return quoted[1 : len(quoted)-1]
+	}
+
+	return string(r)
+}
@@ -0,0 +1,85 @@
+package regex
+
+import (
+	"io"
+	"regexp"
+
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+// Matches the token returned by the code of a rule, like `return NUMBER`
+var returnRegex = regexp.MustCompile(`\breturn\s+([A-Za-z_][A-Za-z0-9_]*)\b`)
+
+// Creates a lexer that runs the AFD directly over the contents of reader,
+// without generating any code for it.
+//
+// tokenType gives the type of the token of every rule of the AFD, or parsertypes.IGNORE to skip it.
+func NewLexer(afd *AFD, reader io.Reader, tokenType func(rule DummyInfo) int, endToken int) *parsertypes.Lexer {
+	return parsertypes.NewLexer(reader, 0, afd.LexerStep(tokenType), endToken)
+}
+
+// Moves through the AFD the same way the generated gettoken function does,
+// the states are numbered with StateNumbers.
+//
+// When a state finishes more than one rule the one with the lowest priority number wins.
+func (self *AFD) LexerStep(tokenType func(rule DummyInfo) int) parsertypes.LexerStep {
+	numbers := self.StateNumbers()
+	transitions := make([]map[rune]int, len(numbers))
+	// The result of moving into every state
+	results := make([]int, len(numbers))
+	for state, number := range numbers {
+		transitions[number] = make(map[rune]int)
+		results[number] = parsertypes.GIVE_NEXT
+
+		found := false
+		best := DummyInfo{}
+		for input, nextState := range self.Transitions[state] {
+			if input.IsDummy() {
+				if !found || input.GetDummy().Priority < best.Priority {
+					best = input.GetDummy()
+					found = true
+				}
+			} else if input.IsValue() && input.GetValue().HasValue() {
+				transitions[number][input.GetValue().GetValue()] = numbers[nextState]
+			}
+		}
+
+		if found {
+			results[number] = tokenType(best)
+		}
+	}
+
+	return func(state *int, input rune) int {
+		nextState, found := transitions[*state][input]
+		if !found {
+			return parsertypes.UNRECOGNIZABLE
+		}
+
+		*state = nextState
+		return results[nextState]
+	}
+}
+
+// Gives the token type of a rule from the name its code returns, like `return NUMBER`,
+// looking it up on the token names of table.
+//
+// Rules that return IGNORE or don't return anything are skipped.
+func TokenTypesFromTable(table *parsertypes.ParsingTable) func(rule DummyInfo) int {
+	types := make(map[string]int)
+	for id, name := range table.TokenNames {
+		types[name] = id
+	}
+
+	return func(rule DummyInfo) int {
+		match := returnRegex.FindStringSubmatch(rule.Code)
+		if match == nil || match[1] == "IGNORE" {
+			return parsertypes.IGNORE
+		}
+
+		tokenType, found := types[match[1]]
+		if !found {
+			return parsertypes.UNRECOGNIZABLE
+		}
+		return tokenType
+	}
+}
@@ -0,0 +1,95 @@
+package regex
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+func TestNewLexer(t *testing.T) {
+	ruleA := CreateDummyToken(DummyInfo{Regex: "a+", Code: "return A", Priority: 2})
+	ruleAB := CreateDummyToken(DummyInfo{Regex: "a+b", Code: "return AB", Priority: 1})
+	ruleC := CreateDummyToken(DummyInfo{Regex: "c", Code: "return A", Priority: 3})
+	ruleKeyword := CreateDummyToken(DummyInfo{Regex: "c", Code: "return AB", Priority: 0})
+	ruleSpace := CreateDummyToken(DummyInfo{Regex: " ", Code: "", Priority: 4})
+
+	afd := AFD{
+		InitialState:     "0",
+		AcceptanceStates: lib.Set[AFDState]{"F": struct{}{}},
+		Transitions: map[AFDState]map[AlphabetInput]AFDState{
+			"0": {
+				CreateValueToken('a'): "1",
+				CreateValueToken('c'): "3",
+				CreateValueToken(' '): "4",
+			},
+			"1": {CreateValueToken('a'): "1", CreateValueToken('b'): "2", ruleA: "F"},
+			"2": {ruleAB: "F"},
+			// Both rules match c, the one with the lowest priority number wins
+			"3": {ruleC: "F", ruleKeyword: "F"},
+			"4": {ruleSpace: "F"},
+			"F": {},
+		},
+	}
+
+	table := parsertypes.ParsingTable{TokenNames: []string{"A", "AB", "<EOF>"}}
+	lexer := NewLexer(&afd, strings.NewReader("aab aa c x a"), TokenTypesFromTable(&table), 2)
+
+	expected := []struct {
+		text      string
+		tokenType int
+	}{
+		{"aab", 1},
+		{"aa", 0},
+		{"c", 1},
+	}
+	for _, want := range expected {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Text != want.text || token.Type != want.tokenType {
+			t.Fatalf("Expected %q of type %d but got %q of type %d", want.text, want.tokenType, token.Text, token.Type)
+		}
+	}
+
+	_, err := lexer.Next()
+	var lexErr *parsertypes.LexError
+	if !errors.As(err, &lexErr) || lexErr.Char != 'x' {
+		t.Fatalf("Expected an error on x but got %v", err)
+	}
+
+	for _, want := range []int{0, 2} {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token.Type != want {
+			t.Fatalf("Expected a token of type %d but got %v", want, token.String())
+		}
+	}
+}
+
+func TestTokenTypesFromTable(t *testing.T) {
+	table := parsertypes.ParsingTable{TokenNames: []string{"NUMBER", "<EOF>"}}
+	tokenType := TokenTypesFromTable(&table)
+
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"return NUMBER", 0},
+		{"{ return NUMBER }", 0},
+		{"return IGNORE", parsertypes.IGNORE},
+		{"", parsertypes.IGNORE},
+		{"return MISSING", parsertypes.UNRECOGNIZABLE},
+	}
+
+	for _, test := range tests {
+		if got := tokenType(DummyInfo{Code: test.code}); got != test.want {
+			t.Errorf("%q: expected %d but got %d", test.code, test.want, got)
+		}
+	}
+}
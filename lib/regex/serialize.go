@@ -0,0 +1,237 @@
+package regex
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// The version of the format written by WriteJSON and WriteBinary.
+//
+// It must be increased every time the format changes, so old files are rejected instead of misread.
+const AFD_FORMAT_VERSION int = 1
+
+const AFD_FORMAT = "uwu-afd"
+
+// The magic bytes at the start of the binary format
+const AFD_MAGIC = "UWUD"
+
+type jsonAFD struct {
+	Format           string           `json:"format"`
+	Version          int              `json:"version"`
+	InitialState     AFDState         `json:"initialState"`
+	AcceptanceStates []AFDState       `json:"acceptanceStates"`
+	States           []AFDState       `json:"states"`
+	Transitions      []jsonTransition `json:"transitions"`
+}
+
+// A transition of the AFD, the input is either a rune or the rule a dummy token finishes.
+type jsonTransition struct {
+	From AFDState   `json:"from"`
+	To   AFDState   `json:"to"`
+	Rune *rune      `json:"rune,omitempty"`
+	Rule *DummyInfo `json:"rule,omitempty"`
+}
+
+// Every transition of the AFD sorted by state and then by input,
+// so the same AFD is always written the same way.
+func toJSONAFD(afd *AFD) (jsonAFD, error) {
+	out := jsonAFD{
+		Format:           AFD_FORMAT,
+		Version:          AFD_FORMAT_VERSION,
+		InitialState:     afd.InitialState,
+		AcceptanceStates: lib.GetValuesStable(afd.AcceptanceStates),
+		States:           afd.GetAllStates(),
+		Transitions:      []jsonTransition{},
+	}
+	slices.Sort(out.States)
+
+	for _, state := range out.States {
+		transitions := []jsonTransition{}
+		for input, nextState := range afd.Transitions[state] {
+			transition := jsonTransition{From: state, To: nextState}
+			if input.IsDummy() {
+				info := input.GetDummy()
+				transition.Rule = &info
+			} else if input.IsValue() && input.GetValue().HasValue() {
+				value := input.GetValue().GetValue()
+				transition.Rune = &value
+			} else {
+				return jsonAFD{}, fmt.Errorf("the transition from %s with %s can't be written", state, input.String())
+			}
+			transitions = append(transitions, transition)
+		}
+
+		slices.SortFunc(transitions, compareTransitions)
+		out.Transitions = append(out.Transitions, transitions...)
+	}
+
+	return out, nil
+}
+
+// Runes go first, then the rules by priority.
+func compareTransitions(a, b jsonTransition) int {
+	if (a.Rune == nil) != (b.Rune == nil) {
+		if a.Rune != nil {
+			return -1
+		}
+		return 1
+	}
+
+	if a.Rune != nil {
+		return int(*a.Rune - *b.Rune)
+	}
+	if a.Rule.Priority != b.Rule.Priority {
+		return int(a.Rule.Priority) - int(b.Rule.Priority)
+	}
+	return strings.Compare(a.Rule.Regex, b.Rule.Regex)
+}
+
+func fromJSONAFD(in *jsonAFD) AFD {
+	afd := AFD{
+		InitialState:     in.InitialState,
+		AcceptanceStates: lib.NewSet[AFDState](),
+		Transitions:      make(map[AFDState]map[AlphabetInput]AFDState),
+	}
+
+	for _, state := range in.AcceptanceStates {
+		afd.AcceptanceStates.Add(state)
+	}
+	for _, state := range in.States {
+		afd.Transitions[state] = make(map[AlphabetInput]AFDState)
+	}
+
+	for _, transition := range in.Transitions {
+		if _, found := afd.Transitions[transition.From]; !found {
+			afd.Transitions[transition.From] = make(map[AlphabetInput]AFDState)
+		}
+
+		var input AlphabetInput
+		if transition.Rule != nil {
+			input = CreateDummyToken(*transition.Rule)
+		} else {
+			input = CreateValueToken(*transition.Rune)
+		}
+		afd.Transitions[transition.From][input] = transition.To
+	}
+
+	return afd
+}
+
+// Writes the AFD as JSON, the format and version are written along the AFD.
+//
+// Only AFDs with rune and dummy transitions can be written, like the ones returned by ToAFD.
+func (self *AFD) WriteJSON(writer io.Writer) error {
+	out, err := toJSONAFD(self)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// Reads an AFD written by WriteJSON.
+//
+// Fails if the file was written with another version of the format.
+func ReadAFDJSON(reader io.Reader) (AFD, error) {
+	in := jsonAFD{}
+	if err := json.NewDecoder(reader).Decode(&in); err != nil {
+		return AFD{}, err
+	}
+
+	if in.Format != AFD_FORMAT {
+		return AFD{}, fmt.Errorf("expected a %s but found a %q", AFD_FORMAT, in.Format)
+	}
+	if in.Version != AFD_FORMAT_VERSION {
+		return AFD{}, fmt.Errorf("unsupported %s version %d, expected version %d", AFD_FORMAT, in.Version, AFD_FORMAT_VERSION)
+	}
+
+	for _, transition := range in.Transitions {
+		if (transition.Rune == nil) == (transition.Rule == nil) {
+			return AFD{}, fmt.Errorf("the transition from %s to %s must have either a rune or a rule", transition.From, transition.To)
+		}
+	}
+
+	return fromJSONAFD(&in), nil
+}
+
+// Writes the AFD in a compact binary format, the version is written along the AFD.
+//
+// Only AFDs with rune and dummy transitions can be written, like the ones returned by ToAFD.
+func (self *AFD) WriteBinary(writer io.Writer) error {
+	out, err := toJSONAFD(self)
+	if err != nil {
+		return err
+	}
+
+	w := lib.NewBinaryWriter(writer)
+	w.WriteHeader(AFD_MAGIC, AFD_FORMAT_VERSION)
+	w.WriteString(out.InitialState)
+	for _, states := range [][]AFDState{out.AcceptanceStates, out.States} {
+		w.WriteInt(len(states))
+		for _, state := range states {
+			w.WriteString(state)
+		}
+	}
+
+	w.WriteInt(len(out.Transitions))
+	for _, transition := range out.Transitions {
+		w.WriteString(transition.From)
+		w.WriteString(transition.To)
+		w.WriteBool(transition.Rule != nil)
+		if transition.Rule != nil {
+			w.WriteString(transition.Rule.Regex)
+			w.WriteString(transition.Rule.Code)
+			w.WriteInt(int(transition.Rule.Priority))
+		} else {
+			w.WriteInt(int(*transition.Rune))
+		}
+	}
+
+	return w.Flush()
+}
+
+// Reads an AFD written by WriteBinary.
+//
+// Fails if the file was written with another version of the format.
+func ReadAFDBinary(reader io.Reader) (AFD, error) {
+	r := lib.NewBinaryReader(reader)
+	version := r.ReadHeader(AFD_MAGIC)
+	if r.Err() != nil {
+		return AFD{}, r.Err()
+	}
+	if version != AFD_FORMAT_VERSION {
+		return AFD{}, fmt.Errorf("unsupported %s version %d, expected version %d", AFD_FORMAT, version, AFD_FORMAT_VERSION)
+	}
+
+	in := jsonAFD{InitialState: r.ReadString()}
+	for _, states := range []*[]AFDState{&in.AcceptanceStates, &in.States} {
+		count := r.ReadLength()
+		for range count {
+			*states = append(*states, r.ReadString())
+		}
+	}
+
+	count := r.ReadLength()
+	for range count {
+		transition := jsonTransition{From: r.ReadString(), To: r.ReadString()}
+		if r.ReadBool() {
+			transition.Rule = &DummyInfo{Regex: r.ReadString(), Code: r.ReadString(), Priority: uint(r.ReadInt())}
+		} else {
+			value := rune(r.ReadInt())
+			transition.Rune = &value
+		}
+		in.Transitions = append(in.Transitions, transition)
+	}
+
+	if r.Err() != nil {
+		return AFD{}, r.Err()
+	}
+	return fromJSONAFD(&in), nil
+}
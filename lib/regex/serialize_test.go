@@ -0,0 +1,78 @@
+package regex
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+func createExampleLexerAFD() AFD {
+	afd := CreateCanvasExampleAFD()
+	afd.Transitions["1"][CreateDummyToken(DummyInfo{Regex: "(a|b)*abb", Code: "return ABB", Priority: 1})] = "F"
+	afd.Transitions["2"][CreateValueToken('λ')] = "2"
+	afd.Transitions["F"] = map[AlphabetInput]AFDState{}
+	afd.AcceptanceStates = lib.Set[AFDState]{"F": struct{}{}}
+	return afd
+}
+
+func TestAFDRoundTrip(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(*AFD, *bytes.Buffer) error
+		read  func(*bytes.Buffer) (AFD, error)
+	}{
+		{"json", func(afd *AFD, b *bytes.Buffer) error { return afd.WriteJSON(b) }, func(b *bytes.Buffer) (AFD, error) { return ReadAFDJSON(b) }},
+		{"binary", func(afd *AFD, b *bytes.Buffer) error { return afd.WriteBinary(b) }, func(b *bytes.Buffer) (AFD, error) { return ReadAFDBinary(b) }},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			afd := createExampleLexerAFD()
+			b := bytes.Buffer{}
+			if err := format.write(&afd, &b); err != nil {
+				t.Fatal(err)
+			}
+			written := b.String()
+
+			read, err := format.read(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(afd, read) {
+				t.Fatalf("Expected:\n%s\nBut got:\n%s", afd.String(), read.String())
+			}
+
+			again := bytes.Buffer{}
+			if err := format.write(&read, &again); err != nil {
+				t.Fatal(err)
+			}
+			if again.String() != written {
+				t.Fatal("Writing the AFD again gave a different result")
+			}
+		})
+	}
+}
+
+func TestWriteAFDRejectsOperators(t *testing.T) {
+	afd := CreateCanvasExampleAFD()
+	afd.Transitions["0"][CreateOperatorToken(OR)] = "1"
+
+	if err := afd.WriteJSON(&bytes.Buffer{}); err == nil {
+		t.Fatal("Expected an error writing an operator transition")
+	}
+}
+
+func TestReadAFDRejectsOtherVersions(t *testing.T) {
+	_, err := ReadAFDJSON(strings.NewReader(`{"format": "uwu-afd", "version": 2}`))
+	if err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Fatalf("Expected a version error but got %v", err)
+	}
+
+	_, err = ReadAFDBinary(strings.NewReader("UWUT\x02"))
+	if err == nil {
+		t.Fatal("Expected an error reading another format")
+	}
+}
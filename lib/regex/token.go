@@ -47,13 +47,13 @@ func (self *Operator) String() string {
 // Serves to append extra metadata to a Regex pattern.
 type DummyInfo struct {
 	// Original string regex associated with this dummy.
-	Regex string
+	Regex string `json:"regex"`
 	// The code to execute once the Regex pattern is identified.
-	Code string
+	Code string `json:"code"`
 	// Used to break ties when parsing tokens!
 	//
 	// The lower the number the higher the priority!
-	Priority uint
+	Priority uint `json:"priority"`
 }
 
 func (info DummyInfo) String() string {
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
-	// "os"
+	"os"
+	"path/filepath"
+	"strings"
 
-	// "github.com/Jose-Prince/UWUCompiler/lib"
+	"github.com/Jose-Prince/UWUCompiler/lib"
 	"github.com/Jose-Prince/UWUCompiler/lib/grammar"
 	regx "github.com/Jose-Prince/UWUCompiler/lib/regex"
 	// parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
@@ -16,43 +20,81 @@ type programParams struct {
 	LexFilePath     string
 	GrammarFilePath string
 	OutGoPath       string
+	PackageName     string
+	DriverPath      string
+	AllowConflicts  bool
+	BuildTree       bool
+	TablePath       string
+	DFAPath         string
+	Mode            string
+	LALRGraphPath   string
+	DFAGraphPath    string
+	ReportPath      string
+	// Grammar transformations applied before generating the parser
+	RemoveLeftRecursion bool
+	LeftFactor          bool
+	GrammarOutPath      string
 }
 
+// The kinds of parsing tables that can be generated
+const (
+	LR1_MODE  = "lr1"
+	LALR_MODE = "lalr"
+	SLR_MODE  = "slr"
+	LL1_MODE  = "ll1"
+)
+
 func parseProgramParams() programParams {
 	params := programParams{}
 
 	flag.StringVar(&params.LexFilePath, "lexPath", "tokens.lex", "The path to the .lex file with the tokens definitions!")
 	flag.StringVar(&params.GrammarFilePath, "grammarPath", "grammar.yal", "The path to the .yal file with the grammar definition!")
 	flag.StringVar(&params.OutGoPath, "outPath", "out.go", "The path where the generated code should be outputted!")
+	flag.StringVar(&params.PackageName, "package", "main", "The name of the package of the generated lexer and parser!")
+	flag.StringVar(&params.DriverPath, "driverPath", "", "The path where a main program that runs the generated parser should be outputted! Only valid with -package main, no driver is written without it.")
+	flag.BoolVar(&params.AllowConflicts, "allowConflicts", false, "Generate the parser even if the grammar has shift/reduce or reduce/reduce conflicts!")
+	flag.BoolVar(&params.BuildTree, "cst", false, "Generate a ParseTree function that returns the concrete syntax tree of the source!")
+	flag.StringVar(&params.TablePath, "saveTable", "", "The path where the parsing table should be saved! It's saved as JSON if the path ends with .json, otherwise in a binary format.")
+	flag.StringVar(&params.DFAPath, "saveDFA", "", "The path where the lexer AFD should be saved! It's saved as JSON if the path ends with .json, otherwise in a binary format.")
+	flag.StringVar(&params.Mode, "mode", LALR_MODE, "The kind of parsing table to generate! Can be lr1, lalr, slr or ll1.")
+	flag.StringVar(&params.LALRGraphPath, "dumpLALR", "", "The path where the automata of the parser should be drawn! It's written as an HTML page if the path ends with .html, otherwise as a graphviz DOT file.")
+	flag.StringVar(&params.ReportPath, "report", "", "The path where a report of the states, items and conflicts of the parser should be written, like the .output file of bison -v!")
+	flag.BoolVar(&params.RemoveLeftRecursion, "removeLeftRecursion", false, "Rewrite the grammar without left recursion before generating the parser! Useful with -mode ll1.")
+	flag.BoolVar(&params.LeftFactor, "leftFactor", false, "Rewrite the grammar so no two productions of a nonterminal start with the same symbols before generating the parser! Useful with -mode ll1.")
+	flag.StringVar(&params.GrammarOutPath, "writeGrammar", "", "The path where the grammar used to generate the parser should be written as a .yal file, after -removeLeftRecursion and -leftFactor!")
+	flag.StringVar(&params.DFAGraphPath, "dumpDFA", "", "The path where the lexer AFD should be drawn! It's written as an HTML page if the path ends with .html, otherwise as a graphviz DOT file.")
 
 	flag.Parse()
+
 	return params
 }
 
 type CompilerFileInfo struct {
-	LexInfo      LexFileData
-	LexAFD       regx.AFD
+	LexInfo LexFileData
+	// The AFD of every mode of LexInfo.Modes, in the same order
+	LexAFDs      []regx.AFD
 	ParsingTable grammar.ParsingTable
+	// The predictive table used instead of ParsingTable on ll1 mode
+	LLTable lib.Optional[grammar.LLParsingTable]
+	// Generate ParseTree to build the concrete syntax tree
+	BuildTree bool
 }
 
-func main() {
-	params := parseProgramParams()
-
-	fmt.Println("Lex file to use:", params.LexFilePath)
-	fmt.Println("Grammar file to use:", params.GrammarFilePath)
-	fmt.Println("Output file will be:", params.OutGoPath)
-
-	lexFileData, err := LexParser(params.LexFilePath)
-	if err != nil {
-		panic(err)
+// The grammar the parser is generated from.
+func (self *CompilerFileInfo) Grammar() *grammar.Grammar {
+	if self.LLTable.HasValue() {
+		table := self.LLTable.GetValue()
+		return &table.Original
 	}
-	fmt.Println("The lex file data is:", lexFileData.String())
 
-	// Combine all regexes into a single regex
+	return &self.ParsingTable.Original
+}
+
+// Combines the regexes of the rules into a single one and builds its minimized AFD,
+// where each rule ends on the dummy with its code.
+func buildLexAFD(rules []LexFileRule) regx.AFD {
 	infix := []regx.RX_Token{}
-	i := 0
-	ruleCount := len(lexFileData.Rules)
-	for _, rule := range lexFileData.Rules {
+	for i, rule := range rules {
 		fmt.Printf("Converting %s...\n", rule.Regex)
 		// What we want is to have something like: ((<REGEX>).(DUMMY))
 		infix = append(infix, regx.CreateOperatorToken(regx.LEFT_PAREN))
@@ -66,10 +108,9 @@ func main() {
 
 		infix = append(infix, regx.CreateOperatorToken(regx.RIGHT_PAREN))
 
-		if i+1 < ruleCount {
+		if i+1 < len(rules) {
 			infix = append(infix, regx.CreateOperatorToken(regx.OR))
 		}
-		i++
 	}
 	fmt.Println("The Infix expression is:\n", regx.TokenStreamToString(infix))
 
@@ -86,10 +127,300 @@ func main() {
 	afd := table.ToAFD()
 	fmt.Println("The AFD is:", afd.String())
 
-	// TODO Parse yal fil
+	stateCount := len(afd.Transitions)
+	afd = afd.Minimize()
+	fmt.Printf("The minimized AFD has %d states instead of %d: %s\n", len(afd.Transitions), stateCount, afd.String())
+	return afd
+}
+
+// The path where the AFD of a lexer mode is saved, the one of LEX_INITIAL_MODE uses path
+// and the others get the mode as suffix like `dfa_STRING.json`.
+func lexModePath(path string, mode string) string {
+	if mode == LEX_INITIAL_MODE {
+		return path
+	}
+
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + mode + ext
+}
+
+// Prints every diagnostic on its own line like compilers do and exits.
+func exitWithDiagnostics(err error) {
+	var diagnostics lib.Diagnostics
+	if errors.As(err, &diagnostics) {
+		for _, diagnostic := range diagnostics {
+			fmt.Fprintln(os.Stderr, diagnostic.String())
+		}
+		log.Fatalf("Found %d problems!", len(diagnostics))
+	}
+
+	log.Fatalf("Failed to read file: %v", err)
+}
+
+// Saves a table as JSON if the path ends with .json, otherwise in its binary format.
+func saveTable(path string, writeJSON func(io.Writer) error, writeBinary func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if filepath.Ext(path) == ".json" {
+		return writeJSON(f)
+	}
+	return writeBinary(f)
+}
+
+// Draws a graph as an HTML page if the path ends with .html, otherwise as a DOT file.
+func dumpGraph(path string, graph lib.Graph) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if ext := filepath.Ext(path); ext == ".html" || ext == ".htm" {
+		return graph.WriteHTML(f)
+	}
+	return graph.WriteDOT(f)
+}
+
+func writeReport(path string, auto *grammar.Automata, table *grammar.ParsingTable, conflicts []grammar.Conflict) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return auto.WriteReport(f, table, conflicts)
+}
+
+// Applies the transformations asked for on the params and writes the result if -writeGrammar is given.
+func transformGrammar(params programParams, g grammar.Grammar) grammar.Grammar {
+	transformed := false
+	apply := func(name string, transform func(*grammar.Grammar) grammar.TransformedGrammar) {
+		fmt.Println(name + "...")
+		result := transform(&g)
+		dropped := lib.NewSet[int]()
+		for i, rule := range result.Grammar.Rules {
+			origin := result.OriginalRules[i]
+			if origin != -1 && g.Rules[origin].SemanticAction != "" && rule.SemanticAction == "" && dropped.Add(origin) {
+				fmt.Fprintf(os.Stderr, "The semantic action of rule %d of %s was dropped since the rule was rewritten!\n", origin, g.Rules[origin].Head.Symbol())
+			}
+		}
+		g = result.Grammar
+		transformed = true
+	}
+
+	if params.RemoveLeftRecursion {
+		apply("Removing left recursion", (*grammar.Grammar).EliminateLeftRecursion)
+	}
+	if params.LeftFactor {
+		apply("Left factoring", (*grammar.Grammar).LeftFactor)
+	}
+	if transformed {
+		g.PrintGrammar()
+	}
+
+	if params.GrammarOutPath != "" {
+		fmt.Println("Writing grammar to", params.GrammarOutPath)
+		f, err := os.Create(params.GrammarOutPath)
+		if err == nil {
+			err = g.WriteYal(f)
+			f.Close()
+		}
+		if err != nil {
+			log.Fatalf("An error ocurred writing the grammar! %v", err)
+		}
+	}
+
+	return g
+}
+
+// Generates the parsing table of the lr1, lalr or slr modes,
+// exits if the grammar has conflicts and they aren't allowed.
+func generateLRTable(params programParams, g *grammar.Grammar) grammar.ParsingTable {
+	initialRule := grammar.GrammarRule{Head: grammar.NewNonTerminalToken("S'"), Production: []grammar.GrammarToken{g.InitialSimbol}}
+	// extendedGrammar := grammar.Grammar{
+	// 	InitialSimbol: g.InitialSimbol,
+	// 	Rules:         append(g.Rules),
+	// 	Terminals:     g.Terminals,
+	// 	NonTerminals:  g.NonTerminals,
+	// 	TokenIds:      g.TokenIds,
+	// }
+
+	fmt.Println("Creating automata...")
+	var lalr grammar.Automata
+	if params.Mode == LR1_MODE {
+		lalr = grammar.InitializeAutomata(initialRule, *g)
+	} else {
+		// The SLR table only needs the LR(0) states, which are the same ones of the LALR automata
+		lalr = grammar.InitializeLALRAutomata(initialRule, *g)
+	}
+
+	fmt.Printf("Generating %s parsing table...\n", params.Mode)
+	var parsingTable grammar.ParsingTable
+	var conflicts []grammar.Conflict
+	if params.Mode == SLR_MODE {
+		parsingTable, conflicts = lalr.GenerateSLRParsingTable(g)
+	} else {
+		parsingTable, conflicts = lalr.GenerateParsingTable(g)
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Println("Searching counterexamples for the conflicts...")
+		lalr.FindCounterexamples(g, conflicts)
+	}
+
+	// Drawn before checking the conflicts, since it's the easiest way of understanding them
+	if params.LALRGraphPath != "" {
+		fmt.Println("Drawing automata on", params.LALRGraphPath)
+		name := fmt.Sprintf("%s automata of %s", strings.ToUpper(params.Mode), params.GrammarFilePath)
+		err := dumpGraph(params.LALRGraphPath, lalr.ToGraph(name, conflicts))
+		if err != nil {
+			log.Fatalf("An error ocurred drawing the automata! %v", err)
+		}
+	}
+
+	if params.ReportPath != "" {
+		fmt.Println("Writing report to", params.ReportPath)
+		err := writeReport(params.ReportPath, &lalr, &parsingTable, conflicts)
+		if err != nil {
+			log.Fatalf("An error ocurred writing the report! %v", err)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Fprintf(os.Stderr, "The grammar has %d conflicts on %s mode:\n", len(conflicts), params.Mode)
+		for _, conflict := range conflicts {
+			fmt.Fprintln(os.Stderr, conflict.String())
+		}
+
+		if params.Mode != LR1_MODE {
+			fmt.Fprintln(os.Stderr, "Use -mode=lr1 to check if they're caused by merging states.")
+		}
+
+		if !params.AllowConflicts {
+			log.Fatalf("Refusing to generate a parser for an ambiguous grammar! Use -allowConflicts to generate it anyway.")
+		}
+	}
+
+	if params.TablePath != "" {
+		fmt.Println("Saving parsing table to", params.TablePath)
+		table := parsingTable.ToParserTable()
+		err := saveTable(params.TablePath, table.WriteJSON, table.WriteBinary)
+		if err != nil {
+			log.Fatalf("An error ocurred saving the parsing table! %v", err)
+		}
+	}
+
+	return parsingTable
+}
+
+// Generates the predictive table of the ll1 mode,
+// exits if the grammar has conflicts and they aren't allowed.
+func generateLLTable(params programParams, g *grammar.Grammar) grammar.LLParsingTable {
+	if params.LALRGraphPath != "" || params.ReportPath != "" || params.TablePath != "" {
+		fmt.Fprintln(os.Stderr, "The ll1 mode has no automata, -dumpLALR, -report and -saveTable are ignored.")
+	}
+	if g.Terminals.Contains(grammar.NewErrorToken()) {
+		fmt.Fprintln(os.Stderr, "The ll1 parser doesn't recover from syntax errors, the rules with the error token will never match it.")
+	}
+
+	fmt.Println("Generating ll1 parsing table...")
+	table, conflicts := grammar.GenerateLLParsingTable(g)
+
+	if len(conflicts) > 0 {
+		grammar.SortLLConflicts(conflicts)
+		fmt.Fprintf(os.Stderr, "The grammar has %d conflicts on %s mode:\n", len(conflicts), params.Mode)
+		for _, conflict := range conflicts {
+			fmt.Fprintln(os.Stderr, conflict.String())
+		}
+		fmt.Fprintln(os.Stderr, "Left recursive rules and rules that start with the same symbols can't be parsed by an LL(1) parser.")
+
+		if !params.AllowConflicts {
+			log.Fatalf("Refusing to generate a parser for a grammar that isn't LL(1)! Use -allowConflicts to generate it anyway.")
+		}
+	}
+
+	return table
+}
+
+func main() {
+	params := parseProgramParams()
+
+	fmt.Println("Lex file to use:", params.LexFilePath)
+	fmt.Println("Grammar file to use:", params.GrammarFilePath)
+	fmt.Println("Parsing table mode:", params.Mode)
+	if params.Mode != LR1_MODE && params.Mode != LALR_MODE && params.Mode != SLR_MODE && params.Mode != LL1_MODE {
+		log.Fatalf("Unknown mode %s! It must be one of %s, %s, %s or %s.", params.Mode, LR1_MODE, LALR_MODE, SLR_MODE, LL1_MODE)
+	}
+	fmt.Println("Output file will be:", params.OutGoPath)
+	if params.DriverPath != "" {
+		if params.PackageName != "main" {
+			log.Fatalf("A driver can only be generated for the main package, but the package is %s!", params.PackageName)
+		}
+		fmt.Println("Driver file will be:", params.DriverPath)
+	}
+
+	lexFileData, err := LexParser(params.LexFilePath)
+	if err != nil {
+		exitWithDiagnostics(err)
+	}
+
 	g, err := grammar.ParseYalFile(params.GrammarFilePath)
 	if err != nil {
-		log.Panicf("Failed to parse grammar file: %s", err)
+		exitWithDiagnostics(err)
+	}
+
+	fmt.Println("Validating grammar...")
+	hasErrors := false
+	for _, issue := range g.Validate() {
+		if issue.Position.HasValue() {
+			position := issue.Position.GetValue()
+			diagnostic := lib.Diagnostic{File: params.GrammarFilePath, Line: position.Line, Col: position.Col, Message: issue.String()}
+			fmt.Fprintln(os.Stderr, diagnostic.String())
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", params.GrammarFilePath, issue.String())
+		}
+		hasErrors = hasErrors || issue.IsError()
+	}
+	if hasErrors {
+		log.Fatalf("The grammar can't be used to generate a parser!")
+	}
+
+	g = transformGrammar(params, g)
+
+	// The grammar is read before building the lexer since its literals add rules to it
+	err = addLiteralRules(params.LexFilePath, &lexFileData, &g)
+	if err != nil {
+		exitWithDiagnostics(err)
+	}
+	err = checkLexTokens(params.LexFilePath, &lexFileData, &g)
+	if err != nil {
+		exitWithDiagnostics(err)
+	}
+
+	fmt.Println("The lex file data is:", lexFileData.String())
+
+	// Every mode has its own AFD with only the rules that can match on it
+	afds := []regx.AFD{}
+	for _, mode := range lexFileData.Modes {
+		if len(lexFileData.Modes) > 1 {
+			fmt.Println("Building the AFD of the lexer mode", mode)
+		}
+		afds = append(afds, buildLexAFD(lexFileData.RulesOf(mode)))
+	}
+
+	if params.DFAGraphPath != "" {
+		for i, mode := range lexFileData.Modes {
+			path := lexModePath(params.DFAGraphPath, mode)
+			fmt.Println("Drawing lexer AFD on", path)
+			err = dumpGraph(path, afds[i].ToGraph(fmt.Sprintf("Lexer AFD of %s on mode %s", params.LexFilePath, mode)))
+			if err != nil {
+				log.Fatalf("An error ocurred drawing the lexer AFD! %v", err)
+			}
+		}
 	}
 
 	// parsingTable := grammar.ParsingTable{
@@ -162,41 +493,39 @@ func main() {
 	// 	},
 	// }
 
-	initialRule := grammar.GrammarRule{Head: grammar.NewNonTerminalToken("S'"), Production: []grammar.GrammarToken{g.InitialSimbol}}
-	// extendedGrammar := grammar.Grammar{
-	// 	InitialSimbol: g.InitialSimbol,
-	// 	Rules:         append(g.Rules),
-	// 	Terminals:     g.Terminals,
-	// 	NonTerminals:  g.NonTerminals,
-	// 	TokenIds:      g.TokenIds,
-	// }
-
-	fmt.Println("Creating automata...")
-	// grammar.InitializeAutomata(initialRule, g)
-	lalr := grammar.InitializeAutomata(initialRule, g)
-
-	fmt.Println("Simplifying states...")
-	lalr.SimplifyStates()
-
-	//
-	// fmt.Println("Generating LARLR HTML...")
-	// err = GenerateHTML(lalr, "lalr_automata.html")
-	// if err != nil {
-	// 	panic(err)
-	// }
-	// fmt.Println("LALR HTML generated")
-	//
-	fmt.Println("Generating Parsing table from LALR AFD...")
-	parsingTable := lalr.GenerateParsingTable(&g)
-
 	info := CompilerFileInfo{
-		LexInfo:      lexFileData,
-		LexAFD:       afd,
-		ParsingTable: parsingTable,
+		LexInfo:   lexFileData,
+		LexAFDs:   afds,
+		BuildTree: params.BuildTree,
+	}
+	if params.Mode == LL1_MODE {
+		info.LLTable = lib.CreateValue(generateLLTable(params, &g))
+	} else {
+		info.ParsingTable = generateLRTable(params, &g)
+	}
+
+	if params.DFAPath != "" {
+		for i, mode := range lexFileData.Modes {
+			path := lexModePath(params.DFAPath, mode)
+			fmt.Println("Saving lexer AFD to", path)
+			err = saveTable(path, afds[i].WriteJSON, afds[i].WriteBinary)
+			if err != nil {
+				log.Fatalf("An error ocurred saving the lexer AFD! %v", err)
+			}
+		}
 	}
+
 	fmt.Println("Writing final compiler source code...")
-	err = WriteCompilerFile(params.OutGoPath, &info)
+	err = WriteCompilerFile(params.OutGoPath, params.PackageName, &info)
 	if err != nil {
 		log.Fatalf("An error ocurred writing final lexer file! %v", err)
 	}
+
+	if params.DriverPath != "" {
+		fmt.Println("Writing driver source code...")
+		err = WriteDriverFile(params.DriverPath, &info)
+		if err != nil {
+			log.Fatalf("An error ocurred writing the driver file! %v", err)
+		}
+	}
 }
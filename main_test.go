@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib/regex"
+	parsertypes "github.com/Jose-Prince/UWUCompiler/parserTypes"
+)
+
+// Two runs on the same inputs must save the same artifacts,
+// and reading them back must write them again without changes.
+func TestSavedArtifactsAreReproducible(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Building the generator is slow")
+	}
+
+	dir := t.TempDir()
+	generator := filepath.Join(dir, "generator")
+	build := exec.Command("go", "build", "-o", generator, ".")
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build the generator: %v\n%s", err, output)
+	}
+
+	// Each run saves the artifacts on its own directory
+	generate := func(run string, extension string) string {
+		runDir := filepath.Join(dir, run)
+		if err := os.Mkdir(runDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(generator,
+			"-lexPath", "example/medium/tokens.lex",
+			"-grammarPath", "example/medium/grammar.yal",
+			"-outPath", filepath.Join(runDir, "out.go"),
+			"-saveTable", filepath.Join(runDir, "table"+extension),
+			"-saveDFA", filepath.Join(runDir, "dfa"+extension),
+		)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("Failed to generate the code: %v\n%s", err, output)
+		}
+		return runDir
+	}
+	readFile := func(path string) []byte {
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return contents
+	}
+
+	formats := []struct {
+		name      string
+		extension string
+		// Reads the table or the dfa and writes it again
+		rewriteTable func(contents []byte, b *bytes.Buffer) error
+		rewriteDFA   func(contents []byte, b *bytes.Buffer) error
+	}{
+		{"json", ".json",
+			func(contents []byte, b *bytes.Buffer) error {
+				table, err := parsertypes.ReadParsingTableJSON(bytes.NewReader(contents))
+				if err != nil {
+					return err
+				}
+				return table.WriteJSON(b)
+			},
+			func(contents []byte, b *bytes.Buffer) error {
+				afd, err := regex.ReadAFDJSON(bytes.NewReader(contents))
+				if err != nil {
+					return err
+				}
+				return afd.WriteJSON(b)
+			},
+		},
+		{"binary", ".bin",
+			func(contents []byte, b *bytes.Buffer) error {
+				table, err := parsertypes.ReadParsingTableBinary(bytes.NewReader(contents))
+				if err != nil {
+					return err
+				}
+				return table.WriteBinary(b)
+			},
+			func(contents []byte, b *bytes.Buffer) error {
+				afd, err := regex.ReadAFDBinary(bytes.NewReader(contents))
+				if err != nil {
+					return err
+				}
+				return afd.WriteBinary(b)
+			},
+		},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			first := generate(format.name+"-first", format.extension)
+			second := generate(format.name+"-second", format.extension)
+
+			for _, file := range []string{"out.go", "table" + format.extension, "dfa" + format.extension} {
+				if !bytes.Equal(readFile(filepath.Join(first, file)), readFile(filepath.Join(second, file))) {
+					t.Errorf("The two runs wrote a different %s", file)
+				}
+			}
+
+			rewrites := map[string]func([]byte, *bytes.Buffer) error{"table": format.rewriteTable, "dfa": format.rewriteDFA}
+			for name, rewrite := range rewrites {
+				contents := readFile(filepath.Join(first, name+format.extension))
+				b := bytes.Buffer{}
+				if err := rewrite(contents, &b); err != nil {
+					t.Fatalf("Failed to rewrite the %s: %v", name, err)
+				}
+				if !bytes.Equal(contents, b.Bytes()) {
+					t.Errorf("Reading the %s back and writing it again changed it", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package parsertypes
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The results of a LexerStep besides the token types
+const (
+	// The input can't continue the current token
+	UNRECOGNIZABLE int = -1
+	// The input continues the current token, but it isn't complete yet
+	GIVE_NEXT int = -2
+	// The token is complete but it must be skipped, like whitespace
+	IGNORE int = -3
+)
+
+// How many bytes the lexer asks the reader for at once
+const LEXER_CHUNK_SIZE int = 4096
+
+// Moves a lexer AFD from state with input, the states are numbered from 0.
+//
+// Returns the type of the token accepted after the input,
+// or UNRECOGNIZABLE, GIVE_NEXT or IGNORE.
+type LexerStep func(state *int, input rune) int
+
+// How the mode of a lexer changes once the token of a rule is accepted, like the start conditions of flex.
+type LexerModeChangeType int
+
+const (
+	// Replaces the current mode, like BEGIN(MODE)
+	MODE_BEGIN LexerModeChangeType = iota
+	// Saves the current mode so MODE_POP can come back to it, like PUSH(MODE)
+	MODE_PUSH
+	// Comes back to the mode saved by the last MODE_PUSH, like POP()
+	MODE_POP
+)
+
+func (self LexerModeChangeType) String() string {
+	switch self {
+	case MODE_BEGIN:
+		return "BEGIN"
+	case MODE_PUSH:
+		return "PUSH"
+	case MODE_POP:
+		return "POP"
+	}
+
+	return "INVALID"
+}
+
+type LexerModeChange struct {
+	Type LexerModeChangeType
+	// The mode to change to, unused by MODE_POP
+	Mode int
+}
+
+// The AFD of the rules a lexer uses on a mode.
+type LexerMode struct {
+	Step         LexerStep
+	InitialState int
+	// The mode changes of the rules by the state of the AFD after accepting their tokens
+	Changes map[int]LexerModeChange
+}
+
+type Token struct {
+	// When does this token start in the contents of the source file, in bytes
+	Start int
+	// Where does this token end in the contents of the source file (exclusive), in bytes
+	End int
+	// The line and column where this token starts, both start counting from 1 and columns count runes
+	Line int
+	Col  int
+	// The text this token matched
+	Text string
+	// The type of the token that it found
+	Type int
+}
+
+func (self *Token) String() string {
+	b := strings.Builder{}
+	b.WriteString("{ ")
+	b.WriteString("Start = ")
+	b.WriteString(strconv.Itoa(self.Start))
+	b.WriteString(", End = ")
+	b.WriteString(strconv.Itoa(self.End))
+	b.WriteString(", Type = ")
+	b.WriteString(strconv.Itoa(self.Type))
+	b.WriteString(" }")
+	return b.String()
+}
+
+// Anything that can supply the tokens to parse, like a Lexer.
+type TokenSource interface {
+	Next() (Token, error)
+}
+
+// Returned when the lexer finds a character that doesn't start any token.
+type LexError struct {
+	// The position of the character in the contents of the source file, in bytes
+	Offset int
+	Line   int
+	Col    int
+	Char   rune
+}
+
+func (self *LexError) Error() string {
+	return fmt.Sprintf("%d:%d: unexpected character %q", self.Line, self.Col, self.Char)
+}
+
+// Splits the contents of a source into tokens.
+//
+// The source is read in chunks, only the bytes that don't belong to a token yet are kept in memory.
+type Lexer struct {
+	modes    []LexerMode
+	endToken int
+	// The current mode and the ones saved with MODE_PUSH
+	mode      int
+	modeStack Stack[int]
+
+	reader io.Reader
+	chunk  []byte
+	// The bytes read from the reader that don't belong to a token yet
+	buffer []byte
+	// The error returned by the reader, io.EOF once all the source was read
+	readErr error
+
+	// Where the next token starts in the contents of the source
+	offset int
+	line   int
+	col    int
+	// Once the reader fails the lexer keeps returning its error
+	err error
+}
+
+// Creates a lexer that tokenizes all the contents of reader,
+// moving from initialState with step and returning endToken once the source is consumed.
+func NewLexer(reader io.Reader, initialState int, step LexerStep, endToken int) *Lexer {
+	return NewModalLexer(reader, []LexerMode{{Step: step, InitialState: initialState}}, endToken)
+}
+
+// Creates a lexer that tokenizes all the contents of reader starting on the first of modes,
+// the AFD of the current mode is the only one used to find the next token.
+func NewModalLexer(reader io.Reader, modes []LexerMode, endToken int) *Lexer {
+	return &Lexer{
+		modes:    modes,
+		endToken: endToken,
+		reader:   reader,
+		chunk:    make([]byte, LEXER_CHUNK_SIZE),
+		line:     1,
+		col:      1,
+	}
+}
+
+// The index of the mode the next token is read on.
+func (self *Lexer) Mode() int {
+	return self.mode
+}
+
+// Applies the change of mode of a rule, popping without any saved mode goes back to the first one.
+func (self *Lexer) changeMode(change LexerModeChange) {
+	switch change.Type {
+	case MODE_BEGIN:
+		self.mode = change.Mode
+	case MODE_PUSH:
+		self.modeStack.Push(self.mode)
+		self.mode = change.Mode
+	case MODE_POP:
+		saved := self.modeStack.Pop()
+		if saved.HasValue() {
+			self.mode = saved.GetValue()
+		} else {
+			self.mode = 0
+		}
+	}
+}
+
+// Reads from the source until the buffer has a complete rune starting at pos,
+// or the reader doesn't have anything else to give.
+func (self *Lexer) fillRune(pos int) {
+	for self.readErr == nil && !utf8.FullRune(self.buffer[pos:]) {
+		read, err := self.reader.Read(self.chunk)
+		self.buffer = append(self.buffer, self.chunk[:read]...)
+		self.readErr = err
+	}
+}
+
+// Moves the start of the next token after text, keeping track of the current line and column.
+func (self *Lexer) advance(text string) {
+	self.offset += len(text)
+	self.buffer = self.buffer[len(text):]
+	for _, r := range text {
+		if r == '\n' {
+			self.line++
+			self.col = 1
+		} else {
+			self.col++
+		}
+	}
+}
+
+// Returns the next token of the source, the longest match always wins.
+//
+// The lexer keeps feeding runes to the AFD until it can't continue,
+// then backtracks to the last position where a token was accepted.
+// If no token starts on the current character a LexError is returned and the character is skipped,
+// so the lexer can keep going.
+// Once all the source is consumed a token of the end type is returned.
+func (self *Lexer) Next() (Token, error) {
+	if self.err != nil {
+		return Token{}, self.err
+	}
+
+	for {
+		mode := self.modes[self.mode]
+		afdState := mode.InitialState
+		tokenType := UNRECOGNIZABLE
+		tokenEnd := -1
+		// The state the AFD was in when the token was accepted, it tells the rule of the token
+		acceptedState := -1
+		for pos := 0; ; {
+			self.fillRune(pos)
+			if pos >= len(self.buffer) {
+				break
+			}
+
+			input, size := utf8.DecodeRune(self.buffer[pos:])
+			parsingResult := mode.Step(&afdState, input)
+			if parsingResult == UNRECOGNIZABLE {
+				break
+			}
+
+			pos += size
+			if parsingResult != GIVE_NEXT {
+				tokenType = parsingResult
+				tokenEnd = pos
+				acceptedState = afdState
+			}
+		}
+
+		if self.readErr != nil && self.readErr != io.EOF {
+			self.err = self.readErr
+			return Token{}, self.err
+		}
+
+		if len(self.buffer) == 0 {
+			return Token{Start: self.offset, End: self.offset, Line: self.line, Col: self.col, Type: self.endToken}, nil
+		}
+
+		if tokenEnd == -1 {
+			char, size := utf8.DecodeRune(self.buffer)
+			err := &LexError{Offset: self.offset, Line: self.line, Col: self.col, Char: char}
+			self.advance(string(self.buffer[:size]))
+			return Token{}, err
+		}
+
+		token := Token{
+			Start: self.offset,
+			End:   self.offset + tokenEnd,
+			Line:  self.line,
+			Col:   self.col,
+			Text:  string(self.buffer[:tokenEnd]),
+			Type:  tokenType,
+		}
+		self.advance(token.Text)
+		if change, found := mode.Changes[acceptedState]; found {
+			self.changeMode(change)
+		}
+		if tokenType != IGNORE {
+			return token, nil
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package parsertypes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	testNumber int = 0
+	testLambda int = 1
+	testEnd    int = 2
+)
+
+// The states of testLexerStep
+const (
+	testInitialState = iota
+	testNumberState
+	testLambdaState
+	testSpaceState
+)
+
+// Numbers, lambdas and spaces to skip
+func testLexerStep(state *int, input rune) int {
+	switch {
+	case input >= '0' && input <= '9' && (*state == testInitialState || *state == testNumberState):
+		*state = testNumberState
+		return testNumber
+	case input == 'λ' && *state == testInitialState:
+		*state = testLambdaState
+		return testLambda
+	case input == ' ' && (*state == testInitialState || *state == testSpaceState):
+		*state = testSpaceState
+		return IGNORE
+	}
+
+	return UNRECOGNIZABLE
+}
+
+func TestLexer(t *testing.T) {
+	// The reader gives a byte at a time, so the runes are split between reads
+	lexer := NewLexer(iotest.OneByteReader(strings.NewReader("12 λ\n λ34?5")), testInitialState, testLexerStep, testEnd)
+
+	expected := []Token{
+		{Start: 0, End: 2, Line: 1, Col: 1, Text: "12", Type: testNumber},
+		{Start: 3, End: 5, Line: 1, Col: 4, Text: "λ", Type: testLambda},
+	}
+	for _, want := range expected {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != want {
+			t.Fatalf("Expected %v but got %v", want, token)
+		}
+	}
+
+	// A new line can't start any token
+	_, err := lexer.Next()
+	var lexErr *LexError
+	if !errors.As(err, &lexErr) || lexErr.Char != '\n' || lexErr.Offset != 5 {
+		t.Fatalf("Expected an error on the new line but got %v", err)
+	}
+
+	expected = []Token{
+		{Start: 7, End: 9, Line: 2, Col: 2, Text: "λ", Type: testLambda},
+		{Start: 9, End: 11, Line: 2, Col: 3, Text: "34", Type: testNumber},
+	}
+	for _, want := range expected {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != want {
+			t.Fatalf("Expected %v but got %v", want, token)
+		}
+	}
+
+	_, err = lexer.Next()
+	if err == nil || err.Error() != "2:5: unexpected character '?'" {
+		t.Fatalf("Expected an error on ? but got %v", err)
+	}
+
+	for _, want := range []Token{{Start: 12, End: 13, Line: 2, Col: 6, Text: "5"}, {Start: 13, End: 13, Line: 2, Col: 7, Type: testEnd}} {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != want {
+			t.Fatalf("Expected %v but got %v", want, token)
+		}
+	}
+}
+
+func TestLexerReadError(t *testing.T) {
+	readErr := errors.New("broken source")
+	lexer := NewLexer(iotest.ErrReader(readErr), testInitialState, testLexerStep, testEnd)
+
+	for range 2 {
+		if _, err := lexer.Next(); err != readErr {
+			t.Fatalf("Expected the error of the reader but got %v", err)
+		}
+	}
+}
+
+// Text between quotes, read on its own mode
+func testStringStep(state *int, input rune) int {
+	switch {
+	case input == '"' && *state == testInitialState:
+		*state = testSpaceState
+		return IGNORE
+	case input != '"' && (*state == testInitialState || *state == testLambdaState):
+		*state = testLambdaState
+		return testLambda
+	}
+
+	return UNRECOGNIZABLE
+}
+
+// Numbers and quotes that start a string
+func testCodeStep(state *int, input rune) int {
+	if input == '"' && *state == testInitialState {
+		*state = testSpaceState
+		return IGNORE
+	}
+
+	return testLexerStep(state, input)
+}
+
+func TestModalLexer(t *testing.T) {
+	modes := []LexerMode{
+		{Step: testCodeStep, InitialState: testInitialState, Changes: map[int]LexerModeChange{testSpaceState: {Type: MODE_PUSH, Mode: 1}}},
+		{Step: testStringStep, InitialState: testInitialState, Changes: map[int]LexerModeChange{testSpaceState: {Type: MODE_POP}}},
+	}
+	lexer := NewModalLexer(strings.NewReader(`12"3 λ"4`), modes, testEnd)
+
+	expected := []struct {
+		token Token
+		mode  int
+	}{
+		{Token{Start: 0, End: 2, Line: 1, Col: 1, Text: "12", Type: testNumber}, 0},
+		// The quote is skipped but changes the mode
+		{Token{Start: 3, End: 7, Line: 1, Col: 4, Text: "3 λ", Type: testLambda}, 1},
+		{Token{Start: 8, End: 9, Line: 1, Col: 8, Text: "4", Type: testNumber}, 0},
+		{Token{Start: 9, End: 9, Line: 1, Col: 9, Type: testEnd}, 0},
+	}
+	for _, want := range expected {
+		token, err := lexer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != want.token {
+			t.Fatalf("Expected %v but got %v", want.token, token)
+		}
+		if lexer.Mode() != want.mode {
+			t.Errorf("Expected the mode %d after %q but got %d", want.mode, token.Text, lexer.Mode())
+		}
+	}
+}
+
+func TestLexerPopWithoutModes(t *testing.T) {
+	lexer := NewModalLexer(strings.NewReader(""), nil, testEnd)
+	lexer.changeMode(LexerModeChange{Type: MODE_BEGIN, Mode: 2})
+	lexer.changeMode(LexerModeChange{Type: MODE_PUSH, Mode: 1})
+	lexer.changeMode(LexerModeChange{Type: MODE_POP})
+	if lexer.Mode() != 2 {
+		t.Errorf("Expected to come back to the mode 2 but got %d", lexer.Mode())
+	}
+
+	lexer.changeMode(LexerModeChange{Type: MODE_POP})
+	if lexer.Mode() != 0 {
+		t.Errorf("Expected to go back to the first mode without saved modes but got %d", lexer.Mode())
+	}
+}
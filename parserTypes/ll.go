@@ -0,0 +1,155 @@
+package parsertypes
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A predictive parsing table of an LL(1) grammar with the rules numbered like the original grammar.
+//
+// The rule that expands the nonterminal n when the next token is t is Predictions[PredictionRows[n]+t],
+// -1 means the token can't start n.
+type LLTable struct {
+	InitialSymbol int
+	EndToken      int
+	TokenNames    []string
+	// The head and the production of every rule of the grammar
+	RuleHeads   []int
+	Productions [][]int
+
+	// Indexed by token id, it's -1 for the terminals
+	PredictionRows []int
+	Predictions    []int
+}
+
+// The rule to expand nonTerminal with token, -1 if there's none.
+func (self *LLTable) Predict(nonTerminal GrammarToken, token GrammarToken) int {
+	if token < 0 || token >= len(self.TokenNames) {
+		return -1
+	}
+
+	row := self.PredictionRows[nonTerminal]
+	if row == -1 {
+		return -1
+	}
+
+	return self.Predictions[row+token]
+}
+
+// Shows a token like `1 (NUMBER)`.
+func (self *LLTable) TokenToHuman(tk GrammarToken) string {
+	return tokenToHuman(self.TokenNames, tk)
+}
+
+// Checks if the token is a terminal of the table.
+func (self *LLTable) isTerminal(token GrammarToken) bool {
+	return self.PredictionRows[token] == -1
+}
+
+// The terminals that can start nonTerminal.
+func (self *LLTable) expectedTokens(nonTerminal GrammarToken) []GrammarToken {
+	expected := []GrammarToken{}
+	for token := range self.TokenNames {
+		if self.Predict(nonTerminal, token) != -1 {
+			expected = append(expected, token)
+		}
+	}
+
+	return expected
+}
+
+// Parses all the tokens until the end token of the table is found,
+// building the concrete syntax tree of the source.
+func (self *LLTable) Parse(tokens TokenSource) (*TreeNode, error) {
+	result, err := self.ParseWithActions(tokens, treeActions(self.TokenNames, self.RuleHeads))
+	tree, _ := result.(*TreeNode)
+	return tree, err
+}
+
+// Parses all the tokens until the end token of the table is found.
+//
+// The parser expands the leftmost nonterminal with the rule the table predicts for the next token,
+// actions.Reduced is called once all the symbols of the rule are parsed, just like on an LR parser.
+// There's no recovery, so the parser stops on the first syntax error.
+// The characters the lexer doesn't recognize are skipped.
+//
+// Returns the value of the initial symbol of the grammar given by actions,
+// and SyntaxErrors with every error found or the error of the source that couldn't be read.
+func (self *LLTable) ParseWithActions(tokens TokenSource, actions ParseActions) (any, error) {
+	// The symbols left to parse, the negative ones mark the end of a rule like -rule-1
+	symbols := Stack[int]{self.InitialSymbol}
+	// The values of the symbols already parsed of the rules that aren't finished
+	values := Stack[any]{}
+	syntaxErrors := SyntaxErrors{}
+
+	nextToken := func() (Token, error) {
+		for {
+			token, err := tokens.Next()
+			var lexErr *LexError
+			if !errors.As(err, &lexErr) {
+				return token, err
+			}
+			syntaxErrors = append(syntaxErrors, err)
+		}
+	}
+
+	token, err := nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for len(symbols) > 0 {
+		symbol := symbols.Pop().GetValue()
+
+		if symbol < 0 {
+			idx := -symbol - 1
+			length := len(self.Productions[idx])
+
+			children := make([]any, length)
+			copy(children, values[len(values)-length:])
+			values = values[:len(values)-length]
+			values.Push(actions.Reduced(idx, children, token))
+			continue
+		}
+
+		if self.isTerminal(symbol) {
+			if token.Type != symbol {
+				syntaxErrors = append(syntaxErrors, &ParseError{Token: token, Expected: []GrammarToken{symbol}, endToken: self.EndToken, tokenNames: self.TokenNames})
+				return nil, syntaxErrors
+			}
+
+			values.Push(actions.Shifted(token))
+			token, err = nextToken()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		idx := self.Predict(symbol, token.Type)
+		if idx == -1 {
+			syntaxErrors = append(syntaxErrors, &ParseError{Token: token, Expected: self.expectedTokens(symbol), endToken: self.EndToken, tokenNames: self.TokenNames})
+			return nil, syntaxErrors
+		}
+		if self.RuleHeads[idx] != symbol {
+			return nil, fmt.Errorf("invalid prediction! Rule %d doesn't expand %s", idx, tokenToHuman(self.TokenNames, symbol))
+		}
+
+		symbols.Push(-idx - 1)
+		production := self.Productions[idx]
+		for i := len(production) - 1; i >= 0; i-- {
+			symbols.Push(production[i])
+		}
+	}
+
+	if token.Type != self.EndToken {
+		syntaxErrors = append(syntaxErrors, &ParseError{Token: token, Expected: []GrammarToken{self.EndToken}, endToken: self.EndToken, tokenNames: self.TokenNames})
+		return nil, syntaxErrors
+	}
+
+	result := values.Peek().GetValue()
+	if len(syntaxErrors) > 0 {
+		return result, syntaxErrors
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package parsertypes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// The predictive table of S -> a S | b
+func createExampleLLTable() LLTable {
+	return LLTable{
+		InitialSymbol: 2,
+		EndToken:      3,
+		TokenNames:    []string{"a", "b", "<S>", "<EOF>"},
+		RuleHeads:     []int{2, 2},
+		Productions:   [][]int{{0, 2}, {1}},
+
+		PredictionRows: []int{-1, -1, 0, -1},
+		Predictions:    []int{0, 1, -1, -1},
+	}
+}
+
+func TestLLParse(t *testing.T) {
+	table := createExampleLLTable()
+	tree, err := table.Parse(exampleTokens("aab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same tree the LR parser builds
+	expected := `<S> -> a <S> (1:1) [0, 3)
+  a "a" (1:1) [0, 1)
+  <S> -> a <S> (1:2) [1, 3)
+    a "a" (1:2) [1, 2)
+    <S> -> b (1:3) [2, 3)
+      b "b" (1:3) [2, 3)
+`
+	if tree.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, tree.String())
+	}
+
+	actions := ParseActions{
+		Shifted: func(token Token) any { return token.Text },
+		Reduced: func(ruleIdx int, children []any, lookahead Token) any {
+			if ruleIdx == 1 {
+				return 0
+			}
+			return children[1].(int) + 1
+		},
+	}
+	result, err := table.ParseWithActions(exampleTokens("aaab"), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 3 {
+		t.Fatalf("Expected 3 but got %v", result)
+	}
+}
+
+func TestLLParseErrors(t *testing.T) {
+	table := createExampleLLTable()
+
+	_, err := table.Parse(exampleTokens("aa"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("Expected a ParseError but got %v", err)
+	}
+	if !reflect.DeepEqual(parseErr.Expected, []int{0, 1}) {
+		t.Errorf("Expected a and b to be expected but got %v", parseErr.Expected)
+	}
+	if err.Error() != "1:3: unexpected EOF reached" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+
+	_, err = table.Parse(exampleTokens("abb"))
+	if err == nil || err.Error() != `1:3: unexpected token "b" (1 (b))` {
+		t.Errorf("Expected an error on the last b but got %v", err)
+	}
+
+	tree, err := table.Parse(exampleTokens("a?b"))
+	if err == nil || err.Error() != `1:2: unexpected character '?'` {
+		t.Errorf("Expected a lex error but got %v", err)
+	}
+	if tree == nil || tree.End != 3 {
+		t.Errorf("Expected the tree of the whole source but got %v", tree)
+	}
+}
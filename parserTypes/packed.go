@@ -0,0 +1,398 @@
+package parsertypes
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// The actions on a PackedTable that aren't shifts or reduces.
+//
+// Shifts are stored as the state plus one and reduces as minus the rule minus one.
+const (
+	PACKED_ERROR  int = 0
+	PACKED_ACCEPT int = math.MinInt32
+)
+
+// The base of the rows that only have their default action
+const PACKED_NO_BASE int = math.MinInt32
+
+// A parsing table with its states numbered from 0 and its rows compressed into flat arrays,
+// just like bison's yypact, yydefact, yytable and yycheck.
+//
+// The action of state s with the terminal t is Table[ActionBase[s]+t] if Check has t on that index,
+// otherwise it's DefaultActions[s].
+// The goto of state s with the nonterminal n is Table[GoToBase[n]+s] if Check has s on that index,
+// otherwise it's DefaultGoTos[n].
+type PackedTable struct {
+	InitialState int
+	EndToken     int
+	ErrorToken   int
+	TokenNames   []string
+	// The head and the length of the production of every rule of the grammar
+	RuleHeads   []int
+	RuleLengths []int
+
+	// Indexed by state
+	ActionBase []int
+	// The most common reduce of every state or PACKED_ERROR,
+	// the states that can shift the error token don't have a default reduce
+	DefaultActions []int
+	// Indexed by token id, only the nonterminals are used
+	GoToBase     []int
+	DefaultGoTos []int
+
+	Table []int
+	Check []int
+}
+
+func packShift(state int) int {
+	return state + 1
+}
+
+func packReduce(rule int) int {
+	return -rule - 1
+}
+
+func isPackedShift(action int) bool {
+	return action > 0
+}
+
+func isPackedReduce(action int) bool {
+	return action < 0 && action != PACKED_ACCEPT
+}
+
+func packedShiftState(action int) int {
+	return action - 1
+}
+
+func packedReduceRule(action int) int {
+	return -action - 1
+}
+
+// The action of state with token, PACKED_ERROR if there's none.
+func (self *PackedTable) Action(state int, token GrammarToken) int {
+	if token < 0 {
+		return PACKED_ERROR
+	}
+
+	base := self.ActionBase[state]
+	if base != PACKED_NO_BASE {
+		idx := base + token
+		if idx >= 0 && idx < len(self.Check) && self.Check[idx] == token {
+			return self.Table[idx]
+		}
+	}
+
+	return self.DefaultActions[state]
+}
+
+// The state to go from state after reducing to nonTerminal, -1 if there's none.
+func (self *PackedTable) GoTo(state int, nonTerminal GrammarToken) int {
+	base := self.GoToBase[nonTerminal]
+	if base != PACKED_NO_BASE {
+		idx := base + state
+		if idx >= 0 && idx < len(self.Check) && self.Check[idx] == state {
+			return self.Table[idx]
+		}
+	}
+
+	return self.DefaultGoTos[nonTerminal]
+}
+
+// Shows a token like `1 (NUMBER)`.
+func (self *PackedTable) TokenToHuman(tk GrammarToken) string {
+	return tokenToHuman(self.TokenNames, tk)
+}
+
+// The terminals the state has an action for on its row, without counting the default reduce.
+func (self *PackedTable) expectedTokens(state int) []GrammarToken {
+	expected := []GrammarToken{}
+	base := self.ActionBase[state]
+	if base == PACKED_NO_BASE {
+		return expected
+	}
+
+	for token := range self.TokenNames {
+		idx := base + token
+		if token != self.ErrorToken && idx >= 0 && idx < len(self.Check) && self.Check[idx] == token && self.Table[idx] != PACKED_ERROR {
+			expected = append(expected, token)
+		}
+	}
+
+	return expected
+}
+
+// Numbers the nodes of the table from 0 in the order they're reached from the initial node,
+// following the shifts and gotos by token.
+func (self *ParsingTable) StateNumbers() map[AFDNodeId]int {
+	numbers := make(map[AFDNodeId]int)
+	queue := []AFDNodeId{self.InitialNodeId}
+	numbers[self.InitialNodeId] = 0
+
+	visit := func(node AFDNodeId) {
+		if _, found := numbers[node]; !found {
+			numbers[node] = len(numbers)
+			queue = append(queue, node)
+		}
+	}
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		successors := make(map[GrammarToken]AFDNodeId)
+		for token, action := range self.ActionTable[node] {
+			if action.IsShift() {
+				successors[token] = action.GetShift()
+			}
+		}
+		for token, to := range self.GoToTable[node] {
+			successors[token] = to
+		}
+
+		tokens := make([]GrammarToken, 0, len(successors))
+		for token := range successors {
+			tokens = append(tokens, token)
+		}
+		slices.Sort(tokens)
+		for _, token := range tokens {
+			visit(successors[token])
+		}
+	}
+
+	// Nodes that can't be reached still get a number
+	unreachable := []AFDNodeId{}
+	for node := range self.ActionTable {
+		if _, found := numbers[node]; !found {
+			unreachable = append(unreachable, node)
+		}
+	}
+	for node := range self.GoToTable {
+		if _, found := numbers[node]; !found && !slices.Contains(unreachable, node) {
+			unreachable = append(unreachable, node)
+		}
+	}
+	slices.SortFunc(unreachable, compareNodeIds)
+	for _, node := range unreachable {
+		numbers[node] = len(numbers)
+	}
+
+	return numbers
+}
+
+// A row of the action or goto table before being packed
+type packedRow struct {
+	isGoTo bool
+	// The state of an action row or the nonterminal of a goto row
+	index int
+	// The terminals of an action row or the states of a goto row, sorted
+	keys   []int
+	values []int
+}
+
+func (self *packedRow) signature() string {
+	b := strings.Builder{}
+	fmt.Fprint(&b, self.isGoTo, self.keys, self.values)
+	return b.String()
+}
+
+// Numbers the states of the table and compresses it the same way bison does.
+//
+// Every state reduces its most common rule on the terminals that don't have an action,
+// so those entries don't have to be stored, and the same happens with the most common goto of every nonterminal.
+// The remaining entries of every row are placed on a shared array where they don't overlap with the others.
+func (self *ParsingTable) Pack() PackedTable {
+	numbers := self.StateNumbers()
+	stateCount := len(numbers)
+	tokenCount := len(self.TokenNames)
+
+	packed := PackedTable{
+		InitialState:   numbers[self.InitialNodeId],
+		EndToken:       self.EndToken,
+		ErrorToken:     self.ErrorToken,
+		TokenNames:     self.TokenNames,
+		RuleHeads:      make([]int, len(self.Original.Rules)),
+		RuleLengths:    make([]int, len(self.Original.Rules)),
+		ActionBase:     make([]int, stateCount),
+		DefaultActions: make([]int, stateCount),
+		GoToBase:       make([]int, tokenCount),
+		DefaultGoTos:   make([]int, tokenCount),
+		Table:          []int{},
+		Check:          []int{},
+	}
+	for i, rule := range self.Original.Rules {
+		packed.RuleHeads[i] = rule.Head
+		packed.RuleLengths[i] = len(rule.Production)
+	}
+
+	rows := []packedRow{}
+	for node, state := range numbers {
+		row := make(map[int]int)
+		reduceCounts := make(map[int]int)
+		canShiftError := false
+		for token, action := range self.ActionTable[node] {
+			switch {
+			case action.Accept:
+				row[token] = PACKED_ACCEPT
+			case action.IsShift():
+				row[token] = packShift(numbers[action.GetShift()])
+				canShiftError = canShiftError || token == self.ErrorToken
+			case action.IsReduce():
+				row[token] = packReduce(action.GetReduce())
+				reduceCounts[action.GetReduce()]++
+			}
+		}
+
+		// Reducing before finding an error would make the error token harder to shift
+		packed.DefaultActions[state] = PACKED_ERROR
+		if !canShiftError && len(reduceCounts) > 0 {
+			defaultRule := mostCommon(reduceCounts)
+			packed.DefaultActions[state] = packReduce(defaultRule)
+			for token, action := range row {
+				if action == packReduce(defaultRule) {
+					delete(row, token)
+				}
+			}
+			for token := range self.ExplicitErrors[node] {
+				row[token] = PACKED_ERROR
+			}
+		}
+
+		rows = append(rows, newPackedRow(false, state, row))
+	}
+
+	gotos := make(map[GrammarToken]map[int]int)
+	for node, row := range self.GoToTable {
+		for nonTerminal, to := range row {
+			if _, found := gotos[nonTerminal]; !found {
+				gotos[nonTerminal] = make(map[int]int)
+			}
+			gotos[nonTerminal][numbers[node]] = numbers[to]
+		}
+	}
+	for token := range tokenCount {
+		packed.GoToBase[token] = PACKED_NO_BASE
+		packed.DefaultGoTos[token] = -1
+
+		row, found := gotos[token]
+		if !found {
+			continue
+		}
+
+		targetCounts := make(map[int]int)
+		for _, to := range row {
+			targetCounts[to]++
+		}
+		defaultGoTo := mostCommon(targetCounts)
+		packed.DefaultGoTos[token] = defaultGoTo
+		for state, to := range row {
+			if to == defaultGoTo {
+				delete(row, state)
+			}
+		}
+
+		rows = append(rows, newPackedRow(true, token, row))
+	}
+
+	packed.packRows(rows)
+	return packed
+}
+
+func newPackedRow(isGoTo bool, index int, entries map[int]int) packedRow {
+	row := packedRow{isGoTo: isGoTo, index: index}
+	for key := range entries {
+		row.keys = append(row.keys, key)
+	}
+	slices.Sort(row.keys)
+	for _, key := range row.keys {
+		row.values = append(row.values, entries[key])
+	}
+
+	return row
+}
+
+// The key with the highest count, the smallest one on ties.
+func mostCommon(counts map[int]int) int {
+	best, bestCount := 0, -1
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best, bestCount = key, count
+		}
+	}
+
+	return best
+}
+
+// Places every row on Table and Check, the biggest ones first.
+//
+// Every row gets a different base so no row can find the entries of another one,
+// only identical rows share their base.
+func (self *PackedTable) packRows(rows []packedRow) {
+	slices.SortFunc(rows, func(a, b packedRow) int {
+		if len(a.keys) != len(b.keys) {
+			return len(b.keys) - len(a.keys)
+		}
+		if a.isGoTo != b.isGoTo {
+			if a.isGoTo {
+				return 1
+			}
+			return -1
+		}
+		return a.index - b.index
+	})
+
+	usedBases := make(map[int]struct{})
+	sharedBases := make(map[string]int)
+	for _, row := range rows {
+		base := PACKED_NO_BASE
+		if len(row.keys) > 0 {
+			signature := row.signature()
+			if shared, found := sharedBases[signature]; found {
+				base = shared
+			} else {
+				base = self.findBase(&row, usedBases)
+				usedBases[base] = struct{}{}
+				sharedBases[signature] = base
+
+				for i, key := range row.keys {
+					self.Table[base+key] = row.values[i]
+					self.Check[base+key] = key
+				}
+			}
+		}
+
+		if row.isGoTo {
+			self.GoToBase[row.index] = base
+		} else {
+			self.ActionBase[row.index] = base
+		}
+	}
+}
+
+// The first base where all the entries of row fit, growing Table and Check if needed.
+func (self *PackedTable) findBase(row *packedRow, usedBases map[int]struct{}) int {
+	for base := -row.keys[0]; ; base++ {
+		if _, used := usedBases[base]; used {
+			continue
+		}
+
+		fits := true
+		for _, key := range row.keys {
+			idx := base + key
+			if idx < len(self.Check) && self.Check[idx] != -1 {
+				fits = false
+				break
+			}
+		}
+
+		if fits {
+			for len(self.Check) <= base+row.keys[len(row.keys)-1] {
+				self.Table = append(self.Table, PACKED_ERROR)
+				self.Check = append(self.Check, -1)
+			}
+			return base
+		}
+	}
+}
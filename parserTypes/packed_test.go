@@ -0,0 +1,94 @@
+package parsertypes
+
+import (
+	"testing"
+)
+
+func TestStateNumbers(t *testing.T) {
+	table := createExampleTable()
+	numbers := table.StateNumbers()
+
+	// The initial node first, then the nodes it reaches by token
+	expected := map[AFDNodeId]int{"0": 0, "2": 1, "3": 2, "1": 3, "4": 4}
+	if len(numbers) != len(expected) {
+		t.Fatalf("Expected %v but got %v", expected, numbers)
+	}
+	for node, number := range expected {
+		if numbers[node] != number {
+			t.Fatalf("Expected %v but got %v", expected, numbers)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	table := createExampleTable()
+	numbers := table.StateNumbers()
+	packed := table.Pack()
+
+	if packed.InitialState != 0 {
+		t.Fatalf("Expected the initial state to be 0 but got %d", packed.InitialState)
+	}
+
+	// Every action of the table must be found on the packed one
+	for node, row := range table.ActionTable {
+		for token, action := range row {
+			got := packed.Action(numbers[node], token)
+			switch {
+			case action.Accept:
+				if got != PACKED_ACCEPT {
+					t.Errorf("Expected accept on %s with %d but got %d", node, token, got)
+				}
+			case action.IsShift():
+				if !isPackedShift(got) || packedShiftState(got) != numbers[action.GetShift()] {
+					t.Errorf("Expected a shift to %s on %s with %d but got %d", action.GetShift(), node, token, got)
+				}
+			case action.IsReduce():
+				if !isPackedReduce(got) || packedReduceRule(got) != action.GetReduce() {
+					t.Errorf("Expected a reduce of %d on %s with %d but got %d", action.GetReduce(), node, token, got)
+				}
+			}
+		}
+	}
+
+	for node, row := range table.GoToTable {
+		for token, to := range row {
+			if got := packed.GoTo(numbers[node], token); got != numbers[to] {
+				t.Errorf("Expected a goto to %s on %s with %d but got %d", to, node, token, got)
+			}
+		}
+	}
+
+	// "3" reduces by default, except for the explicit error
+	state := numbers["3"]
+	if action := packed.Action(state, 1); action != packReduce(1) {
+		t.Errorf("Expected the default reduce but got %d", action)
+	}
+	if action := packed.Action(state, 0); action != PACKED_ERROR {
+		t.Errorf("Expected the explicit error but got %d", action)
+	}
+
+	// The rows of "0" and "2" are the same, so they share their entries
+	if packed.ActionBase[numbers["0"]] != packed.ActionBase[numbers["2"]] {
+		t.Errorf("Expected identical rows to share their base but got %v", packed.ActionBase)
+	}
+	if len(packed.Table) != len(packed.Check) {
+		t.Errorf("Table and Check must have the same length: %v %v", packed.Table, packed.Check)
+	}
+}
+
+func TestPackKeepsErrorRecovery(t *testing.T) {
+	table := createExampleTable()
+	// Pretend `a` is the error token, so "0" and "2" can shift it
+	table.ErrorToken = 0
+	table.ActionTable["2"][3] = Action{Reduce: table.ActionTable["3"][3].Reduce}
+	numbers := table.StateNumbers()
+	packed := table.Pack()
+
+	// A state that can shift the error token never reduces by default
+	if action := packed.DefaultActions[numbers["2"]]; action != PACKED_ERROR {
+		t.Errorf("Expected no default action but got %d", action)
+	}
+	if action := packed.Action(numbers["2"], 3); action != packReduce(1) {
+		t.Errorf("Expected the reduce to stay on the row but got %d", action)
+	}
+}
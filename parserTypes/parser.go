@@ -0,0 +1,245 @@
+package parsertypes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// How many tokens must be shifted after recovering from a syntax error before a new one is reported
+const RECOVERY_SHIFTS int = 3
+
+// Returned when the parser finds a token the grammar doesn't allow.
+type ParseError struct {
+	Token Token
+	// The token types the parser would have accepted instead
+	Expected []int
+
+	endToken   int
+	tokenNames []string
+}
+
+func (self *ParseError) Error() string {
+	if self.Token.Type == self.endToken {
+		return fmt.Sprintf("%d:%d: unexpected EOF reached", self.Token.Line, self.Token.Col)
+	}
+
+	return fmt.Sprintf("%d:%d: unexpected token %q (%s)", self.Token.Line, self.Token.Col, self.Token.Text, tokenToHuman(self.tokenNames, self.Token.Type))
+}
+
+// All the errors found while parsing a source, in the order they were found.
+type SyntaxErrors []error
+
+func (self SyntaxErrors) Error() string {
+	messages := make([]string, len(self))
+	for i, err := range self {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (self SyntaxErrors) Unwrap() []error {
+	return self
+}
+
+// What the parser does with the tokens and productions it finds.
+type ParseActions struct {
+	// Gives the value of every token shifted, including the error token
+	Shifted func(token Token) any
+	// Gives the value of the head of a production from the values of its symbols,
+	// lookahead is the token after the production
+	Reduced func(ruleIdx int, children []any, lookahead Token) any
+}
+
+// Parses all the tokens until the end token of the table is found,
+// building the concrete syntax tree of the source.
+//
+// The table is packed first, use PackedTable.Parse to parse many sources with the same table.
+func Parse(table *ParsingTable, tokens TokenSource) (*TreeNode, error) {
+	packed := table.Pack()
+	return packed.Parse(tokens)
+}
+
+// Parses all the tokens until the end token of the table is found, using actions to give a value to every symbol.
+//
+// The table is packed first, use PackedTable.ParseWithActions to parse many sources with the same table.
+func ParseWithActions(table *ParsingTable, tokens TokenSource, actions ParseActions) (any, error) {
+	packed := table.Pack()
+	return packed.ParseWithActions(tokens, actions)
+}
+
+// Parses all the tokens until the end token of the table is found,
+// building the concrete syntax tree of the source.
+//
+// The error tokens shifted while recovering from syntax errors are kept on the tree,
+// covering the symbols that were popped to recover.
+func (self *PackedTable) Parse(tokens TokenSource) (*TreeNode, error) {
+	result, err := self.ParseWithActions(tokens, treeActions(self.TokenNames, self.RuleHeads))
+	tree, _ := result.(*TreeNode)
+	return tree, err
+}
+
+// The actions that build the concrete syntax tree, with a node for every token and production.
+func treeActions(tokenNames []string, ruleHeads []int) ParseActions {
+	return ParseActions{
+		Shifted: func(token Token) any {
+			return &TreeNode{
+				Type:   token.Type,
+				Symbol: tokenNames[token.Type],
+				Rule:   -1,
+				Start:  token.Start,
+				End:    token.End,
+				Line:   token.Line,
+				Col:    token.Col,
+				Text:   token.Text,
+			}
+		},
+		Reduced: func(ruleIdx int, children []any, lookahead Token) any {
+			head := ruleHeads[ruleIdx]
+			node := &TreeNode{
+				Type:   head,
+				Symbol: tokenNames[head],
+				Rule:   ruleIdx,
+				// Empty productions are placed right before the next token
+				Start: lookahead.Start,
+				End:   lookahead.Start,
+				Line:  lookahead.Line,
+				Col:   lookahead.Col,
+			}
+
+			for _, child := range children {
+				node.Children = append(node.Children, child.(*TreeNode))
+			}
+			if len(node.Children) > 0 {
+				first := node.Children[0]
+				node.Start, node.Line, node.Col = first.Start, first.Line, first.Col
+				node.End = node.Children[len(node.Children)-1].End
+			}
+			return node
+		},
+	}
+}
+
+// Parses all the tokens until the end token of the table is found.
+//
+// Just like yacc, when a token isn't expected the parser pops states until one can shift the error token
+// and then discards tokens until one can follow it, so all the syntax errors of a source are found in one run.
+// The characters the lexer doesn't recognize are skipped.
+//
+// Returns the value of the initial symbol of the grammar given by actions,
+// and SyntaxErrors with every error found or the error of the source that couldn't be read.
+func (self *PackedTable) ParseWithActions(tokens TokenSource, actions ParseActions) (any, error) {
+	states := Stack[int]{self.InitialState}
+	// The values of every symbol on the stack
+	values := Stack[any]{}
+	// The first token of every value, so the error token can cover the values popped to recover
+	starts := Stack[Token]{}
+	syntaxErrors := SyntaxErrors{}
+	// How many tokens must still be shifted to fully recover from the last syntax error
+	recovering := 0
+
+	nextToken := func() (Token, error) {
+		for {
+			token, err := tokens.Next()
+			var lexErr *LexError
+			if !errors.As(err, &lexErr) {
+				return token, err
+			}
+			syntaxErrors = append(syntaxErrors, err)
+		}
+	}
+
+	token, err := nextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		state := states[len(states)-1]
+		action := self.Action(state, token.Type)
+		if action == PACKED_ERROR {
+			if recovering == 0 {
+				syntaxErrors = append(syntaxErrors, &ParseError{Token: token, Expected: self.expectedTokens(state), endToken: self.EndToken, tokenNames: self.TokenNames})
+			}
+
+			if recovering == RECOVERY_SHIFTS {
+				// Nothing was shifted after the error token, so this token can't follow it
+				if token.Type == self.EndToken {
+					return nil, syntaxErrors
+				}
+
+				token, err = nextToken()
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			// Pops states until one can shift the error token
+			errorToken := Token{Start: token.Start, End: token.Start, Line: token.Line, Col: token.Col, Type: self.ErrorToken}
+			for {
+				errorAction := self.Action(state, self.ErrorToken)
+				if isPackedShift(errorAction) {
+					states.Push(packedShiftState(errorAction))
+					values.Push(actions.Shifted(errorToken))
+					starts.Push(errorToken)
+					break
+				}
+
+				if len(states) == 1 {
+					return nil, syntaxErrors
+				}
+				states = states[:len(states)-1]
+				values = values[:len(values)-1]
+				start := starts.Pop().GetValue()
+				errorToken.Start, errorToken.Line, errorToken.Col = start.Start, start.Line, start.Col
+				state = states[len(states)-1]
+			}
+			recovering = RECOVERY_SHIFTS
+			continue
+		}
+
+		if action == PACKED_ACCEPT {
+			result := values.Peek().GetValue()
+			if len(syntaxErrors) > 0 {
+				return result, syntaxErrors
+			}
+			return result, nil
+		} else if isPackedShift(action) {
+			states.Push(packedShiftState(action))
+			values.Push(actions.Shifted(token))
+			starts.Push(token)
+			recovering = max(0, recovering-1)
+
+			token, err = nextToken()
+			if err != nil {
+				return nil, err
+			}
+		} else if isPackedReduce(action) {
+			idx := packedReduceRule(action)
+			length := self.RuleLengths[idx]
+
+			states = states[:len(states)-length]
+			gotoState := states[len(states)-1]
+			newState := self.GoTo(gotoState, self.RuleHeads[idx])
+			if newState == -1 {
+				return nil, fmt.Errorf("invalid parsing state! No goto from %d with %s", gotoState, self.TokenToHuman(self.RuleHeads[idx]))
+			}
+			states.Push(newState)
+
+			children := make([]any, length)
+			copy(children, values[len(values)-length:])
+			values = values[:len(values)-length]
+			values.Push(actions.Reduced(idx, children, token))
+
+			// Empty productions start on the next token
+			start := token
+			if length > 0 {
+				start = starts[len(starts)-length]
+			}
+			starts = starts[:len(starts)-length]
+			starts.Push(start)
+		}
+	}
+}
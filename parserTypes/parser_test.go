@@ -0,0 +1,131 @@
+package parsertypes
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// Supplies the tokens of a slice and then end, or err if it isn't nil.
+type sliceTokens struct {
+	tokens []Token
+	end    Token
+	err    error
+}
+
+func (self *sliceTokens) Next() (Token, error) {
+	if len(self.tokens) == 0 {
+		if self.err != nil {
+			return Token{}, self.err
+		}
+		return self.end, nil
+	}
+
+	token := self.tokens[0]
+	self.tokens = self.tokens[1:]
+	if token.Type == UNRECOGNIZABLE {
+		return Token{}, &LexError{Offset: token.Start, Line: token.Line, Col: token.Col, Char: []rune(token.Text)[0]}
+	}
+	return token, nil
+}
+
+// Splits a source of the example table, every character is a token
+func exampleTokens(source string) *sliceTokens {
+	tokens := &sliceTokens{end: Token{Start: len(source), End: len(source), Line: 1, Col: len(source) + 1, Type: 3}}
+	for i, char := range source {
+		token := Token{Start: i, End: i + 1, Line: 1, Col: i + 1, Text: string(char), Type: UNRECOGNIZABLE}
+		switch char {
+		case 'a':
+			token.Type = 0
+		case 'b':
+			token.Type = 1
+		}
+		tokens.tokens = append(tokens.tokens, token)
+	}
+
+	return tokens
+}
+
+func TestParse(t *testing.T) {
+	table := createExampleTable()
+	tree, err := Parse(&table, exampleTokens("aab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<S> -> a <S> (1:1) [0, 3)
+  a "a" (1:1) [0, 1)
+  <S> -> a <S> (1:2) [1, 3)
+    a "a" (1:2) [1, 2)
+    <S> -> b (1:3) [2, 3)
+      b "b" (1:3) [2, 3)
+`
+	if tree.String() != expected {
+		t.Fatalf("Expected:\n%s\nBut got:\n%s", expected, tree.String())
+	}
+}
+
+func TestParseWithActions(t *testing.T) {
+	table := createExampleTable()
+	// Counts the a's before the b
+	actions := ParseActions{
+		Shifted: func(token Token) any { return token.Text },
+		Reduced: func(ruleIdx int, children []any, lookahead Token) any {
+			if ruleIdx == 1 {
+				return 0
+			}
+			return children[1].(int) + 1
+		},
+	}
+
+	result, err := ParseWithActions(&table, exampleTokens("aaab"), actions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != 3 {
+		t.Fatalf("Expected 3 but got %v", result)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	table := createExampleTable()
+
+	// The grammar doesn't use the error token, so the parser gives up on the first syntax error
+	_, err := Parse(&table, exampleTokens("aa"))
+	var syntaxErrors SyntaxErrors
+	if !errors.As(err, &syntaxErrors) || len(syntaxErrors) != 1 {
+		t.Fatalf("Expected a syntax error but got %v", err)
+	}
+	var parseErr *ParseError
+	if !errors.As(syntaxErrors[0], &parseErr) {
+		t.Fatalf("Expected a ParseError but got %v", syntaxErrors[0])
+	}
+	if !reflect.DeepEqual(parseErr.Expected, []int{0, 1}) {
+		t.Errorf("Expected a and b to be expected but got %v", parseErr.Expected)
+	}
+	if err.Error() != "1:3: unexpected EOF reached" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+
+	_, err = Parse(&table, exampleTokens("abb"))
+	if err == nil || err.Error() != `1:3: unexpected token "b" (1 (b))` {
+		t.Errorf("Expected an error on the last b but got %v", err)
+	}
+
+	// The characters the lexer doesn't recognize are reported but the source is still parsed
+	tree, err := Parse(&table, exampleTokens("a?b"))
+	if err == nil || err.Error() != `1:2: unexpected character '?'` {
+		t.Errorf("Expected a lex error but got %v", err)
+	}
+	if tree == nil || tree.End != 3 {
+		t.Errorf("Expected the tree of the whole source but got %v", tree)
+	}
+
+	// The errors of the source are returned as they are
+	tokens := exampleTokens("a")
+	tokens.err = io.ErrUnexpectedEOF
+	if _, err := Parse(&table, tokens); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected the error of the source but got %v", err)
+	}
+}
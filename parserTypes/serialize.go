@@ -0,0 +1,386 @@
+package parsertypes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// The version of the format written by WriteJSON and WriteBinary.
+//
+// It must be increased every time the format changes, so old files are rejected instead of misread.
+const PARSING_TABLE_FORMAT_VERSION int = 3
+
+const PARSING_TABLE_FORMAT = "uwu-parsing-table"
+
+// The magic bytes at the start of the binary format
+const PARSING_TABLE_MAGIC = "UWUT"
+
+type jsonParsingTable struct {
+	Format         string       `json:"format"`
+	Version        int          `json:"version"`
+	InitialNodeId  AFDNodeId    `json:"initialNode"`
+	Grammar        jsonGrammar  `json:"grammar"`
+	Actions        []jsonAction `json:"actions"`
+	GoTos          []jsonGoTo   `json:"gotos"`
+	EndToken       GrammarToken `json:"endToken"`
+	ErrorToken     GrammarToken `json:"errorToken"`
+	TokenNames     []string     `json:"tokenNames"`
+	ExplicitErrors []jsonCell   `json:"explicitErrors"`
+}
+
+type jsonGrammar struct {
+	InitialSimbol GrammarToken   `json:"initialSymbol"`
+	Rules         []GrammarRule  `json:"rules"`
+	Terminals     []GrammarToken `json:"terminals"`
+	NonTerminals  []GrammarToken `json:"nonTerminals"`
+}
+
+type jsonAction struct {
+	Node   AFDNodeId    `json:"node"`
+	Token  GrammarToken `json:"token"`
+	Shift  *AFDNodeId   `json:"shift,omitempty"`
+	Reduce *int         `json:"reduce,omitempty"`
+	Accept bool         `json:"accept,omitempty"`
+}
+
+type jsonCell struct {
+	Node  AFDNodeId    `json:"node"`
+	Token GrammarToken `json:"token"`
+}
+
+type jsonGoTo struct {
+	Node  AFDNodeId    `json:"node"`
+	Token GrammarToken `json:"token"`
+	To    AFDNodeId    `json:"to"`
+}
+
+// The entries of a table sorted by node and then by token, so the same table is always written the same way.
+func sortedEntries[V any](table map[AFDNodeId]map[GrammarToken]V, visit func(node AFDNodeId, token GrammarToken, value V)) {
+	nodes := make([]AFDNodeId, 0, len(table))
+	for node := range table {
+		nodes = append(nodes, node)
+	}
+	slices.SortFunc(nodes, compareNodeIds)
+
+	for _, node := range nodes {
+		tokens := make([]GrammarToken, 0, len(table[node]))
+		for token := range table[node] {
+			tokens = append(tokens, token)
+		}
+		slices.Sort(tokens)
+
+		for _, token := range tokens {
+			visit(node, token, table[node][token])
+		}
+	}
+}
+
+// Node ids are usually numbers, so shorter ids go first.
+func compareNodeIds(a, b AFDNodeId) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func toJSONParsingTable(table *ParsingTable) jsonParsingTable {
+	out := jsonParsingTable{
+		Format:        PARSING_TABLE_FORMAT,
+		Version:       PARSING_TABLE_FORMAT_VERSION,
+		InitialNodeId: table.InitialNodeId,
+		Grammar: jsonGrammar{
+			InitialSimbol: table.Original.InitialSimbol,
+			Rules:         table.Original.Rules,
+			Terminals:     GetValuesStable(table.Original.Terminals),
+			NonTerminals:  GetValuesStable(table.Original.NonTerminals),
+		},
+		Actions:        []jsonAction{},
+		GoTos:          []jsonGoTo{},
+		ExplicitErrors: []jsonCell{},
+		EndToken:       table.EndToken,
+		ErrorToken:     table.ErrorToken,
+		TokenNames:     table.TokenNames,
+	}
+	if out.TokenNames == nil {
+		out.TokenNames = []string{}
+	}
+
+	sortedEntries(table.ActionTable, func(node AFDNodeId, token GrammarToken, action Action) {
+		entry := jsonAction{Node: node, Token: token, Accept: action.Accept}
+		if action.IsShift() {
+			shift := action.GetShift()
+			entry.Shift = &shift
+		}
+		if action.IsReduce() {
+			reduce := action.GetReduce()
+			entry.Reduce = &reduce
+		}
+		out.Actions = append(out.Actions, entry)
+	})
+
+	sortedEntries(table.GoToTable, func(node AFDNodeId, token GrammarToken, to AFDNodeId) {
+		out.GoTos = append(out.GoTos, jsonGoTo{Node: node, Token: token, To: to})
+	})
+
+	explicitErrors := make(map[AFDNodeId]map[GrammarToken]struct{})
+	for node, tokens := range table.ExplicitErrors {
+		explicitErrors[node] = tokens
+	}
+	sortedEntries(explicitErrors, func(node AFDNodeId, token GrammarToken, _ struct{}) {
+		out.ExplicitErrors = append(out.ExplicitErrors, jsonCell{Node: node, Token: token})
+	})
+
+	return out
+}
+
+func newParsingTable(initialNodeId AFDNodeId, initialSimbol GrammarToken) ParsingTable {
+	return ParsingTable{
+		TokenNames:     []string{},
+		ActionTable:    make(map[AFDNodeId]map[GrammarToken]Action),
+		GoToTable:      make(map[AFDNodeId]map[GrammarToken]AFDNodeId),
+		ExplicitErrors: make(map[AFDNodeId]Set[GrammarToken]),
+		Original: Grammar{
+			InitialSimbol: initialSimbol,
+			Rules:         []GrammarRule{},
+			Terminals:     NewSet[GrammarToken](),
+			NonTerminals:  NewSet[GrammarToken](),
+		},
+		InitialNodeId: initialNodeId,
+	}
+}
+
+func (self *ParsingTable) addAction(node AFDNodeId, token GrammarToken, action Action) {
+	if _, found := self.ActionTable[node]; !found {
+		self.ActionTable[node] = make(map[GrammarToken]Action)
+	}
+	self.ActionTable[node][token] = action
+}
+
+func (self *ParsingTable) addGoTo(node AFDNodeId, token GrammarToken, to AFDNodeId) {
+	if _, found := self.GoToTable[node]; !found {
+		self.GoToTable[node] = make(map[GrammarToken]AFDNodeId)
+	}
+	self.GoToTable[node][token] = to
+}
+
+func (self *ParsingTable) addExplicitError(node AFDNodeId, token GrammarToken) {
+	if _, found := self.ExplicitErrors[node]; !found {
+		self.ExplicitErrors[node] = NewSet[GrammarToken]()
+	}
+	self.ExplicitErrors[node][token] = struct{}{}
+}
+
+// Writes the table as JSON, the format and version are written along the table.
+func (self *ParsingTable) WriteJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(toJSONParsingTable(self))
+}
+
+// Reads a table written by WriteJSON.
+//
+// Fails if the file was written with another version of the format.
+func ReadParsingTableJSON(reader io.Reader) (ParsingTable, error) {
+	in := jsonParsingTable{}
+	if err := json.NewDecoder(reader).Decode(&in); err != nil {
+		return ParsingTable{}, err
+	}
+
+	if in.Format != PARSING_TABLE_FORMAT {
+		return ParsingTable{}, fmt.Errorf("expected a %s but found a %q", PARSING_TABLE_FORMAT, in.Format)
+	}
+	if in.Version != PARSING_TABLE_FORMAT_VERSION {
+		return ParsingTable{}, fmt.Errorf("unsupported %s version %d, expected version %d", PARSING_TABLE_FORMAT, in.Version, PARSING_TABLE_FORMAT_VERSION)
+	}
+
+	table := newParsingTable(in.InitialNodeId, in.Grammar.InitialSimbol)
+	table.EndToken = in.EndToken
+	table.ErrorToken = in.ErrorToken
+	if in.TokenNames != nil {
+		table.TokenNames = in.TokenNames
+	}
+	if in.Grammar.Rules != nil {
+		table.Original.Rules = in.Grammar.Rules
+	}
+	for _, terminal := range in.Grammar.Terminals {
+		table.Original.Terminals.Add(terminal)
+	}
+	for _, nonTerminal := range in.Grammar.NonTerminals {
+		table.Original.NonTerminals.Add(nonTerminal)
+	}
+
+	for _, entry := range in.Actions {
+		action := Action{Accept: entry.Accept}
+		if entry.Shift != nil {
+			action.Shift = lib.CreateValue(*entry.Shift)
+		}
+		if entry.Reduce != nil {
+			action.Reduce = lib.CreateValue(*entry.Reduce)
+		}
+		table.addAction(entry.Node, entry.Token, action)
+	}
+
+	for _, entry := range in.GoTos {
+		table.addGoTo(entry.Node, entry.Token, entry.To)
+	}
+
+	for _, entry := range in.ExplicitErrors {
+		table.addExplicitError(entry.Node, entry.Token)
+	}
+
+	return table, nil
+}
+
+// The kinds of actions on the binary format
+const (
+	binaryShiftAction = iota
+	binaryReduceAction
+	binaryAcceptAction
+)
+
+// Writes the table in a compact binary format, the version is written along the table.
+func (self *ParsingTable) WriteBinary(writer io.Writer) error {
+	table := toJSONParsingTable(self)
+	w := lib.NewBinaryWriter(writer)
+	w.WriteHeader(PARSING_TABLE_MAGIC, PARSING_TABLE_FORMAT_VERSION)
+
+	w.WriteString(table.InitialNodeId)
+	w.WriteInt(table.EndToken)
+	w.WriteInt(table.ErrorToken)
+	w.WriteInt(len(table.TokenNames))
+	for _, name := range table.TokenNames {
+		w.WriteString(name)
+	}
+	w.WriteInt(table.Grammar.InitialSimbol)
+	w.WriteInt(len(table.Grammar.Rules))
+	for _, rule := range table.Grammar.Rules {
+		w.WriteInt(rule.Head)
+		w.WriteInt(len(rule.Production))
+		for _, token := range rule.Production {
+			w.WriteInt(token)
+		}
+	}
+
+	for _, tokens := range [][]GrammarToken{table.Grammar.Terminals, table.Grammar.NonTerminals} {
+		w.WriteInt(len(tokens))
+		for _, token := range tokens {
+			w.WriteInt(token)
+		}
+	}
+
+	w.WriteInt(len(table.Actions))
+	for _, action := range table.Actions {
+		w.WriteString(action.Node)
+		w.WriteInt(action.Token)
+		if action.Shift != nil {
+			w.WriteInt(binaryShiftAction)
+			w.WriteString(*action.Shift)
+		} else if action.Reduce != nil {
+			w.WriteInt(binaryReduceAction)
+			w.WriteInt(*action.Reduce)
+		} else {
+			w.WriteInt(binaryAcceptAction)
+		}
+	}
+
+	w.WriteInt(len(table.GoTos))
+	for _, goTo := range table.GoTos {
+		w.WriteString(goTo.Node)
+		w.WriteInt(goTo.Token)
+		w.WriteString(goTo.To)
+	}
+
+	w.WriteInt(len(table.ExplicitErrors))
+	for _, cell := range table.ExplicitErrors {
+		w.WriteString(cell.Node)
+		w.WriteInt(cell.Token)
+	}
+
+	return w.Flush()
+}
+
+// Reads a table written by WriteBinary.
+//
+// Fails if the file was written with another version of the format.
+func ReadParsingTableBinary(reader io.Reader) (ParsingTable, error) {
+	r := lib.NewBinaryReader(reader)
+	version := r.ReadHeader(PARSING_TABLE_MAGIC)
+	if r.Err() != nil {
+		return ParsingTable{}, r.Err()
+	}
+	if version != PARSING_TABLE_FORMAT_VERSION {
+		return ParsingTable{}, fmt.Errorf("unsupported %s version %d, expected version %d", PARSING_TABLE_FORMAT, version, PARSING_TABLE_FORMAT_VERSION)
+	}
+
+	initialNodeId := r.ReadString()
+	endToken := r.ReadInt()
+	errorToken := r.ReadInt()
+	tokenNames := []string{}
+	nameCount := r.ReadLength()
+	for range nameCount {
+		tokenNames = append(tokenNames, r.ReadString())
+	}
+
+	table := newParsingTable(initialNodeId, r.ReadInt())
+	table.EndToken = endToken
+	table.ErrorToken = errorToken
+	table.TokenNames = tokenNames
+
+	ruleCount := r.ReadLength()
+	for range ruleCount {
+		rule := GrammarRule{Head: r.ReadInt(), Production: []GrammarToken{}}
+		productionLength := r.ReadLength()
+		for range productionLength {
+			rule.Production = append(rule.Production, r.ReadInt())
+		}
+		table.Original.Rules = append(table.Original.Rules, rule)
+	}
+
+	for _, tokens := range []Set[GrammarToken]{table.Original.Terminals, table.Original.NonTerminals} {
+		count := r.ReadLength()
+		for range count {
+			tokens.Add(r.ReadInt())
+		}
+	}
+
+	actionCount := r.ReadLength()
+	for range actionCount {
+		node := r.ReadString()
+		token := r.ReadInt()
+		action := Action{}
+		switch kind := r.ReadInt(); kind {
+		case binaryShiftAction:
+			action.Shift = lib.CreateValue(r.ReadString())
+		case binaryReduceAction:
+			action.Reduce = lib.CreateValue(r.ReadInt())
+		case binaryAcceptAction:
+			action.Accept = true
+		default:
+			return ParsingTable{}, fmt.Errorf("invalid action kind %d", kind)
+		}
+		table.addAction(node, token, action)
+	}
+
+	goToCount := r.ReadLength()
+	for range goToCount {
+		node := r.ReadString()
+		token := r.ReadInt()
+		table.addGoTo(node, token, r.ReadString())
+	}
+
+	errorCount := r.ReadLength()
+	for range errorCount {
+		node := r.ReadString()
+		table.addExplicitError(node, r.ReadInt())
+	}
+
+	if r.Err() != nil {
+		return ParsingTable{}, r.Err()
+	}
+	return table, nil
+}
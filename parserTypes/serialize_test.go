@@ -0,0 +1,115 @@
+package parsertypes
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Jose-Prince/UWUCompiler/lib"
+)
+
+// The table of S -> a S | b
+func createExampleTable() ParsingTable {
+	return ParsingTable{
+		ActionTable: map[AFDNodeId]map[GrammarToken]Action{
+			"0": {0: {Shift: lib.CreateValue("2")}, 1: {Shift: lib.CreateValue("3")}},
+			"1": {3: {Accept: true}},
+			"2": {0: {Shift: lib.CreateValue("2")}, 1: {Shift: lib.CreateValue("3")}},
+			"3": {3: {Reduce: lib.CreateValue(1)}},
+			"4": {3: {Reduce: lib.CreateValue(0)}},
+		},
+		GoToTable: map[AFDNodeId]map[GrammarToken]AFDNodeId{
+			"0": {2: "1"},
+			"2": {2: "4"},
+		},
+		Original: Grammar{
+			InitialSimbol: 2,
+			Rules: []GrammarRule{
+				{Head: 2, Production: []GrammarToken{0, 2}},
+				{Head: 2, Production: []GrammarToken{1}},
+			},
+			Terminals:    Set[GrammarToken]{0: {}, 1: {}, 3: {}},
+			NonTerminals: Set[GrammarToken]{2: {}},
+		},
+		InitialNodeId: "0",
+		EndToken:      3,
+		ErrorToken:    UNRECOGNIZABLE,
+		TokenNames:    []string{"a", "b", "<S>", "<EOF>"},
+		// Not a real explicit error, there's no precedence on this grammar
+		ExplicitErrors: map[AFDNodeId]Set[GrammarToken]{"3": {0: {}}},
+	}
+}
+
+func TestParsingTableRoundTrip(t *testing.T) {
+	formats := []struct {
+		name  string
+		write func(*ParsingTable, *bytes.Buffer) error
+		read  func(*bytes.Buffer) (ParsingTable, error)
+	}{
+		{"json", func(table *ParsingTable, b *bytes.Buffer) error { return table.WriteJSON(b) }, func(b *bytes.Buffer) (ParsingTable, error) { return ReadParsingTableJSON(b) }},
+		{"binary", func(table *ParsingTable, b *bytes.Buffer) error { return table.WriteBinary(b) }, func(b *bytes.Buffer) (ParsingTable, error) { return ReadParsingTableBinary(b) }},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			table := createExampleTable()
+			b := bytes.Buffer{}
+			if err := format.write(&table, &b); err != nil {
+				t.Fatal(err)
+			}
+			written := b.String()
+
+			read, err := format.read(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(table, read) {
+				t.Fatalf("Expected:\n%#v\nBut got:\n%#v", table, read)
+			}
+
+			// The same table must always be written the same way
+			again := bytes.Buffer{}
+			if err := format.write(&read, &again); err != nil {
+				t.Fatal(err)
+			}
+			if again.String() != written {
+				t.Fatal("Writing the table again gave a different result")
+			}
+		})
+	}
+}
+
+func TestReadParsingTableRejectsOtherVersions(t *testing.T) {
+	_, err := ReadParsingTableJSON(strings.NewReader(`{"format": "uwu-parsing-table", "version": 99}`))
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("Expected a version error but got %v", err)
+	}
+
+	_, err = ReadParsingTableJSON(strings.NewReader(`{"format": "uwu-afd", "version": 1}`))
+	if err == nil {
+		t.Fatal("Expected an error reading another format")
+	}
+
+	b := bytes.Buffer{}
+	w := lib.NewBinaryWriter(&b)
+	w.WriteHeader(PARSING_TABLE_MAGIC, 99)
+	w.Flush()
+	_, err = ReadParsingTableBinary(&b)
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("Expected a version error but got %v", err)
+	}
+}
+
+func TestReadParsingTableBinaryRejectsTruncatedFiles(t *testing.T) {
+	table := createExampleTable()
+	b := bytes.Buffer{}
+	if err := table.WriteBinary(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ReadParsingTableBinary(bytes.NewReader(b.Bytes()[:b.Len()-3]))
+	if err == nil {
+		t.Fatal("Expected an error reading a truncated table")
+	}
+}
@@ -0,0 +1,65 @@
+package parsertypes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A node of the concrete syntax tree built by Parse.
+type TreeNode struct {
+	// The token type of the terminal or nonterminal of the node
+	Type   int    `json:"type"`
+	Symbol string `json:"symbol"`
+	// The index of the production used to derive the children, -1 on terminals
+	Rule int `json:"rule"`
+	// The part of the source the node covers, in bytes (end exclusive)
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Where the node starts on the source
+	Line int `json:"line"`
+	Col  int `json:"col"`
+	// The text matched by a terminal
+	Text     string      `json:"text,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+}
+
+// Prints the tree with a node per line, the children are indented under their parent.
+//
+// Nonterminals are shown with the production used for them, like `head -> symbols`.
+func (self *TreeNode) String() string {
+	b := strings.Builder{}
+	self.writeTo(&b, 0)
+	return b.String()
+}
+
+func (self *TreeNode) writeTo(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	if self.Rule == -1 {
+		fmt.Fprintf(b, "%s %q", self.Symbol, self.Text)
+	} else {
+		b.WriteString(self.Symbol)
+		b.WriteString(" ->")
+		for _, child := range self.Children {
+			b.WriteString(" ")
+			b.WriteString(child.Symbol)
+		}
+	}
+	fmt.Fprintf(b, " (%d:%d) [%d, %d)\n", self.Line, self.Col, self.Start, self.End)
+
+	for _, child := range self.Children {
+		child.writeTo(b, depth+1)
+	}
+}
+
+// Dumps the tree as indented JSON, so other tools can consume it.
+func (self *TreeNode) JSON() ([]byte, error) {
+	b := bytes.Buffer{}
+	encoder := json.NewEncoder(&b)
+	// The names of the nonterminals are written like <name>
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(self)
+	return b.Bytes(), err
+}
@@ -51,8 +51,8 @@ func (s Action) GetReduce() int {
 type EpsilonString = lib.Optional[string]
 
 type GrammarRule struct {
-	Head       GrammarToken
-	Production []GrammarToken
+	Head       GrammarToken   `json:"head"`
+	Production []GrammarToken `json:"production"`
 }
 
 type Set[T comparable] map[T]struct{}
@@ -187,6 +187,30 @@ type ParsingTable struct {
 
 	// The node to start parsing from
 	InitialNodeId AFDNodeId
+
+	// The token returned by the lexer once all the source is consumed
+	EndToken GrammarToken
+	// The token shifted to recover from syntax errors, UNRECOGNIZABLE if the grammar doesn't use it
+	ErrorToken GrammarToken
+	// The name of every token indexed by its id, nonterminals are written like <name>
+	TokenNames []string
+	// The cells that must stay syntax errors when the table is packed with default reductions,
+	// like the ones a %nonassoc terminal leaves
+	ExplicitErrors map[AFDNodeId]Set[GrammarToken]
+}
+
+// Shows a token like `1 (NUMBER)`.
+func (self *ParsingTable) TokenToHuman(tk GrammarToken) string {
+	return tokenToHuman(self.TokenNames, tk)
+}
+
+func tokenToHuman(tokenNames []string, tk GrammarToken) string {
+	name := ""
+	if tk >= 0 && tk < len(tokenNames) {
+		name = tokenNames[tk]
+	}
+
+	return fmt.Sprintf("%d (%s)", tk, name)
 }
 
 type Stack[T any] []T